@@ -0,0 +1,98 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+// fakeFileInfo is a fake file with a name and nothing else. The data backends
+// only look at the name.
+type fakeFileInfo struct {
+	name string
+}
+
+func (obj *fakeFileInfo) Name() string       { return obj.name }
+func (obj *fakeFileInfo) Size() int64        { return 0 }
+func (obj *fakeFileInfo) Mode() fs.FileMode  { return 0 }
+func (obj *fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (obj *fakeFileInfo) IsDir() bool        { return false }
+func (obj *fakeFileInfo) Sys() interface{}   { return nil }
+
+// dataTest is a test case for a data backend. The input is scanned as the data
+// of a file with this name.
+type dataTest struct {
+	name      string
+	input     string
+	output    string // joined licenses, or empty for no result
+	component string
+	skip      bool
+}
+
+// testDataBackend scans the input of each test case with the backend, and
+// checks the result.
+func testDataBackend(t *testing.T, b interfaces.DataBackend, tests []dataTest) {
+	t.Helper()
+	for i, test := range tests {
+		info := &interfaces.Info{
+			FileInfo: &fakeFileInfo{name: test.name},
+			UID:      iterator.FileScheme + "/tmp/" + test.name,
+		}
+		result, err := b.ScanData(context.Background(), []byte(test.input), info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		checkResult(t, i, result, test.output, test.component, test.skip)
+	}
+}
+
+// checkResult checks the joined licenses, the component, and whether there is a
+// skip error in the result of test number i. A nil result has none of these.
+func checkResult(t *testing.T, i int, result *interfaces.Result, output, component string, skip bool) {
+	t.Helper()
+	out, comp := "", ""
+	if result != nil {
+		out = licenses.Join(result.Licenses)
+		comp = result.Component
+	}
+	if out != output {
+		t.Errorf("test #%d: out: %v, exp out: %v", i, out, output)
+	}
+	if comp != component {
+		t.Errorf("test #%d: component: %v, exp component: %v", i, comp, component)
+	}
+	if s := result != nil && result.Skip != nil; s != skip {
+		t.Errorf("test #%d: skip: %v, exp skip: %v", i, s, skip)
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// CargoFilename is the file name of the rust package manifest.
+	CargoFilename = "Cargo.toml"
+
+	// CargoLicenseFilePrefix is the prefix of the custom license that is
+	// returned when the license file can't be identified.
+	CargoLicenseFilePrefix = "license-file: "
+)
+
+var (
+	// ErrInvalidCargoLicense is an error used when the license field is
+	// malformed.
+	ErrInvalidCargoLicense = errors.New("invalid cargo license field")
+
+	// ErrCargoWorkspaceNotFound is an error used when a package inherits
+	// its license from a workspace that we can't find.
+	ErrCargoWorkspaceNotFound = errors.New("cargo workspace not found")
+)
+
+// Cargo is a backend for the Cargo.toml manifest of rust packages, which is also
+// found in every crate that was vendored with `cargo vendor`. It reads the SPDX
+// expression in the license field of the package table, and keeps the choices
+// that it makes as a single license so that `MIT OR Apache-2.0` is represented
+// correctly. The older `MIT/Apache-2.0` form is also understood. If there's a
+// license-file field instead, then it is resolved against the package directory
+// and identified with the license classifier library. Fields which are
+// inherited from the workspace are looked up in the workspace manifest in a
+// parent directory.
+type Cargo struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+
+	classifier licenseTextClassifier
+}
+
+// String method returns the name of the backend.
+func (obj *Cargo) String() string {
+	return "cargo"
+}
+
+// Dependent returns true because the license can be inherited from the
+// workspace manifest, or be in a license file.
+func (obj *Cargo) Dependent() bool {
+	return true
+}
+
+// ScanPath is used to extract the license from the package manifest at the path
+// and return licenses based on it.
+func (obj *Cargo) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
+	if info.FileInfo.Name() != CargoFilename {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() { // path.IsDir() should be the same.
+		return nil, nil // skip
+	}
+	if info.FileInfo.Size() == 0 {
+		return nil, nil // skip
+	}
+
+	data, err := os.ReadFile(path.Path())
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "can't read file")
+	}
+	manifest, err := CargoParseManifest(data)
+	if err != nil {
+		// There is a parse error with the file, so we can't properly
+		// examine it for licensing information.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(err, "parse error"),
+		}
+		return result, nil
+	}
+	if manifest.Package == nil {
+		return nil, nil // a virtual workspace manifest
+	}
+
+	root := info.Root.Path()
+	dir := filepath.Dir(path.Path())
+	license := manifest.Package.License
+	licenseFile := manifest.Package.LicenseFile
+	licenseFileDir := dir
+
+	if manifest.Package.LicenseWorkspace || manifest.Package.LicenseFileWorkspace {
+		workspaceDir, workspace, err := obj.workspace(root, dir, manifest)
+		if err != nil {
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       err,
+			}
+			return result, nil
+		}
+		if manifest.Package.LicenseWorkspace {
+			license = workspace.License
+		}
+		if manifest.Package.LicenseFileWorkspace {
+			licenseFile = workspace.LicenseFile
+			licenseFileDir = workspaceDir // it's relative to there
+		}
+	}
+
+	if license != "" {
+		// The old form used a slash to mean OR.
+		expression := strings.ReplaceAll(license, "/", " OR ")
+		licenseList, err := spdxExpressionLicenses(expression)
+		if err != nil {
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       errwrap.Wrapf(errwrap.Append(ErrInvalidCargoLicense, err), "cargo sub-parser error"),
+			}
+			return result, nil
+		}
+		result := &interfaces.Result{
+			Licenses:   licenseList,
+			Confidence: 1.0, // TODO: what should we put here?
+		}
+		return result, nil
+	}
+
+	if licenseFile != "" {
+		return obj.licenseFile(ctx, root, licenseFileDir, licenseFile)
+	}
+
+	return nil, nil // nothing was declared
+}
+
+// workspace finds the workspace that a package belongs to, and returns its
+// directory and the package fields that it defines. It uses the workspace field
+// of the package if it is set, and otherwise it searches each parent directory
+// like cargo does, up to the root directory of the scan. A workspace outside of
+// the root isn't used.
+func (obj *Cargo) workspace(root, dir string, manifest *CargoManifest) (string, *CargoPackage, error) {
+	if manifest.Workspace != nil { // the package is the workspace root
+		return dir, manifest.Workspace, nil
+	}
+
+	if p := manifest.Package.Workspace; p != "" {
+		if filepath.IsAbs(p) {
+			return "", nil, errwrap.Wrapf(ErrOutsideRoot, "workspace %s", p)
+		}
+		p = filepath.Join(dir, p)
+		f := filepath.Join(p, CargoFilename)
+		if !insideRoot(root, f) {
+			return "", nil, errwrap.Wrapf(ErrOutsideRoot, "workspace %s", manifest.Package.Workspace)
+		}
+		m, err := cargoReadManifest(f)
+		if err != nil {
+			return "", nil, errwrap.Wrapf(err, "can't read workspace manifest")
+		}
+		if m.Workspace == nil {
+			return "", nil, ErrCargoWorkspaceNotFound
+		}
+		return p, m.Workspace, nil
+	}
+
+	for p := filepath.Dir(dir); insideDir(root, p); p = filepath.Dir(p) {
+		if f := filepath.Join(p, CargoFilename); insideRoot(root, f) {
+			m, err := cargoReadManifest(f)
+			if err == nil && m.Workspace != nil {
+				return p, m.Workspace, nil
+			}
+			if err != nil && !os.IsNotExist(err) && obj.Debug {
+				obj.Logf("can't read possible workspace manifest in %s: %v", p, err)
+			}
+		}
+		if p == root || p == filepath.Dir(p) {
+			break
+		}
+	}
+	return "", nil, ErrCargoWorkspaceNotFound
+}
+
+// licenseFile identifies the license file which is named relative to the dir.
+// If the license classifier can't identify it, then the file name is returned
+// as a custom license, since it is the best description of it that we have. A
+// file outside of the root directory of the scan isn't read.
+func (obj *Cargo) licenseFile(ctx context.Context, root, dir, name string) (*interfaces.Result, error) {
+	p := filepath.Join(dir, name)
+	result := &interfaces.Result{
+		Licenses: []*licenses.License{
+			{
+				//SPDX: "",
+				Origin: "", // unknown!
+				Custom: CargoLicenseFilePrefix + name,
+			},
+		},
+		Confidence: 1.0, // TODO: what should we put here?
+	}
+
+	if filepath.IsAbs(name) || !insideRoot(root, p) {
+		result.Skip = errwrap.Wrapf(ErrOutsideRoot, "can't read license file")
+		return result, nil
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		result.Skip = errwrap.Wrapf(err, "can't read license file")
+		return result, nil
+	}
+
+	license, confidence, err := obj.classifier.classify(ctx, string(data))
+	if err != nil {
+		return nil, err
+	}
+	if license == nil { // not confident about any license
+		return result, nil
+	}
+	result.Licenses = []*licenses.License{license}
+	result.Confidence = confidence
+	return result, nil
+}
+
+// CargoManifest is the part of a Cargo.toml file that we look at.
+type CargoManifest struct {
+	// Package is the package table, or nil if there isn't one.
+	Package *CargoPackage
+
+	// Workspace is the package table of the workspace table, or nil if
+	// this isn't a workspace root. It is empty if the workspace doesn't
+	// define any package fields.
+	Workspace *CargoPackage
+}
+
+// CargoPackage is the license information of a package.
+type CargoPackage struct {
+	// License is the SPDX expression.
+	License string
+
+	// LicenseFile is the name of a file with the license text.
+	LicenseFile string
+
+	// LicenseWorkspace is true if the license is inherited from the
+	// workspace.
+	LicenseWorkspace bool
+
+	// LicenseFileWorkspace is true if the license file is inherited from
+	// the workspace.
+	LicenseFileWorkspace bool
+
+	// Workspace is the path to the workspace root, if it is set.
+	Workspace string
+}
+
+// CargoParseManifest parses the fields that we need from a Cargo.toml file.
+func CargoParseManifest(data []byte) (*CargoManifest, error) {
+	tables, err := tomlTables(data, []string{"package", "workspace", "workspace.package"})
+	if err != nil {
+		return nil, err
+	}
+	manifest := &CargoManifest{}
+	if table := tables["package"]; table != nil {
+		pkg := &CargoPackage{}
+		var err error
+		if pkg.License, pkg.LicenseWorkspace, err = cargoField(table, "license"); err != nil {
+			return nil, err
+		}
+		if pkg.LicenseFile, pkg.LicenseFileWorkspace, err = cargoField(table, "license-file"); err != nil {
+			return nil, err
+		}
+		pkg.Workspace, _ = table["workspace"].(string)
+		manifest.Package = pkg
+	}
+	if tables["workspace"] != nil || tables["workspace.package"] != nil {
+		pkg := &CargoPackage{}
+		if table := tables["workspace.package"]; table != nil {
+			pkg.License, _ = table["license"].(string)
+			pkg.LicenseFile, _ = table["license-file"].(string)
+		}
+		manifest.Workspace = pkg
+	}
+	return manifest, nil
+}
+
+// cargoField returns the value of a string field in the package table, or true
+// if the field is inherited from the workspace with either the `x.workspace =
+// true` or the `x = { workspace = true }` syntax.
+func cargoField(table map[string]interface{}, key string) (string, bool, error) {
+	if s, ok := table[key+".workspace"].(string); ok {
+		return "", s == "true", nil
+	}
+	switch x := table[key].(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return x, false, nil
+	case map[string]interface{}:
+		s, _ := x["workspace"].(string)
+		return "", s == "true", nil
+	}
+	return "", false, ErrInvalidCargoLicense
+}
+
+// cargoReadManifest reads and parses the manifest at the path.
+func cargoReadManifest(p string) (*CargoManifest, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return CargoParseManifest(data)
+}
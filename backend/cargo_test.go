@@ -0,0 +1,195 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestCargoBackend(t *testing.T) {
+	cargoBackend := &backend.Cargo{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	apache, err := os.ReadFile("../COPYING")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tests := []struct {
+		files  map[string]string // the crate is in the crate/ directory of the root
+		output string            // joined licenses, or empty for no result
+		skip   bool
+	}{
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nname = \"a\"\nlicense = \"MIT OR Apache-2.0\"\n"},
+			"(Apache-2.0 OR MIT)",
+			false,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nname = \"a\"\nlicense = \"MIT/Apache-2.0\"\n"},
+			"(Apache-2.0 OR MIT)",
+			false,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nlicense = \"Apache-2.0 WITH LLVM-exception AND (MIT OR ISC)\"\n\n[dependencies]\nlicense = \"1.0\"\n"},
+			"Apache-2.0 WITH LLVM-exception(unknown), (ISC OR MIT)",
+			false,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nlicense = \"(MIT AND ISC) OR Zlib\"\n"},
+			"MIT, ISC, Zlib",
+			false,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nlicense = \"MIT OR\"\n"},
+			"",
+			true,
+		},
+		{
+			map[string]string{
+				"Cargo.toml":       "[workspace]\nmembers = [\"crate\"]\n\n[workspace.package]\nlicense = \"BSD-3-Clause\"\n",
+				"crate/Cargo.toml": "[package]\nname = \"a\"\nlicense.workspace = true\n",
+			},
+			"BSD-3-Clause",
+			false,
+		},
+		{
+			map[string]string{
+				"Cargo.toml":       "[workspace]\n\n[workspace.package]\nlicense-file = \"LICENSE-CUSTOM\"\n",
+				"LICENSE-CUSTOM":   "You may do nothing with this.\n",
+				"crate/Cargo.toml": "[package]\nlicense-file = { workspace = true }\n",
+			},
+			"license-file: LICENSE-CUSTOM(unknown)",
+			false,
+		},
+		{
+			map[string]string{
+				"crate/Cargo.toml":  "[package]\nlicense-file = \"LICENSE.txt\"\n",
+				"crate/LICENSE.txt": string(apache),
+			},
+			"Apache-2.0",
+			false,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nlicense.workspace = true\n"},
+			"",
+			true,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nlicense-file = \"LICENSE\"\n"},
+			"license-file: LICENSE(unknown)",
+			true,
+		},
+		{
+			map[string]string{
+				"crate/Cargo.toml": "[package]\nlicense-file = \"../../LICENSE\"\n",
+				"../LICENSE":       string(apache),
+			},
+			"license-file: ../../LICENSE(unknown)",
+			true,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package]\nlicense-file = \"/etc/passwd\"\n"},
+			"license-file: /etc/passwd(unknown)",
+			true,
+		},
+		{
+			map[string]string{
+				"../Cargo.toml":    "[workspace]\n\n[workspace.package]\nlicense = \"MIT\"\n",
+				"crate/Cargo.toml": "[package]\nlicense.workspace = true\n",
+			},
+			"",
+			true,
+		},
+		{
+			map[string]string{
+				"../Cargo.toml":    "[workspace]\n\n[workspace.package]\nlicense = \"MIT\"\n",
+				"crate/Cargo.toml": "[package]\nworkspace = \"../..\"\nlicense.workspace = true\n",
+			},
+			"",
+			true,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[workspace]\nmembers = [\"a\"]\n"},
+			"",
+			false,
+		},
+		{
+			map[string]string{"crate/Cargo.toml": "[package\n"},
+			"",
+			true,
+		},
+	}
+
+	for i, test := range tests {
+		dir := filepath.Join(t.TempDir(), "root")
+		for name, data := range test.files {
+			p := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatalf("test #%d: err: %v", i, err)
+			}
+			if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+				t.Fatalf("test #%d: err: %v", i, err)
+			}
+		}
+		p := filepath.Join(dir, "crate", backend.CargoFilename)
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		info := &interfaces.Info{
+			FileInfo: fileInfo,
+			UID:      iterator.FileScheme + p,
+			Root:     safepath.UnsafeParseIntoAbsDir(dir),
+		}
+		result, err := cargoBackend.ScanPath(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		out := ""
+		if result != nil {
+			out = licenses.Join(result.Licenses)
+		}
+		if out != test.output {
+			t.Errorf("test #%d: out: %v, exp out: %v", i, out, test.output)
+		}
+		if skip := result != nil && result.Skip != nil; skip != test.skip {
+			t.Errorf("test #%d: skip: %v, exp skip: %v", i, skip, test.skip)
+		}
+	}
+}
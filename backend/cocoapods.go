@@ -0,0 +1,255 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// CocoapodsExtension is the extension of the cocoapods spec file.
+	CocoapodsExtension = ".podspec"
+
+	// CocoapodsJSONExtension is the extension of the json form of the
+	// cocoapods spec file, which is what the spec repositories contain.
+	CocoapodsJSONExtension = ".podspec.json"
+
+	// CocoapodsLicenseFilePrefix is the prefix of the custom license that
+	// is returned when the license is in a file in the pod.
+	CocoapodsLicenseFilePrefix = "license-file: "
+)
+
+var (
+	// ErrInvalidCocoapodsLicense is an error used when the license field is
+	// malformed.
+	ErrInvalidCocoapodsLicense = errors.New("invalid cocoapods license field")
+)
+
+var (
+	// cocoapodsLicenseRegexp matches the assignment to the license field of
+	// a ruby podspec, and captures the value up to the end of the line.
+	cocoapodsLicenseRegexp = regexp.MustCompile(`\.license\s*=\s*(.*)`)
+
+	// cocoapodsAttributeRegexp matches the assignment of a string literal
+	// to the name or version fields of a ruby podspec.
+	cocoapodsAttributeRegexp = regexp.MustCompile(`\.(name|version)\s*=\s*["']([^"']*)["']`)
+
+	// cocoapodsKeyRegexp matches the type or file key of a ruby hash, in
+	// both the `:type =>` and the `type:` forms, and its string value.
+	cocoapodsKeyRegexp = regexp.MustCompile(`:?(type|file)\s*(?:=>|:)\s*("[^"]*"|'[^']*')`)
+)
+
+// Cocoapods is a backend for the .podspec files which describe cocoapods
+// packages. These are normally ruby, but the json form is also supported. The
+// license field is either a string, or a hash with the license type and the
+// name of the license file. The type is mapped to an SPDX ID if we know it. A
+// license file with no type is returned as a custom license which names it. The
+// ruby form is not evaluated, so only string literals are found.
+type Cocoapods struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+}
+
+// String method returns the name of the backend.
+func (obj *Cocoapods) String() string {
+	return "cocoapods"
+}
+
+// ScanData is used to extract the licenses from the spec data.
+func (obj *Cocoapods) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+	name := info.FileInfo.Name()
+	if !strings.HasSuffix(name, CocoapodsExtension) && !strings.HasSuffix(name, CocoapodsJSONExtension) {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() {
+		return nil, nil // skip
+	}
+	if len(data) == 0 {
+		return nil, nil // skip
+	}
+
+	var spec *CocoapodsSpec
+	if strings.HasSuffix(name, CocoapodsJSONExtension) {
+		var err error
+		if spec, err = CocoapodsParseJSON(data); err != nil {
+			// There is a parse error with the file, so we can't
+			// properly examine it for licensing information.
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       errwrap.Wrapf(err, "parse error"),
+			}
+			return result, nil
+		}
+	} else {
+		spec = CocoapodsParseSpec(data)
+	}
+
+	licenseList := spec.Licenses()
+	if len(licenseList) == 0 {
+		return nil, nil // nothing was declared
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: 1.0, // TODO: what should we put here?
+		Component:  spec.Component(),
+	}
+	return result, nil
+}
+
+// CocoapodsSpec is the subset of the podspec fields that we look at.
+type CocoapodsSpec struct {
+	Name    string
+	Version string
+
+	// Type is the license type, which is usually an SPDX ID or a license
+	// name.
+	Type string
+
+	// File is the name of the license file, if one was named.
+	File string
+}
+
+// Component returns the name and version of the pod, or the empty string if
+// there's no name.
+func (obj *CocoapodsSpec) Component() string {
+	if obj.Name == "" {
+		return ""
+	}
+	if obj.Version == "" {
+		return obj.Name
+	}
+	return obj.Name + "@" + obj.Version
+}
+
+// Licenses returns the licenses of the pod. If there's only a license file, we
+// return a custom license which names it, so that it's clear where to look.
+func (obj *CocoapodsSpec) Licenses() []*licenses.License {
+	if obj.Type != "" {
+		return spdxNameLicenses(obj.Type)
+	}
+	if obj.File != "" {
+		license := &licenses.License{
+			Origin: "", // unknown!
+			Custom: CocoapodsLicenseFilePrefix + obj.File,
+		}
+		return []*licenses.License{license}
+	}
+	return nil
+}
+
+// CocoapodsParseSpec finds the fields we want in a ruby podspec. Only the first
+// assignment of each field is used, since subspecs can't have a license.
+func CocoapodsParseSpec(data []byte) *CocoapodsSpec {
+	spec := &CocoapodsSpec{}
+	s := string(data)
+	for _, m := range cocoapodsAttributeRegexp.FindAllStringSubmatch(s, -1) {
+		if m[1] == "name" && spec.Name == "" {
+			spec.Name = m[2]
+		}
+		if m[1] == "version" && spec.Version == "" {
+			spec.Version = m[2]
+		}
+	}
+
+	m := cocoapodsLicenseRegexp.FindStringSubmatchIndex(s)
+	if m == nil {
+		return spec
+	}
+	value := strings.TrimSpace(s[m[2]:m[3]])
+	if i := strings.Index(value, "#"); i > -1 && !strings.ContainsAny(value[:i], `"'`) {
+		value = strings.TrimSpace(value[:i]) // trailing comment
+	}
+	if !strings.HasPrefix(value, "{") {
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			if i := strings.IndexByte(value[1:], value[0]); i > -1 {
+				spec.Type = strings.TrimSpace(value[1 : i+1])
+			}
+		}
+		return spec
+	}
+
+	// The hash can span several lines, so look until it's closed.
+	rest := s[m[2]:]
+	if i := strings.Index(rest, "}"); i > -1 {
+		rest = rest[:i]
+	}
+	for _, x := range cocoapodsKeyRegexp.FindAllStringSubmatch(rest, -1) {
+		v := gemUnquote(x[2])
+		if x[1] == "type" && spec.Type == "" {
+			spec.Type = v
+		}
+		if x[1] == "file" && spec.File == "" {
+			spec.File = v
+		}
+	}
+	return spec
+}
+
+// CocoapodsParseJSON parses the json form of a podspec.
+func CocoapodsParseJSON(data []byte) (*CocoapodsSpec, error) {
+	var raw struct {
+		Name    string          `json:"name"`
+		Version string          `json:"version"`
+		License json.RawMessage `json:"license"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	spec := &CocoapodsSpec{
+		Name:    raw.Name,
+		Version: raw.Version,
+	}
+	if len(raw.License) == 0 || string(raw.License) == "null" {
+		return spec, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw.License, &s); err == nil {
+		spec.Type = strings.TrimSpace(s)
+		return spec, nil
+	}
+	var license struct {
+		Type string `json:"type"`
+		File string `json:"file"`
+	}
+	if err := json.Unmarshal(raw.License, &license); err != nil {
+		return nil, ErrInvalidCocoapodsLicense
+	}
+	spec.Type = strings.TrimSpace(license.Type)
+	spec.File = strings.TrimSpace(license.File)
+	return spec, nil
+}
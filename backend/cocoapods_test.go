@@ -0,0 +1,55 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+)
+
+func TestCocoapodsBackend(t *testing.T) {
+	cocoapodsBackend := &backend.Cocoapods{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	tests := []dataTest{
+		{"A.podspec", "Pod::Spec.new do |s|\n  s.name = 'A'\n  s.version = '1.0'\n  s.license = 'MIT' # comment\nend\n", "MIT", "A@1.0", false},
+		{"A.podspec", "Pod::Spec.new do |spec|\n  spec.name = \"A\"\n  spec.license = { :type => 'Apache License, Version 2.0',\n    :file => 'LICENSE' }\nend\n", "Apache-2.0", "A", false},
+		{"A.podspec", "Pod::Spec.new do |s|\n  s.license = { type: \"BSD-3-Clause\", file: \"LICENSE\" }\nend\n", "BSD-3-Clause", "", false},
+		{"A.podspec", "Pod::Spec.new do |s|\n  s.license = { :file => 'LICENSE.md' }\nend\n", "license-file: LICENSE.md(unknown)", "", false},
+		{"A.podspec", "Pod::Spec.new do |s|\n  s.summary = 'no license'\nend\n", "", "", false},
+		{"A.podspec.json", `{"name": "A", "version": "2.0", "license": {"type": "MIT", "file": "LICENSE"}}`, "MIT", "A@2.0", false},
+		{"A.podspec.json", `{"name": "A", "license": "Commercial"}`, "Commercial(unknown)", "A", false},
+		{"A.podspec.json", `{"name": "A", "license": 42}`, "", "", true},
+		{"A.podspec.json", `{"name": `, "", "", true},
+		{"A.rb", "s.license = 'MIT'\n", "", "", false},
+	}
+
+	testDataBackend(t, cocoapodsBackend, tests)
+}
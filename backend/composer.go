@@ -0,0 +1,214 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// ComposerFilename is the file name of the php composer package
+	// metadata.
+	ComposerFilename = "composer.json"
+
+	// ComposerLockFilename is the file name of the composer lock file.
+	ComposerLockFilename = "composer.lock"
+)
+
+var (
+	// ErrInvalidComposerLicense is an error used when a license field is
+	// malformed.
+	ErrInvalidComposerLicense = errors.New("invalid composer license field")
+)
+
+// Composer is a backend for the composer.json files which store the metadata of
+// php packages, and for their composer.lock lock files. The license field is an
+// SPDX expression, or a list of them, and a list means that any one of them can
+// be chosen. This choice is kept as a single license. The composer.json result
+// is the declared license of the package in that directory, and the lock file
+// result is the combined set of licenses of all of the locked packages,
+// including the development ones.
+type Composer struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+}
+
+// String method returns the name of the backend.
+func (obj *Composer) String() string {
+	return "composer"
+}
+
+// ScanData is used to extract the licenses from the package or lock file data.
+func (obj *Composer) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+	name := info.FileInfo.Name()
+	if name != ComposerFilename && name != ComposerLockFilename {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() {
+		return nil, nil // skip
+	}
+	if len(data) == 0 {
+		return nil, nil // skip
+	}
+
+	var pkgs []*ComposerPackage
+	component := ""
+	if name == ComposerFilename {
+		var pkg ComposerPackage
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			// There is a parse error with the file, so we can't
+			// properly examine it for licensing information.
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       errwrap.Wrapf(err, "parse error"),
+			}
+			return result, nil
+		}
+		pkgs = append(pkgs, &pkg)
+		component = pkg.Component()
+
+	} else {
+		var lock ComposerLock
+		if err := json.Unmarshal(data, &lock); err != nil {
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       errwrap.Wrapf(err, "parse error"),
+			}
+			return result, nil
+		}
+		pkgs = append(pkgs, lock.Packages...)
+		pkgs = append(pkgs, lock.PackagesDev...)
+	}
+
+	licenseList := []*licenses.License{}
+	var subErr error
+	for _, pkg := range pkgs {
+		if pkg == nil {
+			continue
+		}
+		xs, err := pkg.Licenses()
+		if err != nil {
+			subErr = errwrap.Append(subErr, errwrap.Wrapf(err, "package %s", pkg.Name)) // store for later
+		}
+		// Our parser might have partial results even when it errors.
+		for _, license := range xs {
+			if !licenses.InList(license, licenseList) {
+				licenseList = append(licenseList, license)
+			}
+		}
+	}
+
+	if len(licenseList) == 0 && subErr == nil {
+		return nil, nil // nothing was declared
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	// We return any partial results, and even if we errored, because we can
+	// now notify the user of these issues separately.
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: 1.0, // TODO: what should we put here?
+		Skip:       errwrap.Wrapf(subErr, "composer sub-parser error"),
+		Component:  component,
+	}
+	return result, nil
+}
+
+// ComposerPackage is the subset of the composer.json fields that we look at. The
+// packages in the lock file have the same fields.
+type ComposerPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// License is an SPDX expression string, or a list of them.
+	License json.RawMessage `json:"license"`
+}
+
+// ComposerLock is the subset of the composer.lock fields that we look at.
+type ComposerLock struct {
+	Packages    []*ComposerPackage `json:"packages"`
+	PackagesDev []*ComposerPackage `json:"packages-dev"`
+}
+
+// Component returns the name and version of the package, or the empty string if
+// there's no name. The version is usually only set in the lock file.
+func (obj *ComposerPackage) Component() string {
+	if obj.Name == "" {
+		return ""
+	}
+	if obj.Version == "" {
+		return obj.Name
+	}
+	return obj.Name + "@" + obj.Version
+}
+
+// Licenses returns the licenses in the license field. A list of expressions is
+// a choice between them, so they are joined with OR. Composer allows the lower
+// case `or` and `and` operators, so those are accepted too. Any ids that aren't
+// on the SPDX list, such as "proprietary", are returned as custom licenses.
+func (obj *ComposerPackage) Licenses() ([]*licenses.License, error) {
+	if len(obj.License) == 0 || string(obj.License) == "null" {
+		return nil, nil
+	}
+	var list []string
+	var s string
+	if err := json.Unmarshal(obj.License, &s); err == nil {
+		list = []string{s}
+	} else if err := json.Unmarshal(obj.License, &list); err != nil {
+		return nil, ErrInvalidComposerLicense
+	}
+
+	tokens := []string{}
+	for _, x := range list {
+		if strings.TrimSpace(x) == "" {
+			continue
+		}
+		if len(tokens) > 0 {
+			tokens = append(tokens, "OR")
+		}
+		tokens = append(tokens, "(")
+		for _, token := range strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(x)) {
+			if t := strings.ToUpper(token); t == "OR" || t == "AND" || t == "WITH" {
+				token = t
+			}
+			tokens = append(tokens, token)
+		}
+		tokens = append(tokens, ")")
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return spdxTokensLicenses(tokens)
+}
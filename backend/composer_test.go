@@ -0,0 +1,54 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+)
+
+func TestComposerBackend(t *testing.T) {
+	composerBackend := &backend.Composer{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	tests := []dataTest{
+		{"composer.json", `{"name": "a/b", "license": "MIT"}`, "MIT", "a/b", false},
+		{"composer.json", `{"name": "a/b", "version": "1.0", "license": ["LGPL-2.1-only", "GPL-3.0-or-later"]}`, "(GPL-3.0-or-later OR LGPL-2.1-only)", "a/b@1.0", false},
+		{"composer.json", `{"name": "a/b", "license": "(Apache-2.0 or MIT)"}`, "(Apache-2.0 OR MIT)", "a/b", false},
+		{"composer.json", `{"name": "a/b", "license": "proprietary"}`, "proprietary(unknown)", "a/b", false},
+		{"composer.json", `{"name": "a/b"}`, "", "", false},
+		{"composer.json", `{"name": "a/b", "license": 42}`, "", "a/b", true},
+		{"composer.json", `{"name": `, "", "", true},
+		{"composer.lock", `{"packages": [{"name": "a/b", "version": "1.0", "license": ["MIT"]}, {"name": "c/d", "license": ["BSD-3-Clause"]}], "packages-dev": [{"name": "e/f", "license": ["MIT"]}]}`, "BSD-3-Clause, MIT", "", false},
+		{"package.json", `{"license": "MIT"}`, "", "", false},
+	}
+
+	testDataBackend(t, composerBackend, tests)
+}
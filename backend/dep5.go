@@ -0,0 +1,488 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// Dep5Dir is the name of the directory with the debian packaging.
+	Dep5Dir = "debian"
+
+	// Dep5Filename is the file name of the machine-readable copyright file
+	// in the debian directory.
+	Dep5Filename = "copyright"
+
+	// dep5FormatPrefix is the part of the format url of the
+	// machine-readable copyright files that names the version.
+	dep5FormatPrefix = "copyright-format/1.0"
+)
+
+var (
+	// ErrInvalidDep5 is an error used when a copyright file is malformed.
+	ErrInvalidDep5 = errors.New("invalid debian copyright file")
+
+	// Dep5Names maps the debian short names of licenses, which aren't
+	// the same as the SPDX IDs, to the SPDX IDs. The GNU licenses are
+	// handled separately, since they have a version and a plus.
+	Dep5Names = map[string]string{
+		"Expat":     "MIT",
+		"Artistic":  "Artistic-1.0",
+		"Zope-1.1":  "ZPL-1.1",
+		"Zope-2.0":  "ZPL-2.0",
+		"Zope-2.1":  "ZPL-2.1",
+		"Apache-1":  "Apache-1.0",
+		"Apache-2":  "Apache-2.0",
+		"MPL-2":     "MPL-2.0",
+		"EPL-1":     "EPL-1.0",
+		"Python-2":  "Python-2.0",
+		"PSF-2":     "PSF-2.0",
+		"CC0":       "CC0-1.0",
+		"Unlicense": "Unlicense",
+	}
+
+	// dep5GNURegexp matches the debian short name of a GNU license.
+	dep5GNURegexp = regexp.MustCompile(`^(?i)(GPL|LGPL|AGPL|GFDL)-([0-9]+)(\.[0-9]+)?(\+)?$`)
+)
+
+// Dep5 is a backend for the machine-readable debian/copyright files, which are
+// also known as DEP-5. They are found in debian source packages and in many
+// other projects. Each Files paragraph has a list of patterns, and the license
+// and copyright holders of the files that match them. The last paragraph which
+// matches a file wins. This applies these to each of the files in the tree that
+// the debian directory is in, so the result for a file is its declared license
+// and the copyright holders are returned with it. The result for the copyright
+// file itself is the set of all of the licenses in it. This is a PathBackend
+// because it has to read the copyright file for each of the other files.
+type Dep5 struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+
+	mutex sync.Mutex
+	// dirs caches the path of the closest copyright file for each
+	// directory, keyed by the root and the directory. It stores the empty
+	// string if there isn't one.
+	dirs map[[2]string]string
+	// files caches the parsed copyright files, keyed by their path.
+	files map[string]*dep5File
+}
+
+// String method returns the name of the backend.
+func (obj *Dep5) String() string {
+	return "dep5"
+}
+
+// Dependent returns true because the result for a file comes from the copyright
+// file.
+func (obj *Dep5) Dependent() bool {
+	return true
+}
+
+// ScanPath returns the license and copyright holders that the closest debian
+// copyright file declares for the file at the path.
+func (obj *Dep5) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
+	if info.FileInfo.IsDir() { // path.IsDir() should be the same.
+		return nil, nil // skip
+	}
+	p := path.Path()
+
+	f := obj.find(info.Root.Path(), filepath.Dir(p))
+	if f == "" {
+		return nil, nil // no copyright file applies
+	}
+	copyright, err := obj.parse(f)
+	if f == p && err != nil {
+		// There is a parse error with the file, so we can't properly
+		// examine it for licensing information.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(err, "parse error"),
+		}
+		return result, nil
+	}
+	if err != nil || copyright == nil {
+		return nil, nil // the copyright file result shows the error
+	}
+
+	if f == p {
+		licenseList := []*licenses.License{}
+		for _, x := range copyright.paragraphs {
+			for _, license := range x.licenses {
+				if !licenses.InList(license, licenseList) {
+					licenseList = append(licenseList, license)
+				}
+			}
+		}
+		if len(licenseList) == 0 {
+			return nil, nil // nothing was declared
+		}
+		sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+			return licenseList[i].String() < licenseList[j].String()
+		})
+		result := &interfaces.Result{
+			Licenses:   licenseList,
+			Confidence: 1.0, // TODO: what should we put here?
+		}
+		return result, nil
+	}
+
+	rel, err := filepath.Rel(copyright.root, p)
+	if err != nil {
+		return nil, nil // not in this tree
+	}
+	paragraph := copyright.match(filepath.ToSlash(rel))
+	if paragraph == nil || len(paragraph.licenses) == 0 {
+		return nil, nil // nothing was declared
+	}
+
+	result := &interfaces.Result{
+		Licenses:   paragraph.licenses,
+		Confidence: 1.0, // TODO: what should we put here?
+		Holders:    paragraph.holders,
+	}
+	return result, nil
+}
+
+// find returns the path of the copyright file in the debian directory of the
+// dir or of its closest parent, or the empty string if there isn't one. It
+// doesn't look outside of the root directory of the scan.
+func (obj *Dep5) find(root, dir string) string {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.dirs == nil {
+		obj.dirs = make(map[[2]string]string)
+	}
+	return obj.findLocked(root, dir)
+}
+
+// findLocked is the recursive part of find. The mutex must be held.
+func (obj *Dep5) findLocked(root, dir string) string {
+	key := [2]string{root, dir}
+	if f, exists := obj.dirs[key]; exists {
+		return f
+	}
+	f := filepath.Join(dir, Dep5Dir, Dep5Filename)
+	if fileInfo, err := os.Stat(f); err != nil || !fileInfo.Mode().IsRegular() || !insideRoot(root, f) {
+		f = ""
+		if parent := filepath.Dir(dir); dir != root && insideDir(root, parent) {
+			f = obj.findLocked(root, parent)
+		}
+	}
+	obj.dirs[key] = f
+	return f
+}
+
+// parse returns the parsed copyright file at the path. It returns nil if the
+// file isn't in the machine-readable format.
+func (obj *Dep5) parse(f string) (*dep5File, error) {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.files == nil {
+		obj.files = make(map[string]*dep5File)
+	}
+	if copyright, exists := obj.files[f]; exists {
+		if copyright != nil && copyright.err != nil {
+			return nil, copyright.err
+		}
+		return copyright, nil
+	}
+
+	data, err := os.ReadFile(f)
+	if err != nil {
+		obj.files[f] = &dep5File{err: err}
+		return nil, err
+	}
+	paragraphs, err := Dep5Parse(data)
+	if err != nil {
+		obj.files[f] = &dep5File{err: err}
+		return nil, err
+	}
+	if paragraphs == nil {
+		obj.files[f] = nil
+		return nil, nil
+	}
+
+	copyright := dep5Build(paragraphs, filepath.Dir(filepath.Dir(f)))
+	if obj.Debug {
+		obj.Logf("dep5: %d files paragraphs in %s", len(copyright.paragraphs), f)
+	}
+	obj.files[f] = copyright
+	return copyright, nil
+}
+
+// dep5Build returns the copyright file with the Files paragraphs, whose patterns
+// are relative to the root directory.
+func dep5Build(paragraphs []*Dep5Paragraph, root string) *dep5File {
+	copyright := &dep5File{
+		root: root,
+	}
+	for _, x := range paragraphs {
+		if x.Files == "" {
+			continue // the header or a standalone license
+		}
+		paragraph := &dep5Paragraph{
+			licenses: Dep5Licenses(x.License),
+			holders:  x.Holders(),
+		}
+		for _, pattern := range strings.Fields(x.Files) {
+			paragraph.patterns = append(paragraph.patterns, dep5Pattern(pattern))
+		}
+		copyright.paragraphs = append(copyright.paragraphs, paragraph)
+	}
+	return copyright
+}
+
+// dep5File is a parsed copyright file.
+type dep5File struct {
+	// root is the directory that the patterns are relative to.
+	root string
+
+	paragraphs []*dep5Paragraph
+
+	// err is the error from reading or parsing the file, if any.
+	err error
+}
+
+// match returns the last paragraph that matches the relative path, or nil if
+// none do.
+func (obj *dep5File) match(rel string) *dep5Paragraph {
+	for i := len(obj.paragraphs) - 1; i >= 0; i-- {
+		for _, x := range obj.paragraphs[i].patterns {
+			if x.MatchString(rel) {
+				return obj.paragraphs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// dep5Paragraph is a parsed Files paragraph.
+type dep5Paragraph struct {
+	patterns []*regexp.Regexp
+	licenses []*licenses.License
+	holders  []string
+}
+
+// Dep5Paragraph is a paragraph of a debian copyright file. The fields that we
+// don't use are not stored.
+type Dep5Paragraph struct {
+	// Files is the whitespace separated list of patterns. It is empty for
+	// the header paragraph and for standalone license paragraphs.
+	Files string
+
+	// Copyright is the copyright field, with one holder on each line.
+	Copyright string
+
+	// License is the first line of the license field, which is the short
+	// name of the license, or an expression of them.
+	License string
+}
+
+// Holders returns the copyright holders, which are each of the non-empty lines
+// of the copyright field.
+func (obj *Dep5Paragraph) Holders() []string {
+	holders := []string{}
+	for _, x := range strings.Split(obj.Copyright, "\n") {
+		if x = strings.TrimSpace(x); x != "" && x != "." {
+			holders = append(holders, x)
+		}
+	}
+	return holders
+}
+
+// Dep5Parse parses the paragraphs of a debian copyright file. It returns nil if
+// the first paragraph doesn't have the Format field of the machine-readable
+// format, since the older copyright files are free form text.
+func Dep5Parse(data []byte) ([]*Dep5Paragraph, error) {
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	fields := []map[string]string{}
+	current := map[string]string{}
+	last := ""
+	for i, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue // a comment
+		}
+		if strings.TrimSpace(line) == "" { // the end of a paragraph
+			if len(current) > 0 {
+				fields = append(fields, current)
+			}
+			current = map[string]string{}
+			last = ""
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if last == "" {
+				return nil, errwrap.Wrapf(ErrInvalidDep5, "continuation on line %d", i+1)
+			}
+			current[last] += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		ix := strings.Index(line, ":")
+		if ix < 1 {
+			if len(fields) == 0 || !dep5Format(fields[0]) {
+				return nil, nil // a free form file
+			}
+			return nil, errwrap.Wrapf(ErrInvalidDep5, "no field on line %d", i+1)
+		}
+		last = strings.ToLower(strings.TrimSpace(line[:ix]))
+		current[last] = strings.TrimSpace(line[ix+1:])
+	}
+	if len(current) > 0 {
+		fields = append(fields, current)
+	}
+
+	if len(fields) == 0 || !dep5Format(fields[0]) {
+		return nil, nil // not machine-readable
+	}
+
+	paragraphs := []*Dep5Paragraph{}
+	for _, x := range fields {
+		license := x["license"]
+		if ix := strings.Index(license, "\n"); ix > -1 {
+			license = license[:ix] // the rest is the text
+		}
+		paragraphs = append(paragraphs, &Dep5Paragraph{
+			Files:     strings.TrimSpace(x["files"]),
+			Copyright: strings.TrimSpace(x["copyright"]),
+			License:   strings.TrimSpace(license),
+		})
+	}
+	return paragraphs, nil
+}
+
+// dep5Format returns true if the header paragraph has the Format field of the
+// machine-readable format. The drafts of it used a url with dep5 in it.
+func dep5Format(header map[string]string) bool {
+	format := header["format"]
+	return strings.Contains(format, dep5FormatPrefix) || strings.Contains(format, "dep5")
+}
+
+// Dep5Licenses returns the licenses in the short name of a license field. It is
+// an expression of debian short names, which are joined with a lower case `or`
+// and `and`, and which can have an exception with `with`. These are converted
+// to SPDX IDs where we know them, and otherwise they are custom licenses.
+func Dep5Licenses(name string) []*licenses.License {
+	if name == "" {
+		return nil
+	}
+	words := strings.Fields(strings.ReplaceAll(name, ",", " "))
+	expression := []string{}
+	for i := 0; i < len(words); i++ {
+		x := words[i]
+		switch strings.ToLower(x) {
+		case "or", "and", "with":
+			expression = append(expression, strings.ToUpper(x))
+			continue
+		case "exception":
+			continue // the end of the exception name
+		}
+		if i > 0 && strings.ToLower(words[i-1]) == "with" {
+			expression = append(expression, x+"-exception")
+			continue
+		}
+		expression = append(expression, Dep5ID(x))
+	}
+
+	result, err := spdxExpressionLicenses(strings.Join(expression, " "))
+	if err != nil {
+		// keep the short name, since it's the best we have
+		return []*licenses.License{
+			{
+				//SPDX: "",
+				Origin: "", // unknown!
+				Custom: name,
+			},
+		}
+	}
+	return result
+}
+
+// Dep5ID returns the SPDX ID for a debian short name of a license, or the name
+// unchanged if we don't know it.
+func Dep5ID(name string) string {
+	if id, exists := Dep5Names[name]; exists {
+		return id
+	}
+	if m := dep5GNURegexp.FindStringSubmatch(name); m != nil {
+		minor := m[3]
+		if minor == "" {
+			minor = ".0"
+		}
+		suffix := "-only"
+		if m[4] == "+" {
+			suffix = "-or-later"
+		}
+		license := &licenses.License{
+			SPDX: strings.ToUpper(m[1]) + "-" + m[2] + minor + suffix,
+		}
+		if err := license.Validate(); err == nil {
+			return license.SPDX
+		}
+	}
+	if license, err := licenses.NameToLicense(name); err == nil {
+		return license.SPDX
+	}
+	return name
+}
+
+// dep5Pattern converts a pattern of the Files field into a regexp. A `*` matches
+// anything including a slash, a `?` matches any one character, and a backslash
+// escapes either of them. The pattern is relative to the root of the tree.
+func dep5Pattern(pattern string) *regexp.Regexp {
+	pattern = strings.TrimPrefix(pattern, "./")
+	s := "^"
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			s += regexp.QuoteMeta(string(pattern[i]))
+		case c == '*':
+			s += ".*"
+		case c == '?':
+			s += "."
+		default:
+			s += regexp.QuoteMeta(string(c))
+		}
+	}
+	// a directory pattern matches everything inside of it
+	if strings.HasSuffix(pattern, "/") {
+		s += ".*"
+	}
+	return regexp.MustCompile(s + "$")
+}
@@ -0,0 +1,166 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestDep5Backend(t *testing.T) {
+	copyright := `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: example
+# a comment
+
+Files: *
+Copyright: 2010-2020 Jane Doe <jane@example.com>
+           2015 Example, Inc.
+License: GPL-2+
+ This program is free software; you can redistribute it and/or modify
+ .
+ it under the terms of the GNU General Public License.
+
+Files: src/lib/*
+ src/extra/?.c
+Copyright: Copyright (c) 2018 John Doe
+License: Expat or Apache-2.0
+
+Files: src/lib/vendored.c
+Copyright: 2001 Someone Else
+License: BSD-3-clause and public-domain
+
+Files: debian/*
+Copyright: 2021 Debian Packager
+License: GPL-2+ with OpenSSL exception
+
+License: Expat
+ Permission is hereby granted...
+`
+	tests := []struct {
+		file    string // the file in the tree that we scan
+		output  string // joined licenses, or empty for no result
+		holders string // joined holders
+	}{
+		{"main.c", "GPL-2.0-or-later", "2010-2020 Jane Doe <jane@example.com>; 2015 Example, Inc."},
+		{"src/lib/a/b.c", "(Apache-2.0 OR MIT)", "Copyright (c) 2018 John Doe"},
+		{"src/extra/x.c", "(Apache-2.0 OR MIT)", "Copyright (c) 2018 John Doe"},
+		{"src/extra/xy.c", "GPL-2.0-or-later", "2010-2020 Jane Doe <jane@example.com>; 2015 Example, Inc."},
+		{"src/lib/vendored.c", "BSD-3-Clause, public-domain(unknown)", "2001 Someone Else"},
+		{"debian/rules", "GPL-2.0-or-later WITH OpenSSL-exception(unknown)", "2021 Debian Packager"},
+		{"debian/copyright", "(Apache-2.0 OR MIT), BSD-3-Clause, GPL-2.0-or-later, GPL-2.0-or-later WITH OpenSSL-exception(unknown), public-domain(unknown)", ""},
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{"debian/copyright": copyright}
+	for _, test := range tests {
+		if _, exists := files[test.file]; !exists {
+			files[test.file] = "int x;\n"
+		}
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	dep5Backend := &backend.Dep5{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	for i, test := range tests {
+		p := filepath.Join(dir, test.file)
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		info := &interfaces.Info{
+			FileInfo: fileInfo,
+			UID:      iterator.FileScheme + p,
+			Root:     safepath.UnsafeParseIntoAbsDir(dir),
+		}
+		result, err := dep5Backend.ScanPath(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		out, holders := "", ""
+		if result != nil {
+			out = licenses.Join(result.Licenses)
+			holders = strings.Join(result.Holders, "; ")
+		}
+		if out != test.output {
+			t.Errorf("test #%d: out: %v, exp out: %v", i, out, test.output)
+		}
+		if holders != test.holders {
+			t.Errorf("test #%d: holders: %v, exp holders: %v", i, holders, test.holders)
+		}
+	}
+
+	// The copyright file isn't used if it's outside of the scanned tree.
+	p := filepath.Join(dir, "src/lib/a/b.c")
+	fileInfo, err := os.Stat(p)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info := &interfaces.Info{
+		FileInfo: fileInfo,
+		UID:      iterator.FileScheme + p,
+		Root:     safepath.UnsafeParseIntoAbsDir(filepath.Join(dir, "src")),
+	}
+	if result, err := dep5Backend.ScanPath(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info); err != nil || result != nil {
+		t.Errorf("exp: no result outside of the root, got: %v, err: %v", result, err)
+	}
+}
+
+func TestDep5Parse(t *testing.T) {
+	paragraphs, err := backend.Dep5Parse([]byte("This package was debianized by Someone on\nMon, 1 Jan 2001.\n\nIt was downloaded from: example.com\n"))
+	if err != nil || paragraphs != nil {
+		t.Errorf("exp: a free form file to be ignored, got: %v, err: %v", paragraphs, err)
+	}
+	if _, err := backend.Dep5Parse([]byte("Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\nFiles: *\nnot a field\n")); err == nil {
+		t.Errorf("exp: an error")
+	}
+	for name, exp := range map[string]string{"GPL-2": "GPL-2.0-only", "LGPL-2.1+": "LGPL-2.1-or-later", "agpl-3+": "AGPL-3.0-or-later", "Apache-2.0": "Apache-2.0", "BSD-2-clause": "BSD-2-Clause", "Expat": "MIT", "Custom": "Custom"} {
+		if id := backend.Dep5ID(name); id != exp {
+			t.Errorf("name: %s, exp: %s, got: %s", name, exp, id)
+		}
+	}
+}
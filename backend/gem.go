@@ -0,0 +1,321 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// GemSpecExtension is the extension of ruby gem specification files.
+	GemSpecExtension = ".gemspec"
+
+	// GemMetadataFilename is the file name of the compressed specification
+	// inside of a .gem file.
+	GemMetadataFilename = "metadata.gz"
+
+	// GemMaxMetadataSize is the largest uncompressed metadata that we will
+	// read, so that a malicious file can't use up all of the memory.
+	GemMaxMetadataSize = 1024 * 1024 * 10 // 10 MiB
+
+	// gemMetadataPrefix is how the yaml form of a specification starts.
+	gemMetadataPrefix = "--- !ruby/object:Gem::Specification"
+)
+
+var (
+	// ErrInvalidGemMetadata is an error used when the metadata is
+	// malformed.
+	ErrInvalidGemMetadata = errors.New("invalid gem metadata")
+
+	// GemNames maps the license strings that are common in gems, but which
+	// aren't SPDX IDs, to the SPDX IDs. The names which could mean more
+	// than one SPDX license, such as "BSD" or "Apache", are not here. This
+	// includes "GPL-2" and the like, since they don't say if later
+	// versions are allowed.
+	GemNames = map[string]string{
+		"ruby":          "Ruby",
+		"ruby license":  "Ruby",
+		"2-clause bsdl": "BSD-2-Clause",
+		"bsd-2":         "BSD-2-Clause",
+		"bsd-3":         "BSD-3-Clause",
+		"new bsd":       "BSD-3-Clause",
+		"apache2":       "Apache-2.0",
+		"mit/x11":       "MIT",
+		"the mit":       "MIT",
+	}
+
+	// gemLicenseRegexp matches the assignment to the license or licenses
+	// attribute in a gemspec, and is followed by the value.
+	gemLicenseRegexp = regexp.MustCompile(`\.licenses?\s*=\s*`)
+
+	// gemAttributeRegexp matches the assignment of a string literal to the
+	// name or version attribute in a gemspec.
+	gemAttributeRegexp = regexp.MustCompile(`\.(name|version)\s*=\s*["']([^"']*)["']`)
+
+	// gemStringRegexp matches a string literal in ruby.
+	gemStringRegexp = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+)
+
+// Gem is a backend for the specification of ruby gems. It reads the license and
+// licenses attributes of a .gemspec file, and of the yaml specification in the
+// metadata.gz file of a .gem, which is a tar file that the tar iterator unpacks.
+// Rubygems recommends SPDX IDs, but many gems use other names, and the common
+// ones are mapped to SPDX IDs.
+type Gem struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+}
+
+// String method returns the name of the backend.
+func (obj *Gem) String() string {
+	return "gem"
+}
+
+// ScanData is used to extract the licenses from the specification data.
+func (obj *Gem) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+	name := info.FileInfo.Name()
+	if !strings.HasSuffix(name, GemSpecExtension) && name != GemMetadataFilename {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() {
+		return nil, nil // skip
+	}
+	if len(data) == 0 {
+		return nil, nil // skip
+	}
+
+	var spec *GemSpec
+	var err error
+	if name == GemMetadataFilename {
+		spec, err = GemParseMetadata(data)
+	} else {
+		spec = GemParseSpec(data)
+	}
+	if err != nil {
+		// There is a parse error with the file, so we can't properly
+		// examine it for licensing information.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(err, "parse error"),
+		}
+		return result, nil
+	}
+	if spec == nil || len(spec.Licenses) == 0 {
+		return nil, nil // nothing was declared
+	}
+
+	licenseList := []*licenses.License{}
+	for _, x := range spec.Licenses {
+		xs, err := spdxExpressionLicenses(x)
+		if err != nil || !spdxValid(xs) {
+			xs = []*licenses.License{spdxLicense(GemID(x))}
+		}
+		for _, license := range xs {
+			if !licenses.InList(license, licenseList) {
+				licenseList = append(licenseList, license)
+			}
+		}
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: 1.0, // TODO: what should we put here?
+		Component:  spec.Component(),
+	}
+	return result, nil
+}
+
+// GemSpec is the license information in a gem specification.
+type GemSpec struct {
+	// Name is the name of the gem.
+	Name string
+
+	// Version is the version of the gem.
+	Version string
+
+	// Licenses is the list of license strings.
+	Licenses []string
+}
+
+// Component returns the name and version of the gem, or the empty string if
+// there's no name.
+func (obj *GemSpec) Component() string {
+	if obj.Name == "" {
+		return ""
+	}
+	if obj.Version == "" {
+		return obj.Name
+	}
+	return obj.Name + "@" + obj.Version
+}
+
+// GemParseSpec finds the license and licenses attributes in a .gemspec file. It
+// is ruby code, so we only understand the usual forms of these, which are a
+// string, an array of strings, or a %w array of words. The name and version are
+// only found if they are string literals.
+func GemParseSpec(data []byte) *GemSpec {
+	s := string(data)
+	spec := &GemSpec{}
+	for _, m := range gemAttributeRegexp.FindAllStringSubmatch(s, -1) {
+		if m[1] == "name" && spec.Name == "" {
+			spec.Name = m[2]
+		}
+		if m[1] == "version" && spec.Version == "" {
+			spec.Version = m[2]
+		}
+	}
+
+	for _, ix := range gemLicenseRegexp.FindAllStringIndex(s, -1) {
+		value := s[ix[1]:]
+		switch {
+		case strings.HasPrefix(value, "%w") || strings.HasPrefix(value, "%W"):
+			if len(value) < 3 {
+				continue
+			}
+			end := map[byte]string{'[': "]", '(': ")", '{': "}", '<': ">"}[value[2]]
+			if end == "" {
+				end = value[2:3]
+			}
+			if i := strings.Index(value[3:], end); i > -1 {
+				spec.Licenses = append(spec.Licenses, strings.Fields(value[3:3+i])...)
+			}
+			continue
+
+		case strings.HasPrefix(value, "["):
+			if i := strings.Index(value, "]"); i > -1 {
+				value = value[:i]
+			}
+
+		default: // only look at the rest of the line
+			if i := strings.Index(value, "\n"); i > -1 {
+				value = value[:i]
+			}
+			if m := gemStringRegexp.FindStringIndex(value); m != nil {
+				value = value[:m[1]] // only the first string
+			}
+		}
+		for _, m := range gemStringRegexp.FindAllStringSubmatch(value, -1) {
+			if x := strings.TrimSpace(m[1] + m[2]); x != "" {
+				spec.Licenses = append(spec.Licenses, x)
+			}
+		}
+	}
+	return spec
+}
+
+// GemParseMetadata parses the compressed yaml specification that is in the
+// metadata.gz file of a gem. We don't have a yaml parser, so it only looks for
+// the top-level name, version and licenses keys, in the form that rubygems
+// writes them. It returns nil if this isn't a gem specification.
+func GemParseMetadata(data []byte) (*GemSpec, error) {
+	z, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	b, err := io.ReadAll(io.LimitReader(z, GemMaxMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > GemMaxMetadataSize {
+		return nil, errwrap.Wrapf(ErrInvalidGemMetadata, "too large")
+	}
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	if !strings.HasPrefix(s, gemMetadataPrefix) {
+		return nil, nil
+	}
+
+	spec := &GemSpec{}
+	lines := strings.Split(s, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "name:"):
+			spec.Name = gemUnquote(strings.TrimPrefix(line, "name:"))
+
+		case strings.HasPrefix(line, "version:"):
+			// version: !ruby/object:Gem::Version
+			//   version: 1.2.3
+			if i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "version:") {
+				spec.Version = gemUnquote(strings.TrimPrefix(strings.TrimSpace(lines[i+1]), "version:"))
+			}
+
+		case strings.HasPrefix(line, "licenses:"):
+			value := strings.TrimSpace(strings.TrimPrefix(line, "licenses:"))
+			if strings.HasPrefix(value, "[") { // flow style
+				if !strings.HasSuffix(value, "]") {
+					return nil, errwrap.Wrapf(ErrInvalidGemMetadata, "unterminated licenses")
+				}
+				for _, x := range strings.Split(strings.Trim(value, "[]"), ",") {
+					if x = gemUnquote(x); x != "" {
+						spec.Licenses = append(spec.Licenses, x)
+					}
+				}
+				continue
+			}
+			for ; i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "- "); i++ {
+				if x := gemUnquote(strings.TrimPrefix(strings.TrimSpace(lines[i+1]), "- ")); x != "" {
+					spec.Licenses = append(spec.Licenses, x)
+				}
+			}
+		}
+	}
+	return spec, nil
+}
+
+// gemUnquote removes the spaces and any quotes around a yaml scalar.
+func gemUnquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+	return strings.TrimSpace(s)
+}
+
+// GemID returns the SPDX ID for a license string of a gem, or the string
+// unchanged if we don't know it.
+func GemID(name string) string {
+	if id, exists := GemNames[strings.ToLower(name)]; exists {
+		return id
+	}
+	if license, err := licenses.NameToLicense(name); err == nil {
+		return license.SPDX
+	}
+	return name
+}
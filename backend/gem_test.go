@@ -0,0 +1,71 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+)
+
+func TestGemBackend(t *testing.T) {
+	gemBackend := &backend.Gem{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	gz := func(s string) string {
+		var b bytes.Buffer
+		z := gzip.NewWriter(&b)
+		z.Write([]byte(s))
+		z.Close()
+		return b.String()
+	}
+	tests := []dataTest{
+		{"a.gemspec", "Gem::Specification.new do |s|\n  s.name = 'a'\n  s.version = '1.0'\n  s.license = \"MIT\" # comment 'x'\nend\n", "MIT", "a@1.0", false},
+		{"a.gemspec", "Gem::Specification.new do |spec|\n  spec.name = \"a\"\n  spec.version = A::VERSION\n  spec.licenses = [\"Ruby\",\n    'BSD-2-Clause']\nend\n", "BSD-2-Clause, Ruby", "a", false},
+		{"a.gemspec", "Gem::Specification.new do |s|\n  s.licenses = %w[Apache-2.0 GPL-2]\nend\n", "Apache-2.0, GPL-2(unknown)", "", false},
+		{"a.gemspec", "Gem::Specification.new do |s|\n  s.licenses = ['apache', 'Apache2', 'GPL-3', 'LGPL-3']\nend\n", "Apache-2.0, GPL-3(unknown), LGPL-3(unknown), apache(unknown)", "", false},
+		{"a.gemspec", "Gem::Specification.new do |s|\n  s.licenses = %w(Proprietary)\nend\n", "Proprietary(unknown)", "", false},
+		{"a.gemspec", "Gem::Specification.new do |s|\n  s.summary = 'no license'\nend\n", "", "", false},
+		{
+			"metadata.gz",
+			gz("--- !ruby/object:Gem::Specification\nname: rake\nversion: !ruby/object:Gem::Version\n  version: 13.0.6\nplatform: ruby\ndependencies:\n- !ruby/object:Gem::Dependency\n  name: other\nlicenses:\n- MIT\n- 'New BSD'\nmetadata: {}\n"),
+			"BSD-3-Clause, MIT",
+			"rake@13.0.6",
+			false,
+		},
+		{"metadata.gz", gz("--- !ruby/object:Gem::Specification\nname: a\nlicenses: [\"MIT\", Ruby]\n"), "MIT, Ruby", "a", false},
+		{"metadata.gz", gz("--- !ruby/object:Gem::Specification\nname: a\nlicenses: [MIT\n"), "", "", true},
+		{"metadata.gz", gz("not: a gem\n"), "", "", false},
+		{"metadata.gz", "not gzip", "", "", true},
+	}
+
+	testDataBackend(t, gemBackend, tests)
+}
@@ -0,0 +1,396 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// GoModVendorDir is the name of the directory that go modules are
+	// vendored into.
+	GoModVendorDir = "vendor"
+
+	// GoModModulesFilename is the file name of the list of vendored modules
+	// which is found in the vendor directory.
+	GoModModulesFilename = "modules.txt"
+)
+
+var (
+	// ErrInvalidGoModModules is an error used when the list of vendored
+	// modules is malformed.
+	ErrInvalidGoModModules = errors.New("invalid vendor/modules.txt")
+
+	// ErrGoModNoLicense is an error used when a vendored module doesn't
+	// have a license file.
+	ErrGoModNoLicense = errors.New("no license file found")
+)
+
+// GoMod is a backend for go modules which were vendored with `go mod vendor`. It
+// uses the vendor/modules.txt file to find the root directory of each vendored
+// module, and makes one determination for the whole module on that directory,
+// from the license files that are found there. The result is tagged with the
+// module path and version, so that the results can be grouped by dependency
+// instead of by each of the vendored files. The result for the modules.txt file
+// is the set of licenses of all of the vendored modules. The license files are
+// identified with the license classifier library.
+type GoMod struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+
+	classifier licenseTextClassifier
+
+	mutex sync.Mutex
+	// vendors caches the parsed modules.txt files, keyed by the path of
+	// the vendor directory. It stores nil if there isn't one.
+	vendors map[string]*goModVendor
+	// modules caches the determination for each module directory.
+	modules map[string]*goModDetermination
+}
+
+// String method returns the name of the backend.
+func (obj *GoMod) String() string {
+	return "gomod"
+}
+
+// Dependent returns true because the result for a module comes from the license
+// files in its directory.
+func (obj *GoMod) Dependent() bool {
+	return true
+}
+
+// ScanPath returns the determination for a vendored module if the path is the
+// root directory of one, or the set of licenses of all the vendored modules if
+// this is the vendor/modules.txt file.
+func (obj *GoMod) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
+	p := path.Path()
+	if info.FileInfo.IsDir() { // path.IsDir() should be the same.
+		return obj.module(ctx, info.Root.Path(), p)
+	}
+	if info.FileInfo.Name() == GoModModulesFilename && filepath.Base(filepath.Dir(p)) == GoModVendorDir {
+		return obj.modulesFile(ctx, filepath.Dir(p))
+	}
+	return nil, nil // skip
+}
+
+// module returns the determination for the directory if it is the root of a
+// vendored module, and nil otherwise. The search for the vendor directory stops
+// at the root directory of the scan.
+func (obj *GoMod) module(ctx context.Context, root, dir string) (*interfaces.Result, error) {
+	sep := string(filepath.Separator)
+	if !strings.Contains(dir, sep+GoModVendorDir+sep) {
+		return nil, nil // skip quickly
+	}
+
+	// use the closest vendor directory that has a list of modules
+	for d := filepath.Dir(dir); d != filepath.Dir(d) && insideDir(root, d); d = filepath.Dir(d) {
+		if filepath.Base(d) != GoModVendorDir {
+			continue
+		}
+		vendor, err := obj.vendor(d)
+		if err != nil {
+			return nil, nil // the modules.txt result shows this error
+		}
+		if vendor == nil {
+			continue
+		}
+		rel := filepath.ToSlash(strings.TrimPrefix(dir, d+sep))
+		module, exists := vendor.modules[rel]
+		if !exists {
+			return nil, nil // a package directory or something else
+		}
+		determination, err := obj.determine(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		return determination.result(module.Component()), nil
+	}
+	return nil, nil
+}
+
+// modulesFile returns the set of licenses of all the modules in the vendor
+// directory.
+func (obj *GoMod) modulesFile(ctx context.Context, vendorDir string) (*interfaces.Result, error) {
+	vendor, err := obj.vendor(vendorDir)
+	if err != nil {
+		// There is a parse error with the file, so we can't properly
+		// examine it for licensing information.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(err, "parse error"),
+		}
+		return result, nil
+	}
+	if vendor == nil || len(vendor.list) == 0 {
+		return nil, nil // nothing was vendored
+	}
+
+	result := &interfaces.Result{
+		Licenses:   []*licenses.License{},
+		Confidence: 1.0,
+	}
+	var errs error
+	for _, module := range vendor.list {
+		dir := filepath.Join(vendorDir, filepath.FromSlash(module.Path))
+		if fileInfo, err := os.Stat(dir); err != nil || !fileInfo.IsDir() {
+			continue // no packages were used from it
+		}
+		determination, err := obj.determine(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range determination.licenses {
+			if !licenses.InList(x, result.Licenses) {
+				result.Licenses = append(result.Licenses, x)
+			}
+		}
+		if determination.confidence < result.Confidence {
+			result.Confidence = determination.confidence
+		}
+		if determination.skip != nil {
+			errs = errwrap.Append(errs, errwrap.Wrapf(determination.skip, "module %s", module.Component()))
+		}
+	}
+	sort.Slice(result.Licenses, func(i, j int) bool {
+		return result.Licenses[i].String() < result.Licenses[j].String()
+	})
+	result.Skip = errs
+	return result, nil
+}
+
+// vendor returns the parsed list of modules in the vendor directory, or nil if
+// it doesn't have one.
+func (obj *GoMod) vendor(dir string) (*goModVendor, error) {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.vendors == nil {
+		obj.vendors = make(map[string]*goModVendor)
+	}
+	if vendor, exists := obj.vendors[dir]; exists {
+		if vendor != nil && vendor.err != nil {
+			return nil, vendor.err
+		}
+		return vendor, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, GoModModulesFilename))
+	if os.IsNotExist(err) {
+		obj.vendors[dir] = nil
+		return nil, nil
+	}
+	vendor := &goModVendor{
+		modules: make(map[string]*GoModModule),
+	}
+	obj.vendors[dir] = vendor
+	if err != nil {
+		vendor.err = err
+		return nil, err
+	}
+	if vendor.list, vendor.err = GoModParseModules(data); vendor.err != nil {
+		return nil, vendor.err
+	}
+	for _, module := range vendor.list {
+		vendor.modules[module.Path] = module
+	}
+	return vendor, nil
+}
+
+// determine identifies the license files in the root directory of a module. A
+// license file that can't be identified is returned by its name, unless some
+// other license file of the module was identified, since this is usually a
+// NOTICE or a similar file that goes along with a license.
+func (obj *GoMod) determine(ctx context.Context, dir string) (*goModDetermination, error) {
+	obj.mutex.Lock()
+	if obj.modules == nil {
+		obj.modules = make(map[string]*goModDetermination)
+	}
+	determination, exists := obj.modules[dir]
+	obj.mutex.Unlock()
+	if exists {
+		return determination, nil
+	}
+
+	determination = &goModDetermination{
+		licenses:   []*licenses.License{},
+		confidence: 1.0,
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "can't read module directory")
+	}
+	unknown := []*licenses.License{}
+	for _, entry := range entries { // sorted by name
+		if entry.IsDir() || !licenses.IsLicenseFile(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errwrap.Wrapf(err, "can't read license file")
+		}
+		license, confidence, err := obj.classifier.classify(ctx, string(data))
+		if err != nil {
+			return nil, err
+		}
+		if license == nil {
+			unknown = append(unknown, &licenses.License{
+				//SPDX: "",
+				Origin: "", // unknown!
+				Custom: entry.Name(),
+			})
+			continue
+		}
+		if !licenses.InList(license, determination.licenses) {
+			determination.licenses = append(determination.licenses, license)
+		}
+		if confidence < determination.confidence {
+			determination.confidence = confidence
+		}
+	}
+	if len(determination.licenses) == 0 {
+		determination.licenses = unknown
+	}
+	if len(determination.licenses) == 0 {
+		determination.skip = ErrGoModNoLicense
+	}
+
+	obj.mutex.Lock()
+	obj.modules[dir] = determination
+	obj.mutex.Unlock()
+	return determination, nil
+}
+
+// goModVendor is a parsed vendor/modules.txt file.
+type goModVendor struct {
+	list    []*GoModModule
+	modules map[string]*GoModModule // keyed by module path
+	err     error
+}
+
+// goModDetermination is the licensing of a module.
+type goModDetermination struct {
+	licenses   []*licenses.License
+	confidence float64
+	skip       error
+}
+
+// result returns a new result with this determination. A new one is needed each
+// time, since the scanner adds its own metadata to each result.
+func (obj *goModDetermination) result(component string) *interfaces.Result {
+	return &interfaces.Result{
+		Licenses:   obj.licenses,
+		Confidence: obj.confidence,
+		Skip:       obj.skip,
+		Component:  component,
+	}
+}
+
+// GoModModule is a module in the vendor/modules.txt file.
+type GoModModule struct {
+	// Path is the module path, which is also the directory it is vendored
+	// in.
+	Path string
+
+	// Version is the module version. It is empty for a module which is
+	// replaced by a local directory.
+	Version string
+
+	// Replace is the module path of the replacement if there is one.
+	Replace string
+
+	// ReplaceVersion is the version of the replacement if there is one.
+	// It is empty if the replacement is a local directory.
+	ReplaceVersion string
+}
+
+// Component returns the name and version of the module whose code was vendored,
+// in the `path@version` form. It is the replacement if there is one. A local
+// replacement has no version, so the original module path is returned alone.
+func (obj *GoModModule) Component() string {
+	if obj.Replace != "" && obj.ReplaceVersion != "" {
+		return obj.Replace + "@" + obj.ReplaceVersion
+	}
+	if obj.Replace != "" || obj.Version == "" {
+		return obj.Path
+	}
+	return obj.Path + "@" + obj.Version
+}
+
+// GoModParseModules parses the list of modules in a vendor/modules.txt file.
+// Each module has a line in the form `# path version [=> path [version]]`, which
+// is followed by the lines of annotations and packages which we don't need.
+func GoModParseModules(data []byte) ([]*GoModModule, error) {
+	modules := []*GoModModule{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			continue // an annotation, or a package
+		}
+		fields := strings.Fields(line[2:])
+		module := &GoModModule{}
+		ix := -1
+		for i, x := range fields {
+			if x == "=>" {
+				ix = i
+			}
+		}
+		lhs, rhs := fields, []string{}
+		if ix > -1 {
+			lhs, rhs = fields[:ix], fields[ix+1:]
+			if len(rhs) == 0 || len(rhs) > 2 {
+				return nil, errwrap.Wrapf(ErrInvalidGoModModules, "bad replacement: %s", line)
+			}
+			module.Replace = rhs[0]
+			if len(rhs) == 2 {
+				module.ReplaceVersion = rhs[1]
+			}
+		}
+		if len(lhs) == 0 || len(lhs) > 2 {
+			return nil, errwrap.Wrapf(ErrInvalidGoModModules, "bad module: %s", line)
+		}
+		module.Path = lhs[0]
+		if len(lhs) == 2 {
+			module.Version = lhs[1]
+		}
+		modules = append(modules, module)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
@@ -0,0 +1,151 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestGoModParseModules(t *testing.T) {
+	input := "# github.com/a/b v1.2.3\n## explicit; go 1.16\ngithub.com/a/b\ngithub.com/a/b/sub\n# example.com/c v0.1.0 => example.com/d v0.2.0\n## explicit\nexample.com/c\n# example.com/e => ../e\nexample.com/e\n"
+	exp := []*backend.GoModModule{
+		{Path: "github.com/a/b", Version: "v1.2.3"},
+		{Path: "example.com/c", Version: "v0.1.0", Replace: "example.com/d", ReplaceVersion: "v0.2.0"},
+		{Path: "example.com/e", Replace: "../e"},
+	}
+	modules, err := backend.GoModParseModules([]byte(input))
+	if err != nil {
+		t.Errorf("err: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(modules, exp) {
+		t.Errorf("out: %+v, exp out: %+v", modules, exp)
+	}
+	components := []string{}
+	for _, x := range modules {
+		components = append(components, x.Component())
+	}
+	if exp := []string{"github.com/a/b@v1.2.3", "example.com/d@v0.2.0", "example.com/e"}; !reflect.DeepEqual(components, exp) {
+		t.Errorf("out: %v, exp out: %v", components, exp)
+	}
+
+	if _, err := backend.GoModParseModules([]byte("# a v1 =>\n")); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestGoModBackend(t *testing.T) {
+	gomodBackend := &backend.GoMod{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	apache, err := os.ReadFile("../COPYING")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"vendor/modules.txt":             "# github.com/a/b v1.2.3\n## explicit\ngithub.com/a/b/sub\n# example.com/c v0.1.0\nexample.com/c\n# example.com/unused v1.0.0\n",
+		"vendor/github.com/a/b/LICENSE":  string(apache),
+		"vendor/github.com/a/b/NOTICE":   "This product includes software from B.\n",
+		"vendor/github.com/a/b/sub/x.go": "package sub\n",
+		"vendor/example.com/c/c.go":      "package c\n",
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		root      string // the scanned directory
+		output    string // joined licenses, or empty for no result
+		component string
+		skip      bool
+	}{
+		{"vendor/github.com/a/b/", "", "Apache-2.0", "github.com/a/b@v1.2.3", false},
+		{"vendor/github.com/a/b/sub/", "", "", "", false}, // a package
+		{"vendor/github.com/a/", "", "", "", false},
+		{"vendor/example.com/c/", "", "", "example.com/c@v0.1.0", true}, // no license
+		{"vendor/modules.txt", "", "Apache-2.0", "", true},
+		{"vendor/github.com/a/b/LICENSE", "", "", "", false},
+		{"vendor/github.com/a/b/", "vendor/", "Apache-2.0", "github.com/a/b@v1.2.3", false},
+		{"vendor/github.com/a/b/", "vendor/github.com/", "", "", false}, // modules.txt is outside
+	}
+
+	for i, test := range tests {
+		p := filepath.Join(dir, test.name)
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		var path safepath.Path = safepath.UnsafeParseIntoAbsFile(p)
+		if fileInfo.IsDir() {
+			path = safepath.UnsafeParseIntoAbsDir(p + "/")
+		}
+		info := &interfaces.Info{
+			FileInfo: fileInfo,
+			UID:      iterator.FileScheme + path.String(),
+			Root:     safepath.UnsafeParseIntoAbsDir(filepath.Join(dir, test.root) + "/"),
+		}
+		result, err := gomodBackend.ScanPath(context.Background(), path, info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		out, component := "", ""
+		if result != nil {
+			out = licenses.Join(result.Licenses)
+			component = result.Component
+		}
+		if out != test.output {
+			t.Errorf("test #%d: out: %v, exp out: %v", i, out, test.output)
+		}
+		if component != test.component {
+			t.Errorf("test #%d: component: %v, exp component: %v", i, component, test.component)
+		}
+		if skip := result != nil && result.Skip != nil; skip != test.skip {
+			t.Errorf("test #%d: skip: %v, exp skip: %v", i, skip, test.skip)
+		}
+	}
+}
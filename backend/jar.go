@@ -0,0 +1,286 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// JarManifestFilename is the file name of the manifest in the META-INF
+	// directory of a jar.
+	JarManifestFilename = "MANIFEST.MF"
+
+	// JarExternalLicense is the special Bundle-License value which says
+	// that the license is described somewhere else.
+	JarExternalLicense = "<<EXTERNAL>>"
+)
+
+var (
+	// ErrInvalidJarManifest is an error used when a manifest is malformed.
+	ErrInvalidJarManifest = errors.New("invalid jar manifest")
+)
+
+// Jar is a backend for the manifest file in a jar. The Zip iterator unpacks jar
+// files, and this reads the OSGi Bundle-License header in the main section of
+// the META-INF/MANIFEST.MF file. Each license in it can be an SPDX expression,
+// a name, or the url of the license, and the well-known urls are mapped to
+// SPDX IDs. The name, version and vendor of the bundle are returned as the
+// component, so that we know which library the licenses are for. The pom.xml
+// files that maven puts into the jar are read by the Pom backend.
+type Jar struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+}
+
+// String method returns the name of the backend.
+func (obj *Jar) String() string {
+	return "jar"
+}
+
+// ScanData is used to extract licenses from the manifest data.
+func (obj *Jar) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+	if info.FileInfo.Name() != JarManifestFilename {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() {
+		return nil, nil // skip
+	}
+	if len(data) == 0 {
+		return nil, nil // skip
+	}
+
+	manifest, err := JarParseManifest(data)
+	if err != nil {
+		// There is a parse error with the file, so we can't properly
+		// examine it for licensing information.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(err, "parse error"),
+		}
+		return result, nil
+	}
+	if manifest == nil {
+		return nil, nil // not a jar manifest
+	}
+
+	licenseList, subErr := manifest.Licenses()
+	if len(licenseList) == 0 && subErr == nil {
+		return nil, nil // nothing was declared
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	// We return any partial results, and even if we errored, because we can
+	// now notify the user of these issues separately.
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: 1.0, // TODO: what should we put here?
+		Skip:       errwrap.Wrapf(subErr, "jar sub-parser error"),
+		Component:  manifest.Component(),
+	}
+
+	return result, nil
+}
+
+// JarManifest is the main section of a jar manifest. The attribute names are
+// case insensitive, so they are stored in lower case.
+type JarManifest struct {
+	// Attributes maps the lower case attribute name to its value.
+	Attributes map[string]string
+}
+
+// Get returns the value of an attribute, or the empty string if it's missing.
+func (obj *JarManifest) Get(name string) string {
+	return strings.TrimSpace(obj.Attributes[strings.ToLower(name)])
+}
+
+// Component returns the name and version of the library, such as
+// `Apache Commons Lang@3.12.0`. The vendor is used as the name if there's
+// nothing better. It returns the empty string if there's no name.
+func (obj *JarManifest) Component() string {
+	name := ""
+	for _, x := range []string{"Bundle-Name", "Implementation-Title", "Bundle-SymbolicName", "Implementation-Vendor", "Bundle-Vendor"} {
+		// the symbolic name can have directives such as singleton
+		if name = strings.TrimSpace(strings.Split(obj.Get(x), ";")[0]); name != "" {
+			break
+		}
+	}
+	if name == "" || strings.HasPrefix(name, "%") { // a localized name
+		return ""
+	}
+	version := obj.Get("Bundle-Version")
+	if version == "" {
+		version = obj.Get("Implementation-Version")
+	}
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// Licenses returns the licenses in the Bundle-License attribute. Each clause
+// has a name and optional link and description attributes. The name is used if
+// it's an SPDX expression of known ids or a well-known name, otherwise the url
+// in the link or the name is looked up, and otherwise the name is returned as a
+// custom license.
+func (obj *JarManifest) Licenses() ([]*licenses.License, error) {
+	value := obj.Get("Bundle-License")
+	if value == "" || value == JarExternalLicense {
+		return nil, nil
+	}
+
+	clauses, err := jarSplit(value, ',')
+	if err != nil {
+		return nil, err
+	}
+	result := []*licenses.License{}
+	add := func(license *licenses.License) {
+		if !licenses.InList(license, result) {
+			result = append(result, license)
+		}
+	}
+	for _, clause := range clauses {
+		parts, err := jarSplit(clause, ';')
+		if err != nil {
+			return result, err
+		}
+		name := jarUnquote(parts[0])
+		if name == "" || name == JarExternalLicense {
+			continue
+		}
+		link := ""
+		for _, x := range parts[1:] {
+			kv := strings.SplitN(x, "=", 2)
+			if len(kv) != 2 {
+				return result, errwrap.Wrapf(ErrInvalidJarManifest, "invalid attribute: %s", x)
+			}
+			if strings.TrimSpace(kv[0]) == "link" {
+				link = jarUnquote(kv[1])
+			}
+		}
+
+		if xs, err := spdxExpressionLicenses(name); err == nil && spdxValid(xs) {
+			for _, license := range xs {
+				add(license)
+			}
+			continue
+		}
+		if license, err := licenses.NameToLicense(name); err == nil {
+			add(license)
+			continue
+		}
+		if license, err := licenses.URLToLicense(link); err == nil {
+			add(license)
+			continue
+		}
+		if license, err := licenses.URLToLicense(name); err == nil {
+			add(license)
+			continue
+		}
+		add(&licenses.License{
+			//SPDX: "",
+			Origin: "", // unknown!
+			Custom: name,
+		})
+	}
+	return result, nil
+}
+
+// JarParseManifest parses the main section of a jar manifest. A line that
+// starts with a space continues the value of the previous line. It returns nil
+// if there's no Manifest-Version attribute, since other things can use the
+// same file name.
+func JarParseManifest(data []byte) (*JarManifest, error) {
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	s = strings.TrimPrefix(s, "\ufeff") // some tools add a byte order mark
+
+	attributes := make(map[string]string)
+	last := ""
+	for i, line := range strings.Split(s, "\n") {
+		if line == "" {
+			break // the end of the main section
+		}
+		if strings.HasPrefix(line, " ") {
+			if last == "" {
+				return nil, errwrap.Wrapf(ErrInvalidJarManifest, "continuation on line %d", i+1)
+			}
+			attributes[last] += line[1:]
+			continue
+		}
+		ix := strings.Index(line, ":")
+		if ix < 1 {
+			return nil, errwrap.Wrapf(ErrInvalidJarManifest, "no attribute on line %d", i+1)
+		}
+		last = strings.ToLower(strings.TrimSpace(line[:ix]))
+		attributes[last] = strings.TrimPrefix(line[ix+1:], " ")
+	}
+
+	if _, exists := attributes["manifest-version"]; !exists {
+		return nil, nil
+	}
+	return &JarManifest{
+		Attributes: attributes,
+	}, nil
+}
+
+// jarSplit splits an OSGi header value at each separator which isn't inside of
+// double quotes.
+func jarSplit(input string, sep rune) ([]string, error) {
+	result := []string{}
+	quoted := false
+	start := 0
+	for i, c := range input {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			result = append(result, input[start:i])
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, errwrap.Wrapf(ErrInvalidJarManifest, "unterminated quote")
+	}
+	return append(result, input[start:]), nil
+}
+
+// jarUnquote removes the spaces and optional double quotes around a value.
+func jarUnquote(input string) string {
+	input = strings.TrimSpace(input)
+	if len(input) >= 2 && strings.HasPrefix(input, `"`) && strings.HasSuffix(input, `"`) {
+		input = input[1 : len(input)-1]
+	}
+	return strings.TrimSpace(input)
+}
@@ -0,0 +1,63 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+)
+
+func TestJarBackend(t *testing.T) {
+	jarBackend := &backend.Jar{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	tests := []dataTest{
+		{
+			backend.JarManifestFilename,
+			"Manifest-Version: 1.0\r\nBundle-Name: Apache Commons Lang\r\nBundle-Version: 3.12.0\r\nBundle-License: https://www.apache.org/licenses/LICENSE-2.0.tx\r\n t\r\n\r\nName: org/\r\nBundle-License: MIT\r\n",
+			"Apache-2.0",
+			"Apache Commons Lang@3.12.0",
+			false,
+		},
+		{
+			backend.JarManifestFilename,
+			"Manifest-Version: 1.0\nBundle-SymbolicName: org.example.a;singleton:=true\nImplementation-Version: 1.2\nBundle-License: \"Eclipse Public License\";link=\"http://www.eclipse.org/legal/epl-v10.html\", MIT OR Apache-2.0, Some License;description=\"a, b\"\n",
+			"(Apache-2.0 OR MIT), EPL-1.0, Some License(unknown)",
+			"org.example.a@1.2",
+			false,
+		},
+		{backend.JarManifestFilename, "Manifest-Version: 1.0\nImplementation-Vendor: Example\nBundle-License: <<EXTERNAL>>\n", "", "", false},
+		{backend.JarManifestFilename, "Manifest-Version: 1.0\nImplementation-Vendor: Example\nBundle-License: \"MIT\n", "", "Example", true},
+		{backend.JarManifestFilename, " continued\nManifest-Version: 1.0\n", "", "", true},
+		{backend.JarManifestFilename, "Created-By: not a jar\n", "", "", false},
+	}
+
+	testDataBackend(t, jarBackend, tests)
+}
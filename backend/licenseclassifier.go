@@ -28,6 +28,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/awslabs/yesiscan/interfaces"
 	"github.com/awslabs/yesiscan/util/errwrap"
@@ -180,3 +181,46 @@ func licenseclassifierResultHelper(result *results.LicenseType) (*interfaces.Res
 		Confidence: result.Confidence,
 	}, nil
 }
+
+// licenseTextClassifier identifies the license in the text of a license file
+// with the licenseclassifier library. It is used by the backends which find a
+// reference to a license file in some metadata. The classifier is built the
+// first time it is needed, since that takes a few seconds. It is safe to use
+// concurrently.
+type licenseTextClassifier struct {
+	mutex      sync.Mutex
+	classifier *licenseclassifier.License
+}
+
+// classify returns the license in the text and the confidence of the match. It
+// returns a nil license if it isn't confident about any of them.
+func (obj *licenseTextClassifier) classify(ctx context.Context, text string) (*licenses.License, float64, error) {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.classifier == nil {
+		classifier, err := licenseclassifier.New(licenseclassifier.DefaultConfidenceThreshold)
+		if err != nil {
+			return nil, 0.0, errwrap.Wrapf(err, "cannot create license classifier")
+		}
+		obj.classifier = classifier
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0.0, err
+	}
+
+	match := obj.classifier.NearestMatch(text) // nil if not confident
+	if match == nil || match.Name == "" {
+		return nil, 0.0, nil
+	}
+	license := &licenses.License{
+		SPDX: match.Name,
+	}
+	if err := license.Validate(); err != nil {
+		license = &licenses.License{
+			//SPDX: "",
+			Origin: "licenseclassifier.google.github.com",
+			Custom: match.Name,
+		}
+	}
+	return license, match.Confidence, nil
+}
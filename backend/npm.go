@@ -0,0 +1,572 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// NpmPackageFilename is the file name of the npm package metadata.
+	NpmPackageFilename = "package.json"
+
+	// NpmLockFilename is the file name of the npm lock file.
+	NpmLockFilename = "package-lock.json"
+
+	// NpmShrinkwrapFilename is the file name of the publishable version of
+	// the npm lock file. It has the same format as the lock file.
+	NpmShrinkwrapFilename = "npm-shrinkwrap.json"
+
+	// NpmModulesDir is the directory that npm installs the dependencies of
+	// a package into.
+	NpmModulesDir = "node_modules"
+
+	// NpmSeeLicenseIn is the prefix of the npm license field when the
+	// license is in a file in the package instead of an SPDX expression.
+	NpmSeeLicenseIn = "SEE LICENSE IN "
+)
+
+var (
+	// ErrInvalidNpmLicense is an error used in the NpmLicenseSubParser when
+	// a license expression is malformed.
+	ErrInvalidNpmLicense = errors.New("invalid npm license expression")
+)
+
+// Npm is a backend for the package.json files which store the metadata of npm
+// packages, and for their package-lock.json and npm-shrinkwrap.json lock files.
+// It makes one determination for each package, on the directory of the package,
+// which is tagged with the name and version of the package so that the results
+// can be told apart per dependency. The determination comes from the
+// package.json file in the directory if there is one, and otherwise from the
+// entry of the closest lock file which lists the directory as an installed
+// package. It reads the `license` field, which is an SPDX expression, or the
+// "SEE LICENSE IN <file>" form, and the legacy `licenses` list. The file named
+// in the "SEE LICENSE IN <file>" form is identified with the license classifier
+// library. Since many of the locked dependencies aren't installed in the tree,
+// the lock file result is the combined set of licenses declared by all of them,
+// like the modules.txt result of the gomod backend. Lock file versions 2 and 3
+// store these in the `packages` field, and version 1 files store these in the
+// `dependencies` field if at all.
+type Npm struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+
+	classifier licenseTextClassifier
+
+	mutex sync.Mutex
+
+	// locks caches the parsed lock files, keyed by their path. It stores
+	// nil for the lock files that don't exist.
+	locks map[string]*npmLockFile
+}
+
+// npmLockFile is a parsed lock file, or the error from parsing it.
+type npmLockFile struct {
+	installed map[string]*NpmLockPackage
+	err       error
+}
+
+// String method returns the name of the backend.
+func (obj *Npm) String() string {
+	return "npm"
+}
+
+// Dependent returns true because the result for a package directory comes from
+// the package.json and lock files in and above it.
+func (obj *Npm) Dependent() bool {
+	return true
+}
+
+// ScanPath returns the determination of the package if the path is a package
+// directory, or the combined licenses of the locked dependencies if it is a
+// lock file. The package.json file only has a result if it can't be parsed,
+// since its licenses are on the directory.
+func (obj *Npm) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
+	p := path.Path()
+	root := info.Root.Path()
+	if info.FileInfo.IsDir() {
+		return obj.directory(ctx, root, p)
+	}
+
+	switch info.FileInfo.Name() {
+	case NpmPackageFilename:
+		if info.FileInfo.Size() == 0 {
+			return nil, nil // skip
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, errwrap.Wrapf(err, "can't read file")
+		}
+		var pkg NpmPackage
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			// There is a parse error with the file, so we can't
+			// properly examine it for licensing information.
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       errwrap.Wrapf(err, "parse error"),
+			}
+			return result, nil
+		}
+		return nil, nil // the result is on the directory
+
+	case NpmLockFilename, NpmShrinkwrapFilename:
+		if info.FileInfo.Size() == 0 {
+			return nil, nil // skip
+		}
+		return obj.lockFile(ctx, root, p)
+	}
+
+	return nil, nil // skip
+}
+
+// directory returns the determination of the package in the directory. It
+// comes from the package.json file if there is one, and otherwise from the
+// closest lock file above it which lists it as an installed package.
+func (obj *Npm) directory(ctx context.Context, root, dir string) (*interfaces.Result, error) {
+	data, err := os.ReadFile(filepath.Join(dir, NpmPackageFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errwrap.Wrapf(err, "can't read package file")
+	}
+	if err == nil {
+		var pkg NpmPackage
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return nil, nil // the package.json result has the error
+		}
+		return obj.determine(ctx, root, dir, pkg.Component(), pkg.Fields())
+	}
+
+	// Only the installed dependencies are listed in a lock file.
+	sep := string(filepath.Separator)
+	if !strings.Contains(dir+sep, sep+NpmModulesDir+sep) {
+		return nil, nil // skip
+	}
+	for d := filepath.Dir(dir); insideDir(root, d); d = filepath.Dir(d) {
+		rel, err := filepath.Rel(d, dir)
+		if err != nil {
+			return nil, err // programming error
+		}
+		rel = filepath.ToSlash(rel)
+		// The shrinkwrap file takes precedence, like it does in npm.
+		for _, name := range []string{NpmShrinkwrapFilename, NpmLockFilename} {
+			lock := obj.lock(filepath.Join(d, name))
+			if lock == nil || lock.err != nil {
+				continue // the lock file result has the error
+			}
+			if pkg, exists := lock.installed[rel]; exists {
+				return obj.determine(ctx, root, dir, pkg.Component(rel), pkg.Fields())
+			}
+		}
+		if d == root || d == filepath.Dir(d) {
+			break
+		}
+	}
+	return nil, nil // not a package
+}
+
+// lockFile returns the combined licenses of all of the packages in the lock
+// file.
+func (obj *Npm) lockFile(ctx context.Context, root, p string) (*interfaces.Result, error) {
+	lock := obj.lock(p)
+	if lock == nil {
+		return nil, nil // it was removed
+	}
+	if lock.err != nil {
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(lock.err, "parse error"),
+		}
+		return result, nil
+	}
+
+	paths := []string{}
+	for rel := range lock.installed {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	licenseList := []*licenses.License{}
+	confidence := 1.0
+	var skip error
+	for _, rel := range paths {
+		// In an effort to short-circuit things if needed, we run a
+		// check ourselves and break out early if we see that we have
+		// cancelled early. Lock files can be quite large.
+		select {
+		case <-ctx.Done():
+			return nil, errwrap.Wrapf(ctx.Err(), "scanner ended early")
+		default:
+		}
+
+		pkg := lock.installed[rel]
+		// A license file can only be read if the package is installed.
+		dir := filepath.Join(filepath.Dir(p), filepath.FromSlash(rel))
+		if fileInfo, err := os.Stat(dir); err != nil || !fileInfo.IsDir() {
+			dir = ""
+		}
+		result, err := obj.determine(ctx, root, dir, pkg.Component(rel), pkg.Fields())
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			continue
+		}
+		for _, license := range result.Licenses {
+			if !licenses.InList(license, licenseList) {
+				licenseList = append(licenseList, license)
+			}
+		}
+		if result.Confidence < confidence {
+			confidence = result.Confidence
+		}
+		if result.Skip != nil {
+			skip = errwrap.Append(skip, errwrap.Wrapf(result.Skip, "package %s", rel))
+		}
+	}
+
+	if len(licenseList) == 0 && skip == nil {
+		return nil, nil // nothing was declared
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: confidence,
+		Skip:       skip,
+	}
+	return result, nil
+}
+
+// lock returns the parsed lock file at the path, or nil if it doesn't exist.
+// The result is cached, since each package directory looks up the lock files
+// above it.
+func (obj *Npm) lock(p string) *npmLockFile {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.locks == nil {
+		obj.locks = make(map[string]*npmLockFile)
+	}
+	if lock, exists := obj.locks[p]; exists {
+		return lock
+	}
+
+	var lock *npmLockFile
+	data, err := os.ReadFile(p)
+	if err == nil {
+		lock = &npmLockFile{}
+		var x NpmLock
+		if err := json.Unmarshal(data, &x); err != nil {
+			lock.err = err
+		} else {
+			lock.installed = x.Installed()
+		}
+	} else if !os.IsNotExist(err) {
+		lock = &npmLockFile{err: err}
+	}
+	obj.locks[p] = lock
+	return lock
+}
+
+// determine returns the determination of the package in the directory from its
+// license fields. If the directory is empty, then the package isn't installed,
+// and a "SEE LICENSE IN <file>" license is kept as a custom license.
+func (obj *Npm) determine(ctx context.Context, root, dir, component string, fields []json.RawMessage) (*interfaces.Result, error) {
+	licenseList := []*licenses.License{}
+	confidence := 1.0
+	var subErr error
+	var skip error
+	for _, field := range fields {
+		// If we find an unknown SPDX ID, we don't want to error,
+		// because that would allow someone to put junk in their code to
+		// prevent us scanning it. Instead, it's returned as a custom
+		// license. This includes the "UNLICENSED" value.
+		xs, err := NpmLicenseField(field)
+		if err != nil {
+			subErr = errwrap.Append(subErr, err) // store for later
+		}
+		for _, license := range xs {
+			name := strings.TrimPrefix(license.Custom, NpmSeeLicenseIn)
+			if license.Custom != "" && name != license.Custom && dir != "" {
+				result, err := obj.licenseFile(ctx, root, dir, name)
+				if err != nil {
+					return nil, err
+				}
+				license = result.Licenses[0]
+				if result.Confidence < confidence {
+					confidence = result.Confidence
+				}
+				skip = errwrap.Append(skip, result.Skip)
+			}
+			if !licenses.InList(license, licenseList) {
+				licenseList = append(licenseList, license)
+			}
+		}
+	}
+
+	if len(licenseList) == 0 && subErr == nil {
+		return nil, nil // nothing was declared
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	// We return any partial results, and even if we errored, because we can
+	// now notify the user of these issues separately.
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: confidence,
+		Skip:       errwrap.Append(errwrap.Wrapf(subErr, "npm sub-parser error"), skip),
+		Component:  component,
+	}
+	return result, nil
+}
+
+// licenseFile identifies the license file which is named relative to the
+// package dir. If the license classifier can't identify it, then the license
+// field is returned as a custom license, since it is the best description of it
+// that we have. A file outside of the root directory of the scan isn't read.
+func (obj *Npm) licenseFile(ctx context.Context, root, dir, name string) (*interfaces.Result, error) {
+	p := filepath.Join(dir, name)
+	result := &interfaces.Result{
+		Licenses: []*licenses.License{
+			{
+				//SPDX: "",
+				Origin: "", // unknown!
+				Custom: NpmSeeLicenseIn + name,
+			},
+		},
+		Confidence: 1.0, // TODO: what should we put here?
+	}
+
+	if filepath.IsAbs(name) || !insideRoot(root, p) {
+		result.Skip = errwrap.Wrapf(ErrOutsideRoot, "can't read license file")
+		return result, nil
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		result.Skip = errwrap.Wrapf(err, "can't read license file")
+		return result, nil
+	}
+
+	license, confidence, err := obj.classifier.classify(ctx, string(data))
+	if err != nil {
+		return nil, err
+	}
+	if license == nil { // not confident about any license
+		return result, nil
+	}
+	result.Licenses = []*licenses.License{license}
+	result.Confidence = confidence
+	return result, nil
+}
+
+// NpmPackage is the subset of the package.json fields that we look at.
+type NpmPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// License is usually an SPDX expression string, but it can also be an
+	// object with a type field in some older packages.
+	License json.RawMessage `json:"license"`
+
+	// Licenses is the deprecated list of license objects or strings.
+	Licenses json.RawMessage `json:"licenses"`
+}
+
+// Component returns the name and version of the package, or the empty string if
+// there's no name.
+func (obj *NpmPackage) Component() string {
+	return npmComponent(obj.Name, obj.Version)
+}
+
+// Fields returns the raw license fields that were found in the package.
+func (obj *NpmPackage) Fields() []json.RawMessage {
+	fields := []json.RawMessage{}
+	if len(obj.License) > 0 {
+		fields = append(fields, obj.License)
+	}
+	if len(obj.Licenses) == 0 {
+		return fields
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(obj.Licenses, &list); err != nil {
+		// not a list, so treat it like the single license field
+		return append(fields, obj.Licenses)
+	}
+	return append(fields, list...)
+}
+
+// NpmLock is the subset of the package-lock.json fields that contain licenses.
+type NpmLock struct {
+	LockfileVersion int `json:"lockfileVersion"`
+
+	// Packages is the map of package paths to packages that is used by
+	// lock file versions 2 and 3. The empty path is the root package.
+	Packages map[string]*NpmLockPackage `json:"packages"`
+
+	// Dependencies is the nested map of package names to packages that is
+	// used by lock file versions 1 and 2.
+	Dependencies map[string]*NpmLockPackage `json:"dependencies"`
+}
+
+// NpmLockPackage is a single locked package.
+type NpmLockPackage struct {
+	// Name is only set when it differs from the directory that the package
+	// is installed in, such as for an aliased package.
+	Name    string          `json:"name"`
+	Version string          `json:"version"`
+	License json.RawMessage `json:"license"`
+
+	// Link is true for a link to a workspace package, which has its own
+	// entry and its own package.json file.
+	Link bool `json:"link"`
+
+	// Dependencies is only used in version 1 lock files.
+	Dependencies map[string]*NpmLockPackage `json:"dependencies"`
+}
+
+// Installed returns the locked packages, keyed by the path of the directory
+// that they get installed in relative to the lock file, such as
+// "node_modules/a/node_modules/b". The root package is not included, since its
+// package.json is where it declares its license. If the packages field exists,
+// then the dependencies field is ignored, because it is only there for
+// compatibility with old npm.
+func (obj *NpmLock) Installed() map[string]*NpmLockPackage {
+	installed := make(map[string]*NpmLockPackage)
+	if len(obj.Packages) > 0 {
+		for p, pkg := range obj.Packages {
+			if p == "" || pkg == nil || pkg.Link {
+				continue
+			}
+			installed[p] = pkg
+		}
+		return installed
+	}
+
+	var walk func(string, map[string]*NpmLockPackage)
+	walk = func(prefix string, m map[string]*NpmLockPackage) {
+		for name, pkg := range m {
+			if pkg == nil {
+				continue
+			}
+			p := prefix + NpmModulesDir + "/" + name
+			installed[p] = pkg
+			walk(p+"/", pkg.Dependencies)
+		}
+	}
+	walk("", obj.Dependencies)
+	return installed
+}
+
+// Component returns the name and version of the package which is installed at
+// the path relative to the lock file. The name comes from the path unless the
+// package has its own.
+func (obj *NpmLockPackage) Component(p string) string {
+	name := obj.Name
+	if name == "" {
+		name = p
+		if i := strings.LastIndex(p, NpmModulesDir+"/"); i > -1 {
+			name = p[i+len(NpmModulesDir)+1:] // includes any @scope/
+		}
+	}
+	return npmComponent(name, obj.Version)
+}
+
+// Fields returns the raw license fields of the locked package.
+func (obj *NpmLockPackage) Fields() []json.RawMessage {
+	if len(obj.License) == 0 {
+		return nil
+	}
+	return []json.RawMessage{obj.License}
+}
+
+// npmComponent returns the name@version form of a package, or the empty string
+// if there's no name.
+func npmComponent(name, version string) string {
+	if name == "" {
+		return ""
+	}
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// NpmLicenseField parses one of the license fields from a package. It can be a
+// string, or an object with a type field, which is the old format.
+func NpmLicenseField(field json.RawMessage) ([]*licenses.License, error) {
+	var s string
+	if err := json.Unmarshal(field, &s); err == nil {
+		return NpmLicenseSubParser(s)
+	}
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(field, &obj); err == nil {
+		return NpmLicenseSubParser(obj.Type)
+	}
+	if string(field) == "null" {
+		return nil, nil
+	}
+	return nil, ErrInvalidNpmLicense
+}
+
+// NpmLicenseSubParser is used to parse the npm license string. It is usually an
+// SPDX expression, and a choice between licenses in it is kept as a single
+// license. The "SEE LICENSE IN <file>" and "UNLICENSED" values are returned as
+// custom licenses, and the Npm backend then identifies the file of the former.
+func NpmLicenseSubParser(input string) ([]*licenses.License, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, ErrInvalidNpmLicense
+	}
+	if strings.HasPrefix(input, NpmSeeLicenseIn) {
+		license := &licenses.License{
+			//SPDX: "",
+			Origin: "", // unknown!
+			Custom: input,
+		}
+		return []*licenses.License{license}, nil
+	}
+
+	licenseList, err := spdxExpressionLicenses(input)
+	if err != nil {
+		return nil, ErrInvalidNpmLicense
+	}
+	return licenseList, nil
+}
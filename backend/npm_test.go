@@ -0,0 +1,179 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestNpmLicenseSubParser(t *testing.T) {
+	errVal := backend.ErrInvalidNpmLicense
+	tests := []struct {
+		input  string
+		output string // joined licenses
+		err    error
+	}{
+		{"", "", errVal},
+		{"MIT", "MIT", nil},
+		{" MIT ", "MIT", nil},
+		{"(MIT OR Apache-2.0)", "(Apache-2.0 OR MIT)", nil},
+		{"(ISC AND (MIT OR BSD-3-Clause))", "ISC, (BSD-3-Clause OR MIT)", nil},
+		{"Apache-2.0 WITH LLVM-exception", "Apache-2.0 WITH LLVM-exception(unknown)", nil},
+		{"SEE LICENSE IN LICENSE.txt", "SEE LICENSE IN LICENSE.txt(unknown)", nil},
+		{"UNLICENSED", "UNLICENSED(unknown)", nil},
+		{"(MIT OR", "", errVal},
+		{"MIT OR", "", errVal},
+		{"OR MIT", "", errVal},
+		{"MIT WITH", "", errVal},
+	}
+
+	for i, test := range tests {
+		out, err := backend.NpmLicenseSubParser(test.input)
+		if err != test.err {
+			t.Errorf("test #%d: err: %v, exp err: %v", i, err, test.err)
+			continue
+		}
+		if s := licenses.Join(out); s != test.output {
+			t.Errorf("test #%d: out: %v, exp out: %v", i, s, test.output)
+			continue
+		}
+	}
+}
+
+func TestNpmBackend(t *testing.T) {
+	npmBackend := &backend.Npm{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	apache, err := os.ReadFile("../COPYING")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lock := `{"lockfileVersion": 3, "packages": {"": {"license": "GPL-2.0-only"}, "node_modules/a": {"version": "1.0.0", "license": "MIT"}, "node_modules/b": {"version": "2.0.0", "license": "(ISC OR MIT)"}, "node_modules/@s/c": {"version": "3.0.0"}, "node_modules/d": {"name": "e", "version": "4.0.0", "license": "SEE LICENSE IN LICENSE.txt"}}}`
+	tests := []struct {
+		files     map[string]string // the files in the root directory
+		path      string            // the file or directory to scan
+		output    string            // joined licenses, or empty for no result
+		component string
+		skip      bool
+	}{
+		{map[string]string{"package.json": `{"name": "a", "version": "1.0.0", "license": "(MIT OR Apache-2.0)"}`}, ".", "(Apache-2.0 OR MIT)", "a@1.0.0", false},
+		{map[string]string{"package.json": `{"name": "a", "version": "1.0.0", "license": "(MIT OR Apache-2.0)"}`}, "package.json", "", "", false},
+		{map[string]string{"package.json": `{"name": "a", "license": {"type": "ISC", "url": "https://example.com/"}}`}, ".", "ISC", "a", false},
+		{map[string]string{"package.json": `{"licenses": [{"type": "MIT"}, {"type": "GPL-2.0-only"}]}`}, ".", "GPL-2.0-only, MIT", "", false},
+		{map[string]string{"package.json": `{"licenses": ["BSD-3-Clause"]}`}, ".", "BSD-3-Clause", "", false},
+		{map[string]string{"package.json": `{"license": "SEE LICENSE IN LICENSE.txt"}`, "LICENSE.txt": string(apache)}, ".", "Apache-2.0", "", false},
+		{map[string]string{"package.json": `{"license": "SEE LICENSE IN EULA.md"}`, "EULA.md": "You may do nothing with this.\n"}, ".", "SEE LICENSE IN EULA.md(unknown)", "", false},
+		{map[string]string{"package.json": `{"license": "SEE LICENSE IN EULA.md"}`}, ".", "SEE LICENSE IN EULA.md(unknown)", "", true},
+		{map[string]string{"package.json": `{"license": "SEE LICENSE IN ../LICENSE"}`, "../LICENSE": string(apache)}, ".", "SEE LICENSE IN ../LICENSE(unknown)", "", true},
+		{map[string]string{"package.json": `{"name": "nothing"}`}, ".", "", "", false},
+		{map[string]string{"package.json": `{"license": 42}`}, ".", "", "", true},
+		{map[string]string{"package.json": `{`}, ".", "", "", false},
+		{map[string]string{"package.json": `{`}, "package.json", "", "", true},
+		{map[string]string{"index.json": `{"license": "MIT"}`}, "index.json", "", "", false},
+		{map[string]string{"package-lock.json": lock}, "package-lock.json", "(ISC OR MIT), MIT, SEE LICENSE IN LICENSE.txt(unknown)", "", false},
+		{map[string]string{"package-lock.json": lock, "node_modules/a/index.js": ""}, "node_modules/a", "MIT", "a@1.0.0", false},
+		{map[string]string{"package-lock.json": lock, "node_modules/@s/c/index.js": ""}, "node_modules/@s/c", "", "", false},
+		{map[string]string{"package-lock.json": lock, "node_modules/d/LICENSE.txt": string(apache)}, "node_modules/d", "Apache-2.0", "e@4.0.0", false},
+		{map[string]string{"package-lock.json": lock, "node_modules/d/LICENSE.txt": string(apache)}, "package-lock.json", "(ISC OR MIT), Apache-2.0, MIT", "", false},
+		{map[string]string{"package-lock.json": lock, "node_modules/a/package.json": `{"name": "a", "version": "1.0.1", "license": "0BSD"}`}, "node_modules/a", "0BSD", "a@1.0.1", false},
+		{map[string]string{"../package-lock.json": lock, "node_modules/a/index.js": ""}, "node_modules/a", "", "", false},
+		{map[string]string{"package-lock.json": `{`, "node_modules/a/index.js": ""}, "node_modules/a", "", "", false},
+		{map[string]string{"package-lock.json": `{`}, "package-lock.json", "", "", true},
+		{
+			map[string]string{"package-lock.json": `{"lockfileVersion": 2, "packages": {"node_modules/a": {"license": "MIT"}}, "dependencies": {"a": {"license": "ISC"}}}`},
+			"package-lock.json",
+			"MIT",
+			"",
+			false,
+		},
+		{
+			map[string]string{
+				"npm-shrinkwrap.json":             `{"lockfileVersion": 1, "dependencies": {"a": {"version": "1.0.0", "license": "MIT", "dependencies": {"b": {"version": "2.0.0", "license": "BSD-2-Clause"}}}}}`,
+				"node_modules/a/node_modules/b/x": "",
+			},
+			"node_modules/a/node_modules/b",
+			"BSD-2-Clause",
+			"b@2.0.0",
+			false,
+		},
+		{
+			map[string]string{"npm-shrinkwrap.json": `{"lockfileVersion": 1, "dependencies": {"a": {"license": "MIT", "dependencies": {"b": {"license": "BSD-2-Clause"}}}}}`},
+			"npm-shrinkwrap.json",
+			"BSD-2-Clause, MIT",
+			"",
+			false,
+		},
+		{map[string]string{"package-lock.json": `{"lockfileVersion": 1, "dependencies": {"a": {"version": "1.0.0"}}}`}, "package-lock.json", "", "", false},
+	}
+
+	for i, test := range tests {
+		dir := filepath.Join(t.TempDir(), "root")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		for name, data := range test.files {
+			p := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatalf("test #%d: err: %v", i, err)
+			}
+			if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+				t.Fatalf("test #%d: err: %v", i, err)
+			}
+		}
+		p := filepath.Join(dir, test.path)
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		info := &interfaces.Info{
+			FileInfo: fileInfo,
+			UID:      iterator.FileScheme + p,
+			Root:     safepath.UnsafeParseIntoAbsDir(dir),
+		}
+		var path safepath.Path = safepath.UnsafeParseIntoAbsFile(p)
+		if fileInfo.IsDir() {
+			path = safepath.UnsafeParseIntoAbsDir(p)
+		}
+		result, err := npmBackend.ScanPath(context.Background(), path, info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		checkResult(t, i, result, test.output, test.component, test.skip)
+	}
+}
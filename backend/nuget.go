@@ -0,0 +1,185 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// NugetSpecExtension is the extension of the nuget package manifest.
+	NugetSpecExtension = ".nuspec"
+
+	// NugetLicenseFilePrefix is the prefix of the custom license that is
+	// returned when the license is in a file in the package.
+	NugetLicenseFilePrefix = "license-file: "
+
+	// NugetDeprecatedLicenseURL is the placeholder licenseUrl that nuget
+	// writes into packages which use the license element instead.
+	NugetDeprecatedLicenseURL = "https://aka.ms/deprecateLicenseUrl"
+)
+
+var (
+	// ErrInvalidNugetLicense is an error used when the license element is
+	// malformed.
+	ErrInvalidNugetLicense = errors.New("invalid nuget license element")
+)
+
+// Nuget is a backend for the .nuspec manifest of nuget packages, which is also
+// found inside of every .nupkg file, which the zip iterator unpacks. It reads
+// the license element, which is either an SPDX expression or the name of a
+// file in the package, and the deprecated licenseUrl element, which is mapped
+// to an SPDX ID if it is the url of a well-known license.
+type Nuget struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+}
+
+// String method returns the name of the backend.
+func (obj *Nuget) String() string {
+	return "nuget"
+}
+
+// ScanData is used to extract the licenses from the manifest data.
+func (obj *Nuget) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+	if !strings.HasSuffix(info.FileInfo.Name(), NugetSpecExtension) {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() {
+		return nil, nil // skip
+	}
+	if len(data) == 0 {
+		return nil, nil // skip
+	}
+
+	var pkg NugetPackage
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		// There is a parse error with the file, so we can't properly
+		// examine it for licensing information.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(err, "parse error"),
+		}
+		return result, nil
+	}
+
+	licenseList, subErr := pkg.Licenses()
+	if len(licenseList) == 0 && subErr == nil {
+		return nil, nil // nothing was declared
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	// We return any partial results, and even if we errored, because we can
+	// now notify the user of these issues separately.
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: 1.0, // TODO: what should we put here?
+		Skip:       errwrap.Wrapf(subErr, "nuget sub-parser error"),
+		Component:  pkg.Component(),
+	}
+	return result, nil
+}
+
+// NugetPackage is the part of a .nuspec file that we look at.
+type NugetPackage struct {
+	ID      string `xml:"metadata>id"`
+	Version string `xml:"metadata>version"`
+
+	// License is the license element, or nil if there isn't one.
+	License *NugetLicense `xml:"metadata>license"`
+
+	// LicenseURL is the deprecated url of the license.
+	LicenseURL string `xml:"metadata>licenseUrl"`
+}
+
+// NugetLicense is the license element of a .nuspec file.
+type NugetLicense struct {
+	// Type is either `expression` or `file`.
+	Type string `xml:"type,attr"`
+
+	// Value is the SPDX expression or the path of the file.
+	Value string `xml:",chardata"`
+}
+
+// Component returns the id and version of the package, or the empty string if
+// there's no id.
+func (obj *NugetPackage) Component() string {
+	id, version := strings.TrimSpace(obj.ID), strings.TrimSpace(obj.Version)
+	if id == "" {
+		return ""
+	}
+	if version == "" {
+		return id
+	}
+	return id + "@" + version
+}
+
+// Licenses returns the licenses that the package declares. The license element
+// is used if there is one, and otherwise the licenseUrl is. A license file is
+// returned as a custom license with its name, since the other backends will
+// scan the file itself.
+func (obj *NugetPackage) Licenses() ([]*licenses.License, error) {
+	if obj.License != nil {
+		value := strings.TrimSpace(obj.License.Value)
+		switch strings.TrimSpace(obj.License.Type) {
+		case "expression":
+			return spdxExpressionLicenses(value)
+		case "file":
+			return []*licenses.License{
+				{
+					//SPDX: "",
+					Origin: "", // unknown!
+					Custom: NugetLicenseFilePrefix + value,
+				},
+			}, nil
+		}
+		return nil, errwrap.Wrapf(ErrInvalidNugetLicense, "unknown type: %s", obj.License.Type)
+	}
+
+	u := strings.TrimSpace(obj.LicenseURL)
+	if u == "" || u == NugetDeprecatedLicenseURL {
+		return nil, nil
+	}
+	if license, err := licenses.URLToLicense(u); err == nil {
+		return []*licenses.License{license}, nil
+	}
+	return []*licenses.License{
+		{
+			//SPDX: "",
+			Origin: "", // unknown!
+			Custom: u,
+		},
+	}, nil
+}
@@ -0,0 +1,54 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+)
+
+func TestNugetBackend(t *testing.T) {
+	nugetBackend := &backend.Nuget{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	tests := []dataTest{
+		{"a.nuspec", `<?xml version="1.0"?><package xmlns="http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd"><metadata><id>A</id><version>1.0.0</version><license type="expression">MIT</license><licenseUrl>https://aka.ms/deprecateLicenseUrl</licenseUrl></metadata></package>`, "MIT", "A@1.0.0", false},
+		{"a.nuspec", `<package><metadata><id>A</id><license type="expression">Apache-2.0 OR MIT</license></metadata></package>`, "(Apache-2.0 OR MIT)", "A", false},
+		{"a.nuspec", `<package><metadata><id>A</id><license type="file">LICENSE.txt</license></metadata></package>`, "license-file: LICENSE.txt(unknown)", "A", false},
+		{"a.nuspec", `<package><metadata><licenseUrl>http://www.apache.org/licenses/LICENSE-2.0</licenseUrl></metadata></package>`, "Apache-2.0", "", false},
+		{"a.nuspec", `<package><metadata><licenseUrl>https://example.com/eula</licenseUrl></metadata></package>`, "https://example.com/eula(unknown)", "", false},
+		{"a.nuspec", `<package><metadata><license type="weird">x</license></metadata></package>`, "", "", true},
+		{"a.nuspec", `<package><metadata><id>A</id></metadata></package>`, "", "", false},
+		{"a.nuspec", `<package><metadata>`, "", "", true},
+		{"a.xml", `<package><metadata><license type="expression">MIT</license></metadata></package>`, "", "", false},
+	}
+
+	testDataBackend(t, nugetBackend, tests)
+}
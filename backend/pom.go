@@ -27,24 +27,62 @@ package backend
 import (
 	"context"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/awslabs/yesiscan/interfaces"
 	"github.com/awslabs/yesiscan/util/errwrap"
 	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
 )
 
 const (
 	// PomFilename is the file name used by the pomfiles.
 	PomFilename = "pom.xml"
+
+	// PomExtension is the extension of the pomfiles in a maven repository,
+	// which are named like `artifactId-version.pom`.
+	PomExtension = ".pom"
+
+	// PomRelativePath is the default path of the parent pomfile.
+	PomRelativePath = "../" + PomFilename
+
+	// PomMaxParents is the most parents that we will follow to find the
+	// inherited licenses.
+	PomMaxParents = 16
+)
+
+var (
+	// ErrPomParentNotFound is an error used when a pomfile inherits from a
+	// parent that we can't find.
+	ErrPomParentNotFound = errors.New("pom parent not found")
+
+	// ErrPomParentLoop is an error used when the parents of a pomfile form
+	// a loop, or there are too many of them.
+	ErrPomParentLoop = errors.New("pom parent loop")
 )
 
 // Pom is a backend for Pom or Project Object Model files. It is an xml file
 // commonly used by the Maven Project under the name pom.xml. We are getting the
-// license names by parsing the pom.xml file.
+// licenses by parsing the pom.xml file. Each license has a name and a url, which
+// are mapped to SPDX IDs with the tables in the licenses package, since they
+// are rarely SPDX IDs themselves. The comments of a license are used if neither
+// of those are known, and to see if a later version is allowed. If a pomfile
+// doesn't have any licenses, then it inherits them from its parent, which is
+// found at the relative path of the parent like maven does, or in the local
+// maven repository.
 type Pom struct {
 	Debug bool
 	Logf  func(format string, v ...interface{})
+
+	// Repository is the path to the local maven repository, where parents
+	// are looked for if they aren't found at their relative path. If it is
+	// empty, then the usual ~/.m2/repository is used.
+	Repository string
 }
 
 // String method returns the name of the backend.
@@ -52,26 +90,34 @@ func (obj *Pom) String() string {
 	return "pom"
 }
 
-// ScanData method is used to extract license ids from data and return licenses
-// based on the license ids.
-func (obj *Pom) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+// Dependent returns true because the licenses can be inherited from a parent
+// pomfile.
+func (obj *Pom) Dependent() bool {
+	return true
+}
+
+// ScanPath method is used to extract licenses from the pomfile at the path, or
+// from its parents, and return them.
+func (obj *Pom) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
 	// This check is taking place with the assumption that the file that will be
-	// scanned will have to be named "pom.xml".
-	if info.FileInfo.Name() != PomFilename {
+	// scanned will have to be named "pom.xml", or be in a maven repository.
+	name := info.FileInfo.Name()
+	if name != PomFilename && !strings.HasSuffix(name, PomExtension) {
 		return nil, nil // skip
 	}
-	if info.FileInfo.IsDir() {
+	if info.FileInfo.IsDir() { // path.IsDir() should be the same.
 		return nil, nil // skip
 	}
-	if len(data) == 0 {
+	if info.FileInfo.Size() == 0 {
 		return nil, nil // skip
 	}
 
-	licenseMap := make(map[string]struct{})
-	var pomFileLicenses PomLicenses
-
-	// parsing pom.xml file to get license names in struct
-	if err := xml.Unmarshal(data, &pomFileLicenses); err != nil {
+	data, err := os.ReadFile(path.Path())
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "can't read file")
+	}
+	project, err := PomParse(data)
+	if err != nil {
 		// There is a parse error with the file, so we can't properly
 		// examine it for licensing information with this pom scanner.
 		result := &interfaces.Result{
@@ -81,49 +127,42 @@ func (obj *Pom) ScanData(ctx context.Context, data []byte, info *interfaces.Info
 		return result, nil
 	}
 
-	if len(pomFileLicenses.Names) == 0 {
-		// If we did not get any license names from the pom file we return nil, nil.
+	pomLicenses, err := obj.inherit(info.Root.Path(), path.Path(), project)
+	if errors.Is(err, ErrPomParentNotFound) {
+		// Most parents are only published in a remote repository such
+		// as maven central, so this is common and isn't an error.
+		if obj.Debug {
+			obj.Logf("%s: %v", path.Path(), err)
+		}
 		return nil, nil
 	}
-
-	// lid is license id
-	for _, lid := range pomFileLicenses.Names {
-		licenseMap[lid] = struct{}{}
-	}
-
-	ids := []string{}
-	for id := range licenseMap {
-		ids = append(ids, id)
+	if err != nil {
+		// We didn't find any licenses, and it's probably because
+		// the parents form a loop.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       err,
+		}
+		return result, nil
 	}
-	sort.Strings(ids) // deterministic order
 
 	licenseList := []*licenses.License{}
-
-	for _, id := range ids {
-		license := &licenses.License{
-			SPDX: id,
-			// TODO: populate other fields here?
-		}
-
-		// If we find an unknown SPDX ID, we don't want to error, because that would
-		// allow someone to put junk in their code to prevent us scanning it. Instead,
-		// create an invalid license but return it anyways. If we ever want to check
-		// validity, we know to expect failures.
-		// XXX: Many Pom licenses are not SPDX, therefore we might want to add an alias
-		// matcher in the future.
-		if err := license.Validate(); err != nil {
-			//return nil, err
-			license = &licenses.License{
-				//SPDX: "",
-				Origin: "", // unknown!
-				Custom: id,
-				// TODO: populate other fields here (eg: found license text)
-			}
+	for _, x := range pomLicenses {
+		license := x.License()
+		if license == nil || licenses.InList(license, licenseList) {
+			continue
 		}
-
 		licenseList = append(licenseList, license)
 	}
 
+	if len(licenseList) == 0 {
+		// If we did not get any licenses from the pom file we return nil, nil.
+		return nil, nil
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
 	result := &interfaces.Result{
 		Licenses:   licenseList,
 		Confidence: 1.0, // TODO: what should we put here?
@@ -132,9 +171,240 @@ func (obj *Pom) ScanData(ctx context.Context, data []byte, info *interfaces.Info
 	return result, nil
 }
 
-// PomLicenses is a struct that helps store license names from the licenses
-// field in a pom.xml file.
-type PomLicenses struct {
-	// Names is a variable that will store the license names from pom.xml.
-	Names []string `xml:"licenses>license>name"`
+// inherit returns the licenses of the project, or of the closest parent that
+// has some, since a child replaces all of the licenses of its parent. It errors
+// if a parent can't be found.
+func (obj *Pom) inherit(root, p string, project *PomProject) ([]*PomLicense, error) {
+	seen := map[string]struct{}{p: {}}
+	for {
+		if len(project.Licenses) > 0 {
+			return project.Licenses, nil
+		}
+		if project.Parent == nil {
+			return nil, nil // nothing was declared
+		}
+		if len(seen) > PomMaxParents {
+			return nil, ErrPomParentLoop
+		}
+
+		var err error
+		p, project, err = obj.parent(root, p, project.Parent)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := seen[p]; exists {
+			return nil, ErrPomParentLoop
+		}
+		seen[p] = struct{}{}
+		if obj.Debug {
+			obj.Logf("pom parent: %s", p)
+		}
+	}
+}
+
+// parent finds the parent of the pomfile at the path, and returns its path and
+// the parsed parent. It looks at the relative path first, which is how parents
+// in the same scan tree are found, and then in the local maven repository. The
+// relative path isn't used if it's outside of the root directory of the scan.
+// The pomfile that it finds has to have the coordinates of the parent.
+func (obj *Pom) parent(root, p string, parent *PomParent) (string, *PomProject, error) {
+	rel := PomRelativePath
+	if parent.RelativePath != nil { // an empty one turns the lookup off
+		rel = strings.TrimSpace(*parent.RelativePath)
+	}
+	if rel != "" && !filepath.IsAbs(rel) {
+		f := filepath.Join(filepath.Dir(p), filepath.FromSlash(rel))
+		if fileInfo, err := os.Stat(f); err == nil && fileInfo.IsDir() {
+			f = filepath.Join(f, PomFilename)
+		}
+		if insideRoot(root, f) {
+			project, err := pomRead(f)
+			if err == nil && project.Is(parent) {
+				return f, project, nil
+			}
+			if err != nil && !os.IsNotExist(err) && obj.Debug {
+				obj.Logf("can't read possible pom parent %s: %v", f, err)
+			}
+		}
+	}
+
+	repository := obj.Repository
+	if repository == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil, errwrap.Wrapf(err, "can't find home directory")
+		}
+		repository = filepath.Join(home, ".m2", "repository")
+	}
+	if f := parent.RepositoryPath(repository); f != "" {
+		project, err := pomRead(f)
+		if err == nil && project.Is(parent) {
+			return f, project, nil
+		}
+		if err != nil && !os.IsNotExist(err) && obj.Debug {
+			obj.Logf("can't read possible pom parent %s: %v", f, err)
+		}
+	}
+
+	return "", nil, errwrap.Wrapf(ErrPomParentNotFound, "parent %s", parent)
+}
+
+// PomProject is the part of a pom.xml file that we look at.
+type PomProject struct {
+	// GroupID is the group of the project. It is inherited from the parent
+	// if it is empty.
+	GroupID string `xml:"groupId"`
+
+	// ArtifactID is the name of the project.
+	ArtifactID string `xml:"artifactId"`
+
+	// Version is the version of the project. It is inherited from the
+	// parent if it is empty.
+	Version string `xml:"version"`
+
+	// Parent is the parent of the project, or nil if it has none.
+	Parent *PomParent `xml:"parent"`
+
+	// Licenses is a variable that will store the licenses from pom.xml.
+	Licenses []*PomLicense `xml:"licenses>license"`
+}
+
+// Is returns true if this project has the coordinates of the parent. A version
+// which uses a property isn't compared, since we don't evaluate those.
+func (obj *PomProject) Is(parent *PomParent) bool {
+	groupID, version := strings.TrimSpace(obj.GroupID), strings.TrimSpace(obj.Version)
+	if obj.Parent != nil && groupID == "" {
+		groupID = strings.TrimSpace(obj.Parent.GroupID)
+	}
+	if obj.Parent != nil && version == "" {
+		version = strings.TrimSpace(obj.Parent.Version)
+	}
+	if groupID != strings.TrimSpace(parent.GroupID) || strings.TrimSpace(obj.ArtifactID) != strings.TrimSpace(parent.ArtifactID) {
+		return false
+	}
+	v := strings.TrimSpace(parent.Version)
+	if strings.Contains(version, "${") || strings.Contains(v, "${") {
+		return true
+	}
+	return version == v
+}
+
+// PomParent is the parent element of a pom.xml file.
+type PomParent struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+
+	// RelativePath is the path of the parent pomfile, or its directory. It
+	// is nil if it isn't set, and then the default is used.
+	RelativePath *string `xml:"relativePath"`
+}
+
+// String returns the coordinates of the parent in the usual maven format.
+func (obj *PomParent) String() string {
+	return fmt.Sprintf("%s:%s:%s", strings.TrimSpace(obj.GroupID), strings.TrimSpace(obj.ArtifactID), strings.TrimSpace(obj.Version))
+}
+
+// RepositoryPath returns the path of the parent pomfile inside of a maven
+// repository. It returns the empty string if the coordinates aren't complete.
+func (obj *PomParent) RepositoryPath(repository string) string {
+	groupID := strings.TrimSpace(obj.GroupID)
+	artifactID := strings.TrimSpace(obj.ArtifactID)
+	version := strings.TrimSpace(obj.Version)
+	if groupID == "" || artifactID == "" || version == "" || strings.Contains(version, "${") {
+		return ""
+	}
+	if strings.ContainsAny(groupID+artifactID+version, `/\`) || strings.Contains(groupID+artifactID+version, "..") {
+		return "" // don't let these leave the repository
+	}
+	dir := filepath.Join(append([]string{repository}, strings.Split(groupID, ".")...)...)
+	return filepath.Join(dir, artifactID, version, artifactID+"-"+version+PomExtension)
+}
+
+// PomLicense is a single license element in a pom.xml file. Maven doesn't say
+// what the name should be, so it is often a human readable name rather than an
+// SPDX ID.
+type PomLicense struct {
+	// Name is the name of the license.
+	Name string `xml:"name"`
+
+	// URL is the url of the license text.
+	URL string `xml:"url"`
+
+	// Distribution is either `repo` or `manual`, and says whether the
+	// project may be downloaded from a repository. It doesn't change which
+	// license it is.
+	Distribution string `xml:"distribution"`
+
+	// Comments is some free form text about the license.
+	Comments string `xml:"comments"`
+}
+
+// License returns the license that this element describes. A well-known name
+// wins, then a well-known url, and then comments which are a well-known name.
+// If the name or comments say that a later version may be used, then an `-only`
+// GNU license is changed to the `-or-later` one. Otherwise we return a custom
+// license with the name, or the url if there is no name. It returns nil if the
+// element is empty.
+func (obj *PomLicense) License() *licenses.License {
+	name := strings.TrimSpace(obj.Name)
+	u := strings.TrimSpace(obj.URL)
+	comments := strings.TrimSpace(obj.Comments)
+
+	var license *licenses.License
+	if x, err := licenses.NameToLicense(name); err == nil {
+		license = x
+	} else if x, err := licenses.URLToLicense(u); err == nil {
+		license = x
+	} else if x, err := licenses.NameToLicense(comments); err == nil {
+		license = x
+	}
+	if license != nil {
+		hints := strings.ToLower(name + " " + comments)
+		later := strings.Contains(hints, "or later") || strings.Contains(hints, "later version")
+		if later && strings.HasSuffix(license.SPDX, "-only") {
+			x := &licenses.License{
+				SPDX: strings.TrimSuffix(license.SPDX, "-only") + "-or-later",
+			}
+			if err := x.Validate(); err == nil {
+				return x
+			}
+		}
+		return license
+	}
+
+	// If we find an unknown SPDX ID, we don't want to error, because that would
+	// allow someone to put junk in their code to prevent us scanning it. Instead,
+	// create an invalid license but return it anyways. If we ever want to check
+	// validity, we know to expect failures.
+	if name == "" {
+		name = u
+	}
+	if name == "" {
+		return nil
+	}
+	return &licenses.License{
+		//SPDX: "",
+		Origin: "", // unknown!
+		Custom: name,
+		// TODO: populate other fields here (eg: found license text)
+	}
+}
+
+// PomParse parses the fields that we need from a pom.xml file.
+func PomParse(data []byte) (*PomProject, error) {
+	project := &PomProject{}
+	if err := xml.Unmarshal(data, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// pomRead reads and parses the pomfile at the path.
+func pomRead(p string) (*PomProject, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return PomParse(data)
 }
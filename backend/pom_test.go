@@ -0,0 +1,180 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestPomBackend(t *testing.T) {
+	parent := "<parent><groupId>org.example</groupId><artifactId>parent</artifactId><version>1</version></parent>"
+	tests := []struct {
+		files  map[string]string // the project is in the project/ directory of the root
+		output string            // joined licenses, or empty for no result
+		skip   bool
+	}{
+		{
+			map[string]string{"project/pom.xml": `<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <licenses>
+    <license>
+      <name>The Apache Software License, Version 2.0</name>
+      <url>http://www.apache.org/licenses/LICENSE-2.0.txt</url>
+      <distribution>repo</distribution>
+    </license>
+    <license>
+      <name>Some License</name>
+      <url>https://opensource.org/licenses/MIT</url>
+    </license>
+    <license>
+      <url>https://example.com/LICENSE</url>
+    </license>
+    <license>
+      <name>GPL2</name>
+      <comments>GNU General Public License, version 2 or (at your option) any later version</comments>
+    </license>
+  </licenses>
+</project>`},
+			"Apache-2.0, GPL-2.0-or-later, MIT, https://example.com/LICENSE(unknown)",
+			false,
+		},
+		{
+			map[string]string{"project/pom.xml": "<project><licenses><license><name>Eclipse Public License - v 1.0</name></license><license><name>mit</name></license><license><name>Company License</name><comments>ISC License</comments></license></licenses></project>"},
+			"EPL-1.0, ISC, MIT",
+			false,
+		},
+		{
+			map[string]string{
+				"pom.xml":         "<project><groupId>org.example</groupId><artifactId>parent</artifactId><version>1</version><licenses><license><name>New BSD License</name></license></licenses></project>",
+				"project/pom.xml": "<project>" + parent + "<artifactId>a</artifactId></project>",
+			},
+			"BSD-3-Clause",
+			false,
+		},
+		{
+			map[string]string{
+				"pom.xml":         "<project><groupId>org.example</groupId><artifactId>other</artifactId><version>1</version><licenses><license><name>MIT</name></license></licenses></project>",
+				"project/pom.xml": "<project>" + parent + "<artifactId>a</artifactId></project>",
+				"repository/org/example/parent/1/parent-1.pom":           "<project><parent><groupId>org.example</groupId><artifactId>grandparent</artifactId><version>2</version><relativePath/></parent><artifactId>parent</artifactId><version>1</version></project>",
+				"repository/org/example/grandparent/2/grandparent-2.pom": "<project><groupId>org.example</groupId><artifactId>grandparent</artifactId><version>2</version><licenses><license><name>Apache 2</name></license></licenses></project>",
+			},
+			"Apache-2.0",
+			false,
+		},
+		{
+			map[string]string{
+				"pom.xml":         "<project><groupId>org.example</groupId><artifactId>parent</artifactId><version>1</version><licenses><license><name>MIT</name></license></licenses></project>",
+				"project/pom.xml": "<project>" + parent + "<artifactId>a</artifactId><licenses><license><name>ISC</name></license></licenses></project>",
+			},
+			"ISC",
+			false,
+		},
+		{
+			map[string]string{"project/pom.xml": "<project>" + parent + "<artifactId>a</artifactId></project>"},
+			"",
+			false,
+		},
+		{
+			map[string]string{
+				"pom.xml":         "<project><parent><groupId>org.example</groupId><artifactId>a</artifactId><version>1</version><relativePath>project</relativePath></parent><artifactId>parent</artifactId></project>",
+				"project/pom.xml": "<project>" + parent + "<groupId>org.example</groupId><artifactId>a</artifactId></project>",
+			},
+			"",
+			true,
+		},
+		{
+			map[string]string{"project/pom.xml": "<project><artifactId>a</artifactId></project>"},
+			"",
+			false,
+		},
+		{
+			map[string]string{
+				"../pom.xml":      "<project><groupId>org.example</groupId><artifactId>parent</artifactId><version>1</version><licenses><license><name>MIT</name></license></licenses></project>",
+				"project/pom.xml": "<project><parent><groupId>org.example</groupId><artifactId>parent</artifactId><version>1</version><relativePath>../../pom.xml</relativePath></parent><artifactId>a</artifactId></project>",
+			},
+			"",
+			false,
+		},
+		{
+			map[string]string{"project/pom.xml": "<project><licenses>"},
+			"",
+			true,
+		},
+	}
+
+	for i, test := range tests {
+		dir := filepath.Join(t.TempDir(), "root")
+		for name, data := range test.files {
+			p := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatalf("test #%d: err: %v", i, err)
+			}
+			if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+				t.Fatalf("test #%d: err: %v", i, err)
+			}
+		}
+		pomBackend := &backend.Pom{
+			Debug: false,
+			Logf: func(format string, v ...interface{}) {
+				t.Logf("backend: "+format, v...)
+			},
+			Repository: filepath.Join(dir, "repository"),
+		}
+		p := filepath.Join(dir, "project", backend.PomFilename)
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		info := &interfaces.Info{
+			FileInfo: fileInfo,
+			UID:      iterator.FileScheme + p,
+			Root:     safepath.UnsafeParseIntoAbsDir(dir),
+		}
+		result, err := pomBackend.ScanPath(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		out := ""
+		if result != nil {
+			out = licenses.Join(result.Licenses)
+		}
+		if out != test.output {
+			t.Errorf("test #%d: out: %v, exp out: %v", i, out, test.output)
+		}
+		if skip := result != nil && result.Skip != nil; skip != test.skip {
+			t.Errorf("test #%d: skip: %v, exp skip: %v", i, skip, test.skip)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// PythonMetadataFilename is the file name of the core metadata in the
+	// dist-info directory of a wheel.
+	PythonMetadataFilename = "METADATA"
+
+	// PythonPkgInfoFilename is the file name of the core metadata in a
+	// source distribution or an egg-info directory.
+	PythonPkgInfoFilename = "PKG-INFO"
+
+	// PythonPyprojectFilename is the file name of the standard python
+	// project metadata.
+	PythonPyprojectFilename = "pyproject.toml"
+
+	// PythonSetupCfgFilename is the file name of the static setuptools
+	// configuration.
+	PythonSetupCfgFilename = "setup.cfg"
+
+	// PythonMaxLicenseLength is the longest value of a free form license
+	// field that we consider to be a license name. Longer values are
+	// usually the full text of the license, which we leave to the other
+	// backends.
+	PythonMaxLicenseLength = 100
+
+	// pythonClassifierPrefix is the prefix of the trove classifiers which
+	// are about licenses.
+	pythonClassifierPrefix = "License ::"
+)
+
+var (
+	// ErrInvalidPythonLicense is an error used when a license field is
+	// malformed.
+	ErrInvalidPythonLicense = errors.New("invalid python license field")
+
+	// PythonClassifiers maps the license trove classifiers to SPDX IDs. The
+	// key is the last part of the classifier. The classifiers which could
+	// be more than one SPDX license, such as "BSD License" or the
+	// unversioned "Apache Software License", are not here.
+	PythonClassifiers = map[string]string{
+		"Attribution Assurance License":                                    "AAL",
+		"Blue Oak Model License (BlueOak-1.0.0)":                           "BlueOak-1.0.0",
+		"Boost Software License 1.0 (BSL-1.0)":                             "BSL-1.0",
+		"Computer Associates Trusted Open Source License 1.1 (CATOSL-1.1)": "CATOSL-1.1",
+		"CC0 1.0 Universal (CC0 1.0) Public Domain Dedication":             "CC0-1.0",
+		"Common Development and Distribution License 1.0 (CDDL-1.0)":       "CDDL-1.0",
+		"Common Public License":                                            "CPL-1.0",
+		"Eclipse Public License 1.0 (EPL-1.0)":                             "EPL-1.0",
+		"Eclipse Public License 2.0 (EPL-2.0)":                             "EPL-2.0",
+		"European Union Public Licence 1.0 (EUPL 1.0)":                     "EUPL-1.0",
+		"European Union Public Licence 1.1 (EUPL 1.1)":                     "EUPL-1.1",
+		"European Union Public Licence 1.2 (EUPL 1.2)":                     "EUPL-1.2",
+		"GNU Affero General Public License v3":                             "AGPL-3.0-only",
+		"GNU Affero General Public License v3 or later (AGPLv3+)":          "AGPL-3.0-or-later",
+		"GNU General Public License v2 (GPLv2)":                            "GPL-2.0-only",
+		"GNU General Public License v2 or later (GPLv2+)":                  "GPL-2.0-or-later",
+		"GNU General Public License v3 (GPLv3)":                            "GPL-3.0-only",
+		"GNU General Public License v3 or later (GPLv3+)":                  "GPL-3.0-or-later",
+		"GNU Lesser General Public License v2 (LGPLv2)":                    "LGPL-2.0-only",
+		"GNU Lesser General Public License v2 or later (LGPLv2+)":          "LGPL-2.0-or-later",
+		"GNU Lesser General Public License v3 (LGPLv3)":                    "LGPL-3.0-only",
+		"GNU Lesser General Public License v3 or later (LGPLv3+)":          "LGPL-3.0-or-later",
+		"Historical Permission Notice and Disclaimer (HPND)":               "HPND",
+		"IBM Public License":                                               "IPL-1.0",
+		"ISC License (ISCL)":                                               "ISC",
+		"Intel Open Source License":                                        "Intel",
+		"MIT License":                                                      "MIT",
+		"MIT No Attribution License (MIT-0)":                               "MIT-0",
+		"Mozilla Public License 1.0 (MPL)":                                 "MPL-1.0",
+		"Mozilla Public License 1.1 (MPL 1.1)":                             "MPL-1.1",
+		"Mozilla Public License 2.0 (MPL 2.0)":                             "MPL-2.0",
+		"Mulan Permissive Software License v2 (MulanPSL-2.0)":              "MulanPSL-2.0",
+		"Nokia Open Source License":                                        "Nokia",
+		"Open Software License 3.0 (OSL-3.0)":                              "OSL-3.0",
+		"PostgreSQL License":                                               "PostgreSQL",
+		"Python License (CNRI Python License)":                             "CNRI-Python",
+		"Python Software Foundation License":                               "PSF-2.0",
+		"Sun Industry Standards Source License (SISSL)":                    "SISSL",
+		"The Unlicense (Unlicense)":                                        "Unlicense",
+		"Universal Permissive License (UPL)":                               "UPL-1.0",
+		"W3C License":                                                      "W3C",
+		"zlib/libpng License":                                              "Zlib",
+	}
+)
+
+// Python is a backend for python packaging metadata. It reads the core metadata
+// in the METADATA file of wheels and the PKG-INFO file of source distributions,
+// the pyproject.toml file, and the static setup.cfg file. The SPDX expression
+// in the License-Expression field or the PEP 639 license field is used if it
+// exists. Otherwise the free form license field and the license trove
+// classifiers are used, and the classifiers are mapped to SPDX IDs. The result
+// is the declared license of the package.
+type Python struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+}
+
+// String method returns the name of the backend.
+func (obj *Python) String() string {
+	return "python"
+}
+
+// ScanData is used to extract license ids from data and return licenses based
+// on the license ids.
+func (obj *Python) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+	name := info.FileInfo.Name()
+	if name != PythonMetadataFilename && name != PythonPkgInfoFilename && name != PythonPyprojectFilename && name != PythonSetupCfgFilename {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() {
+		return nil, nil // skip
+	}
+	if len(data) == 0 {
+		return nil, nil // skip
+	}
+
+	var metadata *PythonMetadata
+	var err error
+	switch name {
+	case PythonMetadataFilename, PythonPkgInfoFilename:
+		metadata, err = PythonCoreMetadata(data)
+	case PythonPyprojectFilename:
+		metadata, err = PythonPyproject(data)
+	case PythonSetupCfgFilename:
+		metadata, err = PythonSetupCfg(data)
+	}
+	if err != nil {
+		// There is a parse error with the file, so we can't properly
+		// examine it for licensing information.
+		result := &interfaces.Result{
+			Confidence: 1.0, // TODO: what should we put here?
+			Skip:       errwrap.Wrapf(err, "parse error"),
+		}
+		return result, nil
+	}
+	if metadata == nil {
+		return nil, nil // not a metadata file
+	}
+
+	licenseList := []*licenses.License{}
+	xs, subErr := metadata.Licenses()
+	for _, license := range xs {
+		if !licenses.InList(license, licenseList) {
+			licenseList = append(licenseList, license)
+		}
+	}
+	if len(licenseList) == 0 && subErr == nil {
+		return nil, nil // nothing was declared
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	// We return any partial results, and even if we errored, because we can
+	// now notify the user of these issues separately.
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: 1.0, // TODO: what should we put here?
+		Skip:       errwrap.Wrapf(subErr, "python sub-parser error"),
+	}
+
+	return result, nil
+}
+
+// PythonMetadata is the license information from any of the python metadata
+// formats.
+type PythonMetadata struct {
+	// Expression is the SPDX license expression if there is one.
+	Expression string
+
+	// License is the free form license field if there is one.
+	License string
+
+	// Classifiers is the list of trove classifiers.
+	Classifiers []string
+}
+
+// Licenses returns the licenses that this metadata declares. If there's an
+// SPDX expression, then only that is used, since the other fields are
+// deprecated in that case. Otherwise the free form license field is used if it
+// looks like the name of a license, along with any license classifiers, which
+// are mapped to SPDX IDs. Unknown classifiers are returned with the prefix
+// removed.
+func (obj *PythonMetadata) Licenses() ([]*licenses.License, error) {
+	if s := strings.TrimSpace(obj.Expression); s != "" {
+		licenseList, err := spdxExpressionLicenses(s)
+		if err != nil {
+			return nil, ErrInvalidPythonLicense
+		}
+		return licenseList, nil
+	}
+
+	result := PythonLicenseField(obj.License)
+	for _, x := range obj.Classifiers {
+		x = strings.TrimSpace(x)
+		if !strings.HasPrefix(x, pythonClassifierPrefix) {
+			continue
+		}
+		parts := strings.Split(x, "::")
+		last := strings.TrimSpace(parts[len(parts)-1])
+		if len(parts) < 3 && last == "OSI Approved" {
+			continue // the parent of the real classifiers
+		}
+		if id, exists := PythonClassifiers[last]; exists {
+			result = append(result, spdxLicense(id))
+			continue
+		}
+		result = append(result, spdxLicense(last))
+	}
+	return result, nil
+}
+
+// PythonLicenseField returns the licenses in a free form license field. If it
+// is an SPDX expression of valid ids, then those are returned. If it's only one
+// short line, then it is returned as the name of a license. Otherwise it's
+// probably the full license text, and nothing is returned.
+func PythonLicenseField(input string) []*licenses.License {
+	input = strings.TrimSpace(input)
+	if input == "" || input == "UNKNOWN" {
+		return nil
+	}
+	if xs, err := spdxExpressionLicenses(input); err == nil && spdxValid(xs) {
+		return xs
+	}
+	if strings.Contains(input, "\n") || len(input) > PythonMaxLicenseLength {
+		return nil
+	}
+	return []*licenses.License{spdxLicense(input)}
+}
+
+// PythonCoreMetadata parses the core metadata format which is used by METADATA
+// and PKG-INFO files. It returns nil if the data doesn't have a Metadata-Version
+// field, since these file names are also used by other things.
+func PythonCoreMetadata(data []byte) (*PythonMetadata, error) {
+	// The parser needs a trailing blank line if the file is all headers.
+	data = append(append([]byte{}, data...), "\n\n"...)
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Header.Get("Metadata-Version") == "" {
+		return nil, nil
+	}
+	license := parsed.Header.Get("License")
+	if pythonFolded(data, "License") {
+		// The parser joins the lines of a folded field, but one that
+		// spans many lines is the full text and not a license name.
+		license = ""
+	}
+	return &PythonMetadata{
+		Expression:  parsed.Header.Get("License-Expression"),
+		License:     license,
+		Classifiers: parsed.Header["Classifier"],
+	}, nil
+}
+
+// pythonFolded returns true if the named header field continues on the next
+// line. Only the headers, which end at the first blank line, are searched.
+func pythonFolded(data []byte, key string) bool {
+	prefix := strings.ToLower(key) + ":"
+	found := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			break // end of headers
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if found {
+				return true
+			}
+			continue
+		}
+		found = strings.HasPrefix(strings.ToLower(line), prefix)
+	}
+	return false
+}
+
+// PythonSetupCfg parses the metadata section of a setup.cfg file. Values which
+// use the `file:` or `attr:` directives are ignored, since they aren't static.
+func PythonSetupCfg(data []byte) (*PythonMetadata, error) {
+	metadata := &PythonMetadata{}
+	section := ""
+	key := ""
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			key = ""
+			continue
+		}
+		if section != "metadata" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' { // continuation
+			if key != "" {
+				values[key] += "\n" + trimmed
+			}
+			continue
+		}
+		ix := strings.IndexAny(line, "=:")
+		if ix == -1 {
+			return nil, ErrInvalidPythonLicense
+		}
+		// setuptools accepts dashes, but prefers underscores
+		key = strings.ReplaceAll(strings.TrimSpace(line[:ix]), "-", "_")
+		values[key] = strings.TrimSpace(line[ix+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	static := func(s string) string {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "file:") || strings.HasPrefix(s, "attr:") {
+			return ""
+		}
+		return s
+	}
+	metadata.Expression = static(values["license_expression"])
+	metadata.License = static(values["license"])
+	if s := static(values["classifiers"]); s != "" {
+		for _, x := range strings.Split(s, "\n") {
+			if x = strings.TrimSpace(x); x != "" {
+				metadata.Classifiers = append(metadata.Classifiers, x)
+			}
+		}
+	}
+	return metadata, nil
+}
+
+// PythonPyproject parses the project table of a pyproject.toml file. The PEP 639
+// form of the license field is a string with an SPDX expression, and the older
+// PEP 621 form is a table with the license text or the name of the license
+// file. The Poetry table is used if there is no project license.
+func PythonPyproject(data []byte) (*PythonMetadata, error) {
+	tables, err := tomlTables(data, []string{"project", "tool.poetry"})
+	if err != nil {
+		return nil, err
+	}
+	metadata := &PythonMetadata{}
+	for _, name := range []string{"project", "tool.poetry"} {
+		table := tables[name]
+		if table == nil {
+			continue
+		}
+		switch x := table["license"].(type) {
+		case string:
+			if name == "project" {
+				metadata.Expression = x
+			} else {
+				metadata.License = x
+			}
+		case map[string]interface{}:
+			if s, ok := x["text"].(string); ok {
+				metadata.License = s
+			}
+			// the file variant is found by the license file backends
+		}
+		if s, ok := table["license.text"].(string); ok {
+			metadata.License = s
+		}
+		if list, ok := table["classifiers"].([]interface{}); ok {
+			for _, x := range list {
+				if s, ok := x.(string); ok {
+					metadata.Classifiers = append(metadata.Classifiers, s)
+				}
+			}
+		}
+		if metadata.Expression != "" || metadata.License != "" || len(metadata.Classifiers) > 0 {
+			break
+		}
+	}
+	return metadata, nil
+}
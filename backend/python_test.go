@@ -0,0 +1,80 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+)
+
+func TestPythonBackend(t *testing.T) {
+	pythonBackend := &backend.Python{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	tests := []dataTest{
+		{"METADATA", "Metadata-Version: 2.1\nName: a\nLicense: MIT\n", "MIT", "", false},
+		{"METADATA", "Metadata-Version: 2.4\nName: a\nLicense-Expression: MIT OR Apache-2.0\nClassifier: License :: OSI Approved :: ISC License (ISCL)\n", "(Apache-2.0 OR MIT)", "", false},
+		{"METADATA", "Metadata-Version: 2.4\nLicense-Expression: (MIT\n", "", "", true},
+		{
+			"PKG-INFO",
+			"Metadata-Version: 1.2\nName: a\nLicense: UNKNOWN\nClassifier: License :: OSI Approved\nClassifier: License :: OSI Approved :: GNU General Public License v3 or later (GPLv3+)\nClassifier: License :: OSI Approved :: BSD License\nClassifier: Programming Language :: Python\n",
+			"BSD License(unknown), GPL-3.0-or-later",
+			"",
+			false,
+		},
+		{"PKG-INFO", "Metadata-Version: 2.1\nLicense: Copyright (c) 2022 Someone\n        \n        Permission is hereby granted...\n", "", "", false},
+		{"PKG-INFO", "Metadata-Version: 2.1\nLicense: Apache 2.0\n", "Apache 2.0(unknown)", "", false},
+		{"PKG-INFO", "Metadata-Version: 2.1\nClassifier: License :: OSI Approved :: Apache Software License\n", "Apache Software License(unknown)", "", false},
+		{"METADATA", "Name: not python\n", "", "", false},
+		{"pyproject.toml", "[project]\nname = \"a\"\nlicense = \"MIT AND (Apache-2.0 OR BSD-2-Clause)\"\n", "(Apache-2.0 OR BSD-2-Clause), MIT", "", false},
+		{
+			"pyproject.toml",
+			"[build-system]\nrequires = [\"setuptools\"]\n\n[project]\nname = 'a' # comment\nlicense = {text = \"MIT\"}\nclassifiers = [\n    \"License :: OSI Approved :: Apache Software License\", # comment\n    'Programming Language :: Python',\n]\n",
+			"Apache Software License(unknown), MIT",
+			"",
+			false,
+		},
+		{"pyproject.toml", "[project]\nlicense = {file = \"LICENSE\"}\n", "", "", false},
+		{"pyproject.toml", "[project]\nlicense.text = \"\"\"\nMIT\"\"\"\n", "MIT", "", false},
+		{"pyproject.toml", "[tool.poetry]\nname = \"a\"\nlicense = \"BSD-3-Clause\"\n", "BSD-3-Clause", "", false},
+		{"pyproject.toml", "[project]\nlicense = \"MIT\n", "", "", true},
+		{
+			"setup.cfg",
+			"[metadata]\nname = a\nlicense = MPL-2.0\nclassifiers =\n    License :: OSI Approved :: Mozilla Public License 2.0 (MPL 2.0)\n    License :: OSI Approved :: MIT License\n\n[options]\nlicense = GPL-2.0-only\n",
+			"MIT, MPL-2.0",
+			"",
+			false,
+		},
+		{"setup.cfg", "[metadata]\nlicense = file: LICENSE\n", "", "", false},
+		{"setup.py", "license='MIT'", "", "", false},
+	}
+
+	testDataBackend(t, pythonBackend, tests)
+}
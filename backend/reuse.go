@@ -0,0 +1,677 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// ReuseLicensesDir is the name of the directory in the root of a REUSE
+	// project which has the license texts. Each one is named after its ID.
+	ReuseLicensesDir = "LICENSES"
+
+	// ReuseDir is the name of the directory in the root of a REUSE project
+	// which has the dep5 file.
+	ReuseDir = ".reuse"
+
+	// ReuseDep5Filename is the file name of the DEP-5 file in the ReuseDir.
+	ReuseDep5Filename = "dep5"
+
+	// ReuseTomlFilename is the file name of the REUSE.toml annotations.
+	// There can be one in any directory of the project.
+	ReuseTomlFilename = "REUSE.toml"
+
+	// ReuseSidecarExtension is the extension of the files which hold the
+	// license and copyright tags for the file with the same name without
+	// the extension. This is used for binary files and the like.
+	ReuseSidecarExtension = ".license"
+
+	// ReuseCopyrightTag is the tag we look for when finding the copyright
+	// holders in a file.
+	ReuseCopyrightTag = "SPDX-FileCopyrightText:"
+
+	// ReusePrecedenceClosest is the default precedence of an annotation.
+	// It is only used if the file doesn't have its own license or holders.
+	ReusePrecedenceClosest = "closest"
+
+	// ReusePrecedenceAggregate is the precedence of an annotation which is
+	// combined with the license and holders in the file.
+	ReusePrecedenceAggregate = "aggregate"
+
+	// ReusePrecedenceOverride is the precedence of an annotation which is
+	// used instead of anything in the file.
+	ReusePrecedenceOverride = "override"
+
+	// reuseTomlVersion is the only version of REUSE.toml that we know.
+	reuseTomlVersion = "1"
+)
+
+var (
+	// ErrInvalidReuseToml is an error used when a REUSE.toml is malformed.
+	ErrInvalidReuseToml = errors.New("invalid REUSE.toml file")
+)
+
+// Reuse is a backend for projects that follow the REUSE specification. These
+// have the license texts in a LICENSES directory at their root, and they can
+// declare the license and copyright holders of a file with a sidecar file that
+// has the same name with a .license extension, with the DEP-5 file in the .reuse
+// directory, or with the annotations in the REUSE.toml files. This applies all
+// of these to the files that they cover, so the result for a file is its REUSE
+// license and the copyright holders are returned with it. The SPDX tags in the
+// file itself are combined with the annotations as their precedence says, but
+// a file with only tags is left to the spdx backend. The result for a license
+// text in the LICENSES directory is the license that its name is the ID of, and
+// the result for a DEP-5 or REUSE.toml file is the set of all of the licenses
+// in it.
+type Reuse struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+
+	mutex sync.Mutex
+	// roots caches the root of the REUSE project for each directory, keyed
+	// by the root of the scan and the directory. It stores the empty string
+	// if there isn't one.
+	roots map[[2]string]string
+	// tomls caches the parsed REUSE.toml files, keyed by their path. It
+	// stores nil if there isn't a file at that path.
+	tomls map[string]*reuseToml
+	// dep5s caches the parsed dep5 files, keyed by their path.
+	dep5s map[string]*dep5File
+}
+
+// String method returns the name of the backend.
+func (obj *Reuse) String() string {
+	return "reuse"
+}
+
+// Dependent returns true because the result for a file comes from its sidecar
+// file and from the annotation files of its project.
+func (obj *Reuse) Dependent() bool {
+	return true
+}
+
+// ScanPath returns the license and copyright holders that the REUSE project
+// that the path is in declares for it.
+func (obj *Reuse) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
+	if info.FileInfo.IsDir() { // path.IsDir() should be the same.
+		return nil, nil // skip
+	}
+	p := path.Path()
+	dir := filepath.Dir(p)
+
+	root := obj.root(info.Root.Path(), dir)
+	if root == "" {
+		return nil, nil // not in a REUSE project
+	}
+
+	if dir == filepath.Join(root, ReuseLicensesDir) { // a license text
+		id := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+		if id == "" {
+			return nil, nil // skip
+		}
+		result := &interfaces.Result{
+			Licenses:   []*licenses.License{spdxLicense(id)},
+			Confidence: 1.0, // TODO: what should we put here?
+		}
+		return result, nil
+	}
+
+	if p == filepath.Join(root, ReuseDir, ReuseDep5Filename) || filepath.Base(p) == ReuseTomlFilename {
+		return obj.annotationsResult(p, root)
+	}
+
+	if strings.HasSuffix(p, ReuseSidecarExtension) {
+		if _, err := os.Stat(strings.TrimSuffix(p, ReuseSidecarExtension)); err == nil {
+			return nil, nil // a sidecar, which applies to another file
+		}
+	}
+
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return nil, nil // not in this tree
+	}
+	rel = filepath.ToSlash(rel)
+
+	// The dep5 file is like an aggregate annotation that is outside of all
+	// of the REUSE.toml files.
+	// Any errors in the annotation files are shown in their own results.
+	matches := []*reuseAnnotation{}
+	if copyright, err := obj.dep5(filepath.Join(root, ReuseDir, ReuseDep5Filename), root); err == nil && copyright != nil {
+		if paragraph := copyright.match(rel); paragraph != nil {
+			matches = append(matches, &reuseAnnotation{
+				precedence: ReusePrecedenceAggregate,
+				licenses:   paragraph.licenses,
+				holders:    paragraph.holders,
+			})
+		}
+	}
+	for _, f := range obj.tomlPaths(dir, root) {
+		annotations, err := obj.toml(f)
+		if err != nil {
+			continue
+		}
+		if x := annotations.match(p); x != nil {
+			matches = append(matches, x)
+		}
+	}
+
+	// The outermost override wins over everything else.
+	for _, x := range matches {
+		if x.precedence == ReusePrecedenceOverride {
+			return reuseResult(x.licenses, x.holders, nil), nil
+		}
+	}
+
+	sidecar := p + ReuseSidecarExtension
+	fileInfo, err := os.Stat(sidecar)
+	hasSidecar := err == nil && fileInfo.Mode().IsRegular()
+	if len(matches) == 0 && !hasSidecar {
+		// Any tags in the file are found by the spdx backend.
+		return nil, nil
+	}
+
+	f := p
+	if hasSidecar { // the sidecar is used instead of the file
+		f = sidecar
+	}
+	ids, holders, tagsErr := ReuseTags(f)
+	licenseList := reuseLicenses(ids)
+
+	// The closest annotation fills in whatever the file doesn't have.
+	for i := len(matches) - 1; i >= 0; i-- {
+		x := matches[i]
+		if x.precedence != ReusePrecedenceClosest {
+			continue
+		}
+		if len(licenseList) == 0 {
+			licenseList = x.licenses
+		}
+		if len(holders) == 0 {
+			holders = x.holders
+		}
+		break
+	}
+	for _, x := range matches {
+		if x.precedence != ReusePrecedenceAggregate {
+			continue
+		}
+		licenseList = reuseAppend(licenseList, x.licenses)
+		holders = append(holders, x.holders...)
+	}
+
+	return reuseResult(licenseList, holders, tagsErr), nil
+}
+
+// annotationsResult returns the result for the dep5 or REUSE.toml file at the
+// path, which is the set of all of the licenses in it.
+func (obj *Reuse) annotationsResult(p, root string) (*interfaces.Result, error) {
+	annotations := []*reuseAnnotation{}
+	if filepath.Base(p) == ReuseTomlFilename {
+		x, err := obj.toml(p)
+		if err != nil {
+			// There is a parse error with the file, so we can't
+			// properly examine it for licensing information.
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       errwrap.Wrapf(err, "parse error"),
+			}
+			return result, nil
+		}
+		annotations = x.annotations
+	} else {
+		copyright, err := obj.dep5(p, root)
+		if err != nil {
+			result := &interfaces.Result{
+				Confidence: 1.0, // TODO: what should we put here?
+				Skip:       errwrap.Wrapf(err, "parse error"),
+			}
+			return result, nil
+		}
+		if copyright != nil {
+			for _, x := range copyright.paragraphs {
+				annotations = append(annotations, &reuseAnnotation{licenses: x.licenses})
+			}
+		}
+	}
+
+	licenseList := []*licenses.License{}
+	for _, x := range annotations {
+		licenseList = reuseAppend(licenseList, x.licenses)
+	}
+	return reuseResult(licenseList, nil, nil), nil
+}
+
+// root returns the root directory of the REUSE project that the dir is in, or
+// the empty string if it isn't in one. The root is the closest directory which
+// has a LICENSES or a .reuse directory. If there isn't one, then it is the
+// outermost directory which has a REUSE.toml file, since those can be nested.
+// It doesn't look outside of the root directory of the scan.
+func (obj *Reuse) root(scanRoot, dir string) string {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.roots == nil {
+		obj.roots = make(map[[2]string]string)
+	}
+	if root := obj.rootLocked(scanRoot, dir); root != "" {
+		return root
+	}
+
+	root := ""
+	for d := dir; insideDir(scanRoot, d); d = filepath.Dir(d) {
+		if f := filepath.Join(d, ReuseTomlFilename); insideRoot(scanRoot, f) && obj.isFileLocked(f) {
+			root = d
+		}
+		if d == scanRoot || filepath.Dir(d) == d {
+			break
+		}
+	}
+	return root
+}
+
+// rootLocked is the recursive part of root which looks for the directories. The
+// mutex must be held.
+func (obj *Reuse) rootLocked(scanRoot, dir string) string {
+	key := [2]string{scanRoot, dir}
+	if root, exists := obj.roots[key]; exists {
+		return root
+	}
+	root := ""
+	for _, x := range []string{ReuseLicensesDir, ReuseDir} {
+		if fileInfo, err := os.Stat(filepath.Join(dir, x)); err == nil && fileInfo.IsDir() {
+			root = dir
+			break
+		}
+	}
+	if root == "" {
+		if parent := filepath.Dir(dir); dir != scanRoot && insideDir(scanRoot, parent) {
+			root = obj.rootLocked(scanRoot, parent)
+		}
+	}
+	obj.roots[key] = root
+	return root
+}
+
+// isFileLocked returns true if there's a REUSE.toml file at the path. It caches
+// the ones that don't exist as nil. The mutex must be held.
+func (obj *Reuse) isFileLocked(f string) bool {
+	if obj.tomls == nil {
+		obj.tomls = make(map[string]*reuseToml)
+	}
+	if x, exists := obj.tomls[f]; exists {
+		return x != nil
+	}
+	if fileInfo, err := os.Stat(f); err == nil && fileInfo.Mode().IsRegular() {
+		return true // it gets parsed and cached when it's needed
+	}
+	obj.tomls[f] = nil
+	return false
+}
+
+// tomlPaths returns the paths of the REUSE.toml files in the dir and in each of
+// its parents up to the root, from the outermost one to the closest one.
+func (obj *Reuse) tomlPaths(dir, root string) []string {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	result := []string{}
+	for d := dir; ; d = filepath.Dir(d) {
+		if f := filepath.Join(d, ReuseTomlFilename); obj.isFileLocked(f) {
+			result = append([]string{f}, result...)
+		}
+		if d == root || filepath.Dir(d) == d {
+			break
+		}
+	}
+	return result
+}
+
+// toml returns the parsed REUSE.toml file at the path.
+func (obj *Reuse) toml(f string) (*reuseToml, error) {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.tomls == nil {
+		obj.tomls = make(map[string]*reuseToml)
+	}
+	if x, exists := obj.tomls[f]; exists && x != nil {
+		return x, x.err
+	}
+
+	x := &reuseToml{
+		dir: filepath.Dir(f),
+	}
+	obj.tomls[f] = x
+	data, err := os.ReadFile(f)
+	if err != nil {
+		x.err = err
+		return x, err
+	}
+	annotations, err := ReuseParseToml(data)
+	if err != nil {
+		x.err = errwrap.Wrapf(err, "%s", f)
+		return x, x.err
+	}
+	for _, a := range annotations {
+		annotation := &reuseAnnotation{
+			precedence: a.Precedence,
+			licenses:   reuseLicenses(a.Licenses),
+			holders:    a.Copyrights,
+		}
+		for _, pattern := range a.Paths {
+			annotation.patterns = append(annotation.patterns, reusePattern(pattern))
+		}
+		x.annotations = append(x.annotations, annotation)
+	}
+	if obj.Debug {
+		obj.Logf("reuse: %d annotations in %s", len(x.annotations), f)
+	}
+	return x, nil
+}
+
+// dep5 returns the parsed dep5 file at the path, or nil if there isn't one.
+func (obj *Reuse) dep5(f, root string) (*dep5File, error) {
+	obj.mutex.Lock()
+	defer obj.mutex.Unlock()
+	if obj.dep5s == nil {
+		obj.dep5s = make(map[string]*dep5File)
+	}
+	if copyright, exists := obj.dep5s[f]; exists {
+		if copyright != nil && copyright.err != nil {
+			return nil, copyright.err
+		}
+		return copyright, nil
+	}
+
+	data, err := os.ReadFile(f)
+	if os.IsNotExist(err) {
+		obj.dep5s[f] = nil
+		return nil, nil
+	}
+	if err == nil {
+		var paragraphs []*Dep5Paragraph
+		if paragraphs, err = Dep5Parse(data); err == nil && paragraphs == nil {
+			err = errwrap.Wrapf(ErrInvalidDep5, "not machine-readable")
+		}
+		if err == nil {
+			copyright := dep5Build(paragraphs, root)
+			obj.dep5s[f] = copyright
+			return copyright, nil
+		}
+	}
+	err = errwrap.Wrapf(err, "%s", f)
+	obj.dep5s[f] = &dep5File{err: err}
+	return nil, err
+}
+
+// reuseToml is a parsed REUSE.toml file.
+type reuseToml struct {
+	// dir is the directory that the paths are relative to.
+	dir string
+
+	annotations []*reuseAnnotation
+
+	// err is the error from reading or parsing the file, if any.
+	err error
+}
+
+// match returns the last annotation that matches the path, or nil if none do.
+func (obj *reuseToml) match(p string) *reuseAnnotation {
+	rel, err := filepath.Rel(obj.dir, p)
+	if err != nil {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(obj.annotations) - 1; i >= 0; i-- {
+		for _, x := range obj.annotations[i].patterns {
+			if x.MatchString(rel) {
+				return obj.annotations[i]
+			}
+		}
+	}
+	return nil
+}
+
+// reuseAnnotation is a parsed annotation.
+type reuseAnnotation struct {
+	patterns   []*regexp.Regexp
+	precedence string
+	licenses   []*licenses.License
+	holders    []string
+}
+
+// ReuseAnnotation is an annotation of a REUSE.toml file.
+type ReuseAnnotation struct {
+	// Paths is the list of patterns of the files that this applies to.
+	Paths []string
+
+	// Precedence is how this is combined with the tags in the files. It
+	// is ReusePrecedenceClosest if it's not set.
+	Precedence string
+
+	// Copyrights is the list of copyright holders.
+	Copyrights []string
+
+	// Licenses is the list of SPDX expressions, which all apply.
+	Licenses []string
+}
+
+// ReuseParseToml parses the annotations of a REUSE.toml file.
+func ReuseParseToml(data []byte) ([]*ReuseAnnotation, error) {
+	arrays, err := tomlArrays(data, []string{"annotations"})
+	if err != nil {
+		return nil, err
+	}
+	version := ""
+	if top := arrays[""]; len(top) > 0 {
+		version = fmt.Sprintf("%v", top[0]["version"])
+	}
+	if version != reuseTomlVersion {
+		return nil, errwrap.Wrapf(ErrInvalidReuseToml, "unsupported version: %s", version)
+	}
+
+	annotations := []*ReuseAnnotation{}
+	for i, x := range arrays["annotations"] {
+		annotation := &ReuseAnnotation{
+			Paths:      reuseStrings(x["path"]),
+			Precedence: ReusePrecedenceClosest,
+			Copyrights: reuseStrings(x["SPDX-FileCopyrightText"]),
+			Licenses:   reuseStrings(x["SPDX-License-Identifier"]),
+		}
+		if len(annotation.Paths) == 0 {
+			return nil, errwrap.Wrapf(ErrInvalidReuseToml, "annotation %d has no path", i+1)
+		}
+		if s, ok := x["precedence"].(string); ok && s != "" {
+			annotation.Precedence = s
+		}
+		switch annotation.Precedence {
+		case ReusePrecedenceClosest, ReusePrecedenceAggregate, ReusePrecedenceOverride:
+		default:
+			return nil, errwrap.Wrapf(ErrInvalidReuseToml, "annotation %d has an invalid precedence: %s", i+1, annotation.Precedence)
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations, nil
+}
+
+// ReuseTags returns the SPDX license expressions and the copyright holders that
+// are in the tags of the file. The tags are found in the same way that the spdx
+// backend finds them.
+func ReuseTags(f string) ([]string, []string, error) {
+	file, err := os.Open(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	ids := []string{}
+	holders := []string{}
+	scanner := bufio.NewScanner(file)
+	buf := []byte{}                       // create a buffer for very long lines
+	scanner.Buffer(buf, SpdxMaxBytesLine) // set the max size of that buffer
+	for scanner.Scan() {
+		s := scanner.Text()
+		for _, tag := range []string{magicStringSPDX, ReuseCopyrightTag} {
+			strs := strings.SplitN(s, tag, 2)
+			if len(strs) == 1 || len(stripTrash(strs[0])) > magicNumberSPDX {
+				continue
+			}
+			value := strings.TrimSpace(strings.Split(strs[1], "*/")[0])
+			value = strings.TrimSpace(strings.TrimSuffix(value, "-->"))
+			if value == "" {
+				continue
+			}
+			if tag == ReuseCopyrightTag {
+				holders = append(holders, value)
+				continue
+			}
+			ids = append(ids, value)
+		}
+	}
+	if err := scanner.Err(); err != nil && err != bufio.ErrTooLong {
+		return ids, holders, err
+	}
+	return ids, holders, nil
+}
+
+// reuseResult returns the result for the licenses and holders, or nil if there
+// is nothing to return.
+func reuseResult(licenseList []*licenses.License, holders []string, err error) *interfaces.Result {
+	if len(licenseList) == 0 && err == nil {
+		return nil // nothing was declared
+	}
+	licenseList = reuseAppend(nil, licenseList) // copy and dedup
+
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	found := make(map[string]struct{})
+	unique := []string{}
+	for _, x := range holders {
+		if _, exists := found[x]; exists {
+			continue
+		}
+		found[x] = struct{}{}
+		unique = append(unique, x)
+	}
+	if len(unique) == 0 {
+		unique = nil
+	}
+
+	// We return any partial results, and even if we errored, because we can
+	// now notify the user of these issues separately.
+	return &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: 1.0, // TODO: what should we put here?
+		Skip:       errwrap.Wrapf(err, "reuse sub-parser error"),
+		Holders:    unique,
+	}
+}
+
+// reuseLicenses returns the licenses of the SPDX expressions, which all apply.
+// An expression that can't be parsed is kept as a custom license.
+func reuseLicenses(expressions []string) []*licenses.License {
+	licenseList := []*licenses.License{}
+	for _, x := range expressions {
+		xs, err := spdxExpressionLicenses(x)
+		if err != nil {
+			xs = []*licenses.License{
+				{
+					//SPDX: "",
+					Origin: "", // unknown!
+					Custom: x,
+				},
+			}
+		}
+		licenseList = reuseAppend(licenseList, xs)
+	}
+	return licenseList
+}
+
+// reuseAppend returns the list with each of the licenses that aren't already in
+// it added to the end.
+func reuseAppend(licenseList, xs []*licenses.License) []*licenses.License {
+	for _, x := range xs {
+		if !licenses.InList(x, licenseList) {
+			licenseList = append(licenseList, x)
+		}
+	}
+	return licenseList
+}
+
+// reuseStrings returns the string or the list of strings in the toml value.
+func reuseStrings(value interface{}) []string {
+	result := []string{}
+	switch x := value.(type) {
+	case string:
+		if s := strings.TrimSpace(x); s != "" {
+			result = append(result, s)
+		}
+	case []interface{}:
+		for _, v := range x {
+			if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+				result = append(result, strings.TrimSpace(s))
+			}
+		}
+	}
+	return result
+}
+
+// reusePattern converts a path pattern of a REUSE.toml annotation into a regexp.
+// A `*` matches anything except for a slash, a `**` matches anything including
+// a slash, and a backslash escapes a star or itself. The pattern is relative to
+// the directory of the REUSE.toml file.
+func reusePattern(pattern string) *regexp.Regexp {
+	pattern = strings.TrimPrefix(pattern, "./")
+	s := "^"
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			s += regexp.QuoteMeta(string(pattern[i]))
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			s += ".*"
+		case c == '*':
+			s += "[^/]*"
+		default:
+			s += regexp.QuoteMeta(string(c))
+		}
+	}
+	return regexp.MustCompile(s + "$")
+}
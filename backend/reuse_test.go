@@ -0,0 +1,193 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestReuseBackend(t *testing.T) {
+	toml := `version = 1
+
+[[annotations]]
+path = ["src/**", "README.md"]
+SPDX-FileCopyrightText = "2024 Example, Inc."
+SPDX-License-Identifier = "Apache-2.0"
+
+[[annotations]]
+path = "src/*.gen.go"
+precedence = "aggregate"
+SPDX-License-Identifier = "MIT"
+
+[[annotations]]
+path = "third_party/**"
+precedence = "override"
+SPDX-FileCopyrightText = ["2001 Vendor"]
+SPDX-License-Identifier = "BSD-3-Clause"
+`
+	subToml := `version = 1
+
+[[annotations]]
+path = "*.go"
+SPDX-License-Identifier = "MPL-2.0"
+`
+	dep5 := `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+
+Files: docs/*
+Copyright: 2020 Writer
+License: CC-BY-4.0
+`
+	files := map[string]string{
+		"REUSE.toml":                   toml,
+		".reuse/dep5":                  dep5,
+		"LICENSES/Apache-2.0.txt":      "Apache License\n",
+		"LICENSES/LicenseRef-Mine.txt": "Mine\n",
+		"README.md":                    "# readme\n",
+		"src/a.go":                     "package src\n",
+		"src/b.go":                     "// SPDX-FileCopyrightText: 2023 Jane\n// SPDX-License-Identifier: GPL-2.0-only\npackage src\n",
+		"src/c.gen.go":                 "// SPDX-License-Identifier: ISC\npackage src\n",
+		"src/sub/REUSE.toml":           subToml,
+		"src/sub/d.go":                 "package sub\n",
+		"third_party/e.c":              "/* SPDX-License-Identifier: GPL-3.0-only */\n",
+		"docs/guide.txt":               "guide\n",
+		"img/logo.svg":                 "<svg/>\n",
+		"img/logo.svg.license":         "SPDX-FileCopyrightText: 2022 Artist\nSPDX-License-Identifier: CC0-1.0\n",
+		"other.txt":                    "// SPDX-License-Identifier: MIT\n",
+	}
+	tests := []struct {
+		file    string // the file in the tree that we scan
+		output  string // joined licenses, or empty for no result
+		holders string // joined holders
+	}{
+		{"README.md", "Apache-2.0", "2024 Example, Inc."},
+		{"src/a.go", "Apache-2.0", "2024 Example, Inc."},
+		{"src/b.go", "GPL-2.0-only", "2023 Jane"},
+		{"src/c.gen.go", "ISC, MIT", ""},
+		{"src/sub/d.go", "MPL-2.0", ""}, // the closest annotation wins
+		{"third_party/e.c", "BSD-3-Clause", "2001 Vendor"},
+		{"docs/guide.txt", "CC-BY-4.0", "2020 Writer"},
+		{"img/logo.svg", "CC0-1.0", "2022 Artist"},
+		{"img/logo.svg.license", "", ""},
+		{"other.txt", "", ""}, // left to the spdx backend
+		{"LICENSES/Apache-2.0.txt", "Apache-2.0", ""},
+		{"LICENSES/LicenseRef-Mine.txt", "LicenseRef-Mine(unknown)", ""},
+		{"REUSE.toml", "Apache-2.0, BSD-3-Clause, MIT", ""},
+		{".reuse/dep5", "CC-BY-4.0", ""},
+	}
+
+	dir := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	reuseBackend := &backend.Reuse{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	for i, test := range tests {
+		p := filepath.Join(dir, test.file)
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		info := &interfaces.Info{
+			FileInfo: fileInfo,
+			UID:      iterator.FileScheme + p,
+			Root:     safepath.UnsafeParseIntoAbsDir(dir),
+		}
+		result, err := reuseBackend.ScanPath(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		out, holders := "", ""
+		if result != nil {
+			out = licenses.Join(result.Licenses)
+			holders = strings.Join(result.Holders, "; ")
+		}
+		if out != test.output {
+			t.Errorf("test #%d: out: %v, exp out: %v", i, out, test.output)
+		}
+		if holders != test.holders {
+			t.Errorf("test #%d: holders: %v, exp holders: %v", i, holders, test.holders)
+		}
+	}
+
+	// The REUSE project isn't used if its root is outside of the scanned
+	// tree.
+	p := filepath.Join(dir, "src/a.go")
+	fileInfo, err := os.Stat(p)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info := &interfaces.Info{
+		FileInfo: fileInfo,
+		UID:      iterator.FileScheme + p,
+		Root:     safepath.UnsafeParseIntoAbsDir(filepath.Join(dir, "src")),
+	}
+	if result, err := reuseBackend.ScanPath(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info); err != nil || result != nil {
+		t.Errorf("exp: no result outside of the root, got: %v, err: %v", result, err)
+	}
+}
+
+func TestReuseParseToml(t *testing.T) {
+	for i, data := range []string{
+		"version = 2\n",
+		"[[annotations]]\npath = \"*\"\n",
+		"version = 1\n\n[[annotations]]\nSPDX-License-Identifier = \"MIT\"\n",
+		"version = 1\n\n[[annotations]]\npath = \"*\"\nprecedence = \"first\"\n",
+	} {
+		if _, err := backend.ReuseParseToml([]byte(data)); err == nil {
+			t.Errorf("test #%d: exp: an error", i)
+		}
+	}
+	annotations, err := backend.ReuseParseToml([]byte("version = 1\n\n[[annotations]]\npath = \"*\"\nSPDX-License-Identifier = [\"MIT\", \"CC0-1.0\"]\n"))
+	if err != nil {
+		t.Errorf("err: %v", err)
+		return
+	}
+	if len(annotations) != 1 || annotations[0].Precedence != backend.ReusePrecedenceClosest || strings.Join(annotations[0].Licenses, ", ") != "MIT, CC0-1.0" {
+		t.Errorf("unexpected annotations: %+v", annotations)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrOutsideRoot is an error used when a file refers to another file
+	// which is outside of the directory that is being scanned.
+	ErrOutsideRoot = errors.New("path is outside of the scanned directory")
+)
+
+// insideRoot returns true if the path is the root directory or is inside of it.
+// The root is the directory that the iterator is walking. A symlink could point
+// anywhere, so if the path exists, then this is checked again after resolving
+// them.
+func insideRoot(root, p string) bool {
+	if !insideDir(root, p) {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return true // it doesn't exist, so nothing can be read from it
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	return insideDir(resolvedRoot, resolved)
+}
+
+// insideDir returns true if the path is the dir or is inside of it. It only
+// looks at the names, so the paths don't have to exist.
+func insideDir(dir, p string) bool {
+	if !filepath.IsAbs(dir) || !filepath.IsAbs(p) {
+		return false
+	}
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
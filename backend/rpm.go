@@ -0,0 +1,411 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// RpmSpecExtension is the extension of rpm spec files.
+	RpmSpecExtension = ".spec"
+)
+
+var (
+	// RpmNames maps the short names of licenses that Fedora used before it
+	// switched to SPDX IDs, to the SPDX IDs. The versioned GNU licenses,
+	// such as GPLv2+, are handled separately. The names which could mean
+	// more than one SPDX license, such as "BSD", are not here.
+	RpmNames = map[string]string{
+		"ASL 1.0":              "Apache-1.0",
+		"ASL 1.1":              "Apache-1.1",
+		"ASL 2.0":              "Apache-2.0",
+		"Artistic 2.0":         "Artistic-2.0",
+		"Artistic clarified":   "ClArtistic",
+		"BSD with advertising": "BSD-4-Clause",
+		"Boost":                "BSL-1.0",
+		"CC0":                  "CC0-1.0",
+		"CDDL":                 "CDDL-1.0",
+		"CPL":                  "CPL-1.0",
+		"EPL":                  "EPL-1.0",
+		"GPL+":                 "GPL-1.0-or-later",
+		"LGPL+":                "LGPL-2.0-or-later",
+		"MIT":                  "MIT",
+		"MPLv1.0":              "MPL-1.0",
+		"MPLv1.1":              "MPL-1.1",
+		"MPLv2.0":              "MPL-2.0",
+		"OFL":                  "OFL-1.1",
+		"OpenLDAP":             "OLDAP-2.8",
+		"OpenSSL":              "OpenSSL",
+		"PHP":                  "PHP-3.01",
+		"Python":               "Python-2.0",
+		"Ruby":                 "Ruby",
+		"UCD":                  "Unicode-DFS-2016",
+		"Vim":                  "Vim",
+		"W3C":                  "W3C",
+		"WTFPL":                "WTFPL",
+		"zlib":                 "Zlib",
+		"ZPLv2.1":              "ZPL-2.1",
+	}
+
+	// rpmGNURegexp matches the Fedora short name of a GNU license.
+	rpmGNURegexp = regexp.MustCompile(`^(A|L)?GPLv([0-9]+)(\.[0-9]+)?(\+)?$`)
+
+	// rpmMacroRegexp matches the `%{name}` and `%{?name}` forms of macros.
+	rpmMacroRegexp = regexp.MustCompile(`%\{\??([A-Za-z0-9_]+)\}`)
+
+	// rpmSections are the macros which start the sections of a spec file,
+	// other than %package and %files.
+	rpmSections = map[string]struct{}{
+		"%description":            {},
+		"%prep":                   {},
+		"%build":                  {},
+		"%install":                {},
+		"%check":                  {},
+		"%clean":                  {},
+		"%conf":                   {},
+		"%generate_buildrequires": {},
+		"%pre":                    {},
+		"%post":                   {},
+		"%preun":                  {},
+		"%postun":                 {},
+		"%pretrans":               {},
+		"%posttrans":              {},
+		"%triggerin":              {},
+		"%triggerun":              {},
+		"%triggerpostun":          {},
+		"%verifyscript":           {},
+		"%sourcelist":             {},
+		"%patchlist":              {},
+		"%changelog":              {},
+	}
+
+	// rpmTagRegexp matches a tag in the preamble of a package.
+	rpmTagRegexp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*)\s*:\s*(.*)$`)
+)
+
+// Rpm is a backend for rpm spec files. It reads the License tag of the main
+// package and of each of the subpackages, and the files that are marked with
+// %license in the files sections. The License tags are SPDX expressions in
+// newer spec files, and use the older Fedora short names otherwise, which are
+// mapped to SPDX IDs. The license files are looked for next to the spec file,
+// which is where they are if it's in the source tree, and are identified with
+// the license classifier library. The result is the set of all of these
+// licenses.
+type Rpm struct {
+	Debug bool
+	Logf  func(format string, v ...interface{})
+
+	classifier licenseTextClassifier
+}
+
+// String method returns the name of the backend.
+func (obj *Rpm) String() string {
+	return "rpm"
+}
+
+// Dependent returns true because the files that are marked with %license are
+// identified too.
+func (obj *Rpm) Dependent() bool {
+	return true
+}
+
+// ScanPath is used to extract the licenses from the spec file at the path.
+func (obj *Rpm) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
+	if !strings.HasSuffix(info.FileInfo.Name(), RpmSpecExtension) {
+		return nil, nil // skip
+	}
+	if info.FileInfo.IsDir() { // path.IsDir() should be the same.
+		return nil, nil // skip
+	}
+	if info.FileInfo.Size() == 0 {
+		return nil, nil // skip
+	}
+
+	data, err := os.ReadFile(path.Path())
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "can't read file")
+	}
+	spec := RpmParseSpec(data)
+	if len(spec.Licenses) == 0 && len(spec.LicenseFiles) == 0 {
+		return nil, nil // nothing was declared
+	}
+
+	licenseList := []*licenses.License{}
+	add := func(license *licenses.License) {
+		if !licenses.InList(license, licenseList) {
+			licenseList = append(licenseList, license)
+		}
+	}
+	for _, x := range spec.Licenses {
+		for _, license := range RpmLicenses(x) {
+			add(license)
+		}
+	}
+
+	confidence := 1.0
+	dir := filepath.Dir(path.Path())
+	for _, name := range spec.LicenseFiles {
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || len(matches) == 0 {
+			if obj.Debug {
+				obj.Logf("rpm: license file %s not found next to %s", name, path.Path())
+			}
+			continue
+		}
+		for _, p := range matches {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				continue // a directory or something else
+			}
+			license, c, err := obj.classifier.classify(ctx, string(data))
+			if err != nil {
+				return nil, err
+			}
+			if license == nil { // not confident about any license
+				continue
+			}
+			add(license)
+			if len(spec.Licenses) == 0 && c < confidence {
+				confidence = c // nothing was declared
+			}
+		}
+	}
+
+	if len(licenseList) == 0 {
+		return nil, nil
+	}
+	sort.Slice(licenseList, func(i, j int) bool { // deterministic order
+		return licenseList[i].String() < licenseList[j].String()
+	})
+
+	result := &interfaces.Result{
+		Licenses:   licenseList,
+		Confidence: confidence,
+		Component:  spec.Component(),
+	}
+	return result, nil
+}
+
+// RpmSpec is the license information in a spec file.
+type RpmSpec struct {
+	// Name is the name of the main package.
+	Name string
+
+	// Version is the version of the main package.
+	Version string
+
+	// Licenses is the list of License tags of the main package and of each
+	// of the subpackages.
+	Licenses []string
+
+	// LicenseFiles is the list of files which are marked with %license in
+	// the files sections. They can be glob patterns.
+	LicenseFiles []string
+}
+
+// Component returns the name and version of the package, or the empty string if
+// they use macros that we couldn't expand.
+func (obj *RpmSpec) Component() string {
+	if obj.Name == "" || strings.Contains(obj.Name, "%") {
+		return ""
+	}
+	if obj.Version == "" || strings.Contains(obj.Version, "%") {
+		return obj.Name
+	}
+	return obj.Name + "@" + obj.Version
+}
+
+// RpmParseSpec parses the parts of a spec file that we need. The simple macros
+// that are defined with %define or %global are expanded. Both of the branches
+// of any conditionals are used, since we can't evaluate them, and so we find
+// all of the licenses that might apply.
+func RpmParseSpec(data []byte) *RpmSpec {
+	spec := &RpmSpec{}
+	macros := make(map[string]string)
+	expand := func(s string) string {
+		return rpmMacroRegexp.ReplaceAllStringFunc(s, func(m string) string {
+			name := rpmMacroRegexp.FindStringSubmatch(m)[1]
+			if value, exists := macros[name]; exists {
+				return value
+			}
+			if strings.HasPrefix(m, "%{?") {
+				return "" // it's empty if it's not defined
+			}
+			return m
+		})
+	}
+
+	section := "package" // the preamble of the main package
+	main := true
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+		case "%define", "%global":
+			if len(fields) >= 3 {
+				macros[fields[1]] = expand(strings.Join(fields[2:], " "))
+			}
+			continue
+		case "%package":
+			section, main = "package", false
+			continue
+		case "%files":
+			section, main = "files", false
+			continue
+		case "%if", "%ifarch", "%ifnarch", "%ifos", "%ifnos", "%else", "%elif", "%endif":
+			continue // we look at every branch
+		}
+		if _, exists := rpmSections[fields[0]]; exists {
+			section = "" // %description, %prep, %build and so on
+			continue
+		}
+
+		switch section {
+		case "package":
+			m := rpmTagRegexp.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			value := strings.TrimSpace(expand(m[2]))
+			switch strings.ToLower(m[1]) {
+			case "license":
+				if value != "" {
+					spec.Licenses = append(spec.Licenses, value)
+				}
+			case "name":
+				if main {
+					spec.Name = value
+					macros["name"] = value
+				}
+			case "version":
+				if main {
+					spec.Version = value
+					macros["version"] = value
+				}
+			}
+
+		case "files":
+			if fields[0] != "%license" {
+				continue
+			}
+			for _, x := range strings.Fields(expand(strings.Join(fields[1:], " "))) {
+				x = strings.Trim(x, `"'`)
+				if x == "" || strings.Contains(x, "%") || filepath.IsAbs(x) || strings.Contains(x, "..") {
+					continue // we can't find these
+				}
+				spec.LicenseFiles = append(spec.LicenseFiles, x)
+			}
+		}
+	}
+	return spec
+}
+
+// RpmLicenses returns the licenses in the value of a License tag. It is used as
+// an SPDX expression if all of the ids are known. Otherwise it is read as an
+// expression of Fedora short names, which can have spaces in them, and are
+// joined by `and` and `or`.
+func RpmLicenses(value string) []*licenses.License {
+	if xs, err := spdxExpressionLicenses(value); err == nil && spdxValid(xs) {
+		return xs
+	}
+
+	tokens := []string{}
+	words := []string{}
+	flush := func() {
+		if len(words) > 0 {
+			tokens = append(tokens, RpmID(strings.Join(words, " ")))
+		}
+		words = []string{}
+	}
+	for _, x := range strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ", ",", " ").Replace(value)) {
+		switch x {
+		case "(", ")":
+			flush()
+			tokens = append(tokens, x)
+		case "and", "AND", "or", "OR":
+			flush()
+			tokens = append(tokens, strings.ToUpper(x))
+		default:
+			words = append(words, x)
+		}
+	}
+	flush()
+
+	xs, err := spdxTokensLicenses(tokens)
+	if err != nil {
+		// keep the value, since it's the best we have
+		return []*licenses.License{
+			{
+				//SPDX: "",
+				Origin: "", // unknown!
+				Custom: value,
+			},
+		}
+	}
+	return xs
+}
+
+// RpmID returns the SPDX ID for a Fedora short name of a license, or the name
+// unchanged if we don't know it.
+func RpmID(name string) string {
+	if id, exists := RpmNames[name]; exists {
+		return id
+	}
+	if m := rpmGNURegexp.FindStringSubmatch(name); m != nil {
+		minor := m[3]
+		if minor == "" {
+			minor = ".0"
+		}
+		suffix := "-only"
+		if m[4] == "+" {
+			suffix = "-or-later"
+		}
+		license := &licenses.License{
+			SPDX: m[1] + "GPL-" + m[2] + minor + suffix,
+		}
+		if err := license.Validate(); err == nil {
+			return license.SPDX
+		}
+	}
+	if license, err := licenses.NameToLicense(name); err == nil {
+		return license.SPDX
+	}
+	return name
+}
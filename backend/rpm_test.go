@@ -0,0 +1,152 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+
+package backend_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestRpmBackend(t *testing.T) {
+	rpmBackend := &backend.Rpm{
+		Debug: false,
+		Logf: func(format string, v ...interface{}) {
+			t.Logf("backend: "+format, v...)
+		},
+	}
+	apache, err := os.ReadFile("../COPYING")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tests := []struct {
+		files     map[string]string // the spec file is example.spec
+		output    string            // joined licenses, or empty for no result
+		component string
+	}{
+		{
+			map[string]string{"example.spec": `%global srcname example
+Name:           %{srcname}
+Version:        1.2.3
+Release:        1%{?dist}
+Summary:        An example
+License:        ASL 2.0 and (GPLv2+ or MIT)
+
+%description
+License: not a tag in here
+
+%package -n python3-%{srcname}
+Summary:        The python bindings
+License:        LGPLv2+ and Public Domain
+
+%prep
+%autosetup
+
+%files
+%license %{_licensedir}/other
+%doc README
+
+%changelog
+* Mon Jan 01 2001 Someone - 1.0
+- License: changed
+`},
+			"(GPL-2.0-or-later OR MIT), Apache-2.0, LGPL-2.0-or-later, Public Domain(unknown)",
+			"example@1.2.3",
+		},
+		{
+			map[string]string{"example.spec": "Name: example\nLicense: Apache-2.0 AND (MIT OR BSD-3-Clause)\n"},
+			"(BSD-3-Clause OR MIT), Apache-2.0",
+			"example",
+		},
+		{
+			map[string]string{
+				"example.spec": "Name: example\nVersion: %{ver}\n\n%files\n%license COPY* 'LICENSE 2'\n",
+				"COPYING":      string(apache),
+			},
+			"Apache-2.0",
+			"example",
+		},
+		{
+			map[string]string{"example.spec": "Name: example\n%if 0%{?fedora}\nLicense: MIT\n%else\nLicense: BSD\n%endif\n"},
+			"BSD(unknown), MIT",
+			"example",
+		},
+		{
+			map[string]string{"example.spec": "Name: example\n\n%description\nnothing\n"},
+			"",
+			"",
+		},
+	}
+
+	for i, test := range tests {
+		dir := t.TempDir()
+		for name, data := range test.files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+				t.Fatalf("test #%d: err: %v", i, err)
+			}
+		}
+		p := filepath.Join(dir, "example.spec")
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("test #%d: err: %v", i, err)
+		}
+		info := &interfaces.Info{
+			FileInfo: fileInfo,
+			UID:      iterator.FileScheme + p,
+		}
+		result, err := rpmBackend.ScanPath(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info)
+		if err != nil {
+			t.Errorf("test #%d: err: %v", i, err)
+			continue
+		}
+		out, component := "", ""
+		if result != nil {
+			out = licenses.Join(result.Licenses)
+			component = result.Component
+		}
+		if out != test.output {
+			t.Errorf("test #%d: out: %v, exp out: %v", i, out, test.output)
+		}
+		if component != test.component {
+			t.Errorf("test #%d: component: %v, exp component: %v", i, component, test.component)
+		}
+	}
+}
+
+func TestRpmID(t *testing.T) {
+	for name, exp := range map[string]string{"ASL 2.0": "Apache-2.0", "GPLv2": "GPL-2.0-only", "GPLv3+": "GPL-3.0-or-later", "LGPLv2.1+": "LGPL-2.1-or-later", "AGPLv3": "AGPL-3.0-only", "zlib": "Zlib", "BSD": "BSD"} {
+		if id := backend.RpmID(name); id != exp {
+			t.Errorf("name: %s, exp: %s, got: %s", name, exp, id)
+		}
+	}
+}
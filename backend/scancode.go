@@ -485,6 +485,7 @@ func scancodeLicensesHelper(input []*ScancodeLicenseResult, skip error) (*interf
 
 	confidence := float64(1.0)
 	output := []*licenses.License{}
+	regions := []*interfaces.Region{}
 	for _, x := range input {
 		result, err := scancodeLicenseHelper(x)
 		if err != nil {
@@ -504,6 +505,13 @@ func scancodeLicensesHelper(input []*ScancodeLicenseResult, skip error) (*interf
 		}
 		l := result.Licenses[0]
 		output = append(output, l)
+		if x.StartLine > 0 && x.EndLine >= x.StartLine {
+			regions = append(regions, &interfaces.Region{
+				License:   l,
+				StartLine: x.StartLine,
+				EndLine:   x.EndLine,
+			})
+		}
 		// XXX: since we occasionally remove duplicates, is this bad for
 		// the math?
 		confidence = confidence * result.Confidence
@@ -513,6 +521,7 @@ func scancodeLicensesHelper(input []*ScancodeLicenseResult, skip error) (*interf
 		Licenses:   output,
 		Confidence: confidence,
 		Skip:       skip,
+		Regions:    regions,
 	}, nil
 }
 
@@ -566,5 +575,6 @@ func deduplicateResult(input *interfaces.Result) (*interfaces.Result, error) {
 		Licenses:   output,
 		Confidence: input.Confidence,
 		Skip:       input.Skip,
+		Regions:    input.Regions,
 	}, nil
 }
@@ -28,6 +28,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
@@ -78,6 +79,7 @@ func (obj *Spdx) ScanData(ctx context.Context, data []byte, info *interfaces.Inf
 	defer cancel()
 
 	licenseMap := make(map[string]struct{})
+	lines := make(map[string][]int64) // line numbers of each license id
 
 	// An official parser for SPDX ID's seems be:
 	// https://github.com/spdx/tools-golang/blob/a16d50ee155238df280a68252acc25e9afb7acea/idsearcher/idsearcher.go#L269
@@ -88,7 +90,9 @@ func (obj *Spdx) ScanData(ctx context.Context, data []byte, info *interfaces.Inf
 	scanner := bufio.NewScanner(reader)
 	buf := []byte{}                       // create a buffer for very long lines
 	scanner.Buffer(buf, SpdxMaxBytesLine) // set the max size of that buffer
+	line := int64(0)
 	for scanner.Scan() {
+		line++
 		// In an effort to short-circuit things if needed, we run a
 		// check ourselves and break out early if we see that we have
 		// cancelled early.
@@ -117,6 +121,7 @@ func (obj *Spdx) ScanData(ctx context.Context, data []byte, info *interfaces.Inf
 		lid = stripTrash(lid)
 
 		licenseMap[lid] = struct{}{}
+		lines[lid] = append(lines[lid], line)
 	}
 	var skip error
 	scannerErr := scanner.Err()
@@ -132,6 +137,7 @@ func (obj *Spdx) ScanData(ctx context.Context, data []byte, info *interfaces.Inf
 	sort.Strings(ids) // deterministic order
 
 	licenseList := []*licenses.License{}
+	regions := []*interfaces.Region{}
 
 	for _, id := range ids {
 		license := &licenses.License{
@@ -156,6 +162,13 @@ func (obj *Spdx) ScanData(ctx context.Context, data []byte, info *interfaces.Inf
 		}
 
 		licenseList = append(licenseList, license)
+		for _, x := range lines[id] {
+			regions = append(regions, &interfaces.Region{
+				License:   license,
+				StartLine: x,
+				EndLine:   x,
+			})
+		}
 	}
 
 	if len(licenseMap) == 0 && skip == nil {
@@ -171,6 +184,7 @@ func (obj *Spdx) ScanData(ctx context.Context, data []byte, info *interfaces.Inf
 		Licenses:   licenseList,
 		Confidence: 1.0, // TODO: what should we put here?
 		Skip:       skip,
+		Regions:    regions,
 	}
 
 	// We perform the strange task of processing any partial results, and
@@ -186,3 +200,248 @@ func (obj *Spdx) ScanData(ctx context.Context, data []byte, info *interfaces.Inf
 func stripTrash(lid string) string {
 	return stripTrashSPDX.ReplaceAllString(lid, "")
 }
+
+// spdxExpressionLicenses parses an SPDX expression into the list of licenses
+// that it requires, which are combined by the logical AND. A choice between
+// licenses is returned as a single license with the Or field set. A license
+// with an exception is kept as a single custom license. The license structs
+// can't represent an AND inside of an OR, so if one is found, then each of the
+// ids are returned on their own, which is the more cautious reading. Any ids
+// that aren't on the SPDX list are returned as custom licenses.
+func spdxExpressionLicenses(input string) ([]*licenses.License, error) {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(input))
+	return spdxTokensLicenses(tokens)
+}
+
+// spdxTokensLicenses is the same as spdxExpressionLicenses, except that it takes
+// the list of tokens of the expression. This lets a caller use ids which have
+// spaces in them, which are then returned as custom licenses.
+func spdxTokensLicenses(tokens []string) ([]*licenses.License, error) {
+	p := &spdxParser{tokens: tokens}
+	node, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected token: %s", p.tokens[p.pos])
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "invalid expression")
+	}
+
+	var result []*licenses.License
+	for _, x := range node.and() {
+		license, ok := x.license()
+		if !ok { // an AND inside of an OR
+			result = nil
+			for _, id := range node.ids() {
+				result = append(result, spdxLicense(id))
+			}
+			break
+		}
+		result = append(result, license)
+	}
+	return result, nil
+}
+
+// spdxNameLicenses returns the licenses for a value which is either an SPDX
+// expression of known ids, or the name of a license. A well-known name is mapped
+// to its SPDX ID, and anything else is returned as a custom license.
+func spdxNameLicenses(value string) []*licenses.License {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if xs, err := spdxExpressionLicenses(value); err == nil && spdxValid(xs) {
+		return xs
+	}
+	if license, err := licenses.NameToLicense(value); err == nil {
+		return []*licenses.License{license}
+	}
+	return []*licenses.License{spdxLicense(value)}
+}
+
+// spdxValid returns true if there's at least one license, and they are all on
+// the SPDX license list. A name that isn't an SPDX ID is still a valid
+// expression of one custom id, so this is how we tell the two apart.
+func spdxValid(xs []*licenses.License) bool {
+	for _, x := range xs {
+		if err := x.Validate(); err != nil {
+			return false
+		}
+	}
+	return len(xs) > 0
+}
+
+// spdxLicense returns the license for an id, which is custom if it isn't on
+// the SPDX license list.
+func spdxLicense(id string) *licenses.License {
+	license := &licenses.License{
+		SPDX: id,
+	}
+	if err := license.Validate(); err != nil {
+		license = &licenses.License{
+			//SPDX: "",
+			Origin: "", // unknown!
+			Custom: id,
+		}
+	}
+	return license
+}
+
+// spdxNode is a node in a parsed SPDX expression. It is either an id, or an
+// operator with a list of operands.
+type spdxNode struct {
+	id       string
+	op       string // AND or OR
+	operands []*spdxNode
+}
+
+// and returns the list of nodes that this node requires.
+func (obj *spdxNode) and() []*spdxNode {
+	if obj.op != "AND" {
+		return []*spdxNode{obj}
+	}
+	result := []*spdxNode{}
+	for _, x := range obj.operands {
+		result = append(result, x.and()...)
+	}
+	return result
+}
+
+// or returns the ids that this node is a choice between. It returns false if
+// one of the choices requires more than one license.
+func (obj *spdxNode) or() ([]string, bool) {
+	if obj.op == "" {
+		return []string{obj.id}, true
+	}
+	if obj.op != "OR" {
+		return nil, false
+	}
+	result := []string{}
+	for _, x := range obj.operands {
+		ids, ok := x.or()
+		if !ok {
+			return nil, false
+		}
+		result = append(result, ids...)
+	}
+	return result, true
+}
+
+// license returns the license for this node. It returns false if it can't be
+// represented as a single license.
+func (obj *spdxNode) license() (*licenses.License, bool) {
+	ids, ok := obj.or()
+	if !ok {
+		return nil, false
+	}
+	found := make(map[string]struct{})
+	alternatives := []*licenses.License{}
+	for _, id := range ids {
+		if _, exists := found[id]; exists {
+			continue
+		}
+		found[id] = struct{}{}
+		alternatives = append(alternatives, spdxLicense(id))
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0], true
+	}
+	sort.Slice(alternatives, func(i, j int) bool {
+		return alternatives[i].String() < alternatives[j].String()
+	})
+	return &licenses.License{Or: alternatives}, true
+}
+
+// ids returns all of the ids in this node in the order that they appear.
+func (obj *spdxNode) ids() []string {
+	if obj.op == "" {
+		return []string{obj.id}
+	}
+	result := []string{}
+	for _, x := range obj.operands {
+		result = append(result, x.ids()...)
+	}
+	return result
+}
+
+// spdxParser is a recursive descent parser for SPDX expressions. The AND
+// operator binds more tightly than the OR operator.
+type spdxParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or the empty string at the end.
+func (obj *spdxParser) peek() string {
+	if obj.pos < len(obj.tokens) {
+		return obj.tokens[obj.pos]
+	}
+	return ""
+}
+
+// or parses a list of one or more operands joined by OR.
+func (obj *spdxParser) or() (*spdxNode, error) {
+	return obj.list("OR", obj.and)
+}
+
+// and parses a list of one or more operands joined by AND.
+func (obj *spdxParser) and() (*spdxNode, error) {
+	return obj.list("AND", obj.operand)
+}
+
+// list parses a list of one or more operands joined by the operator.
+func (obj *spdxParser) list(op string, next func() (*spdxNode, error)) (*spdxNode, error) {
+	node, err := next()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*spdxNode{node}
+	for obj.peek() == op {
+		obj.pos++
+		node, err := next()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, node)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &spdxNode{op: op, operands: operands}, nil
+}
+
+// operand parses a parenthesized expression, or an id with an optional
+// exception.
+func (obj *spdxParser) operand() (*spdxNode, error) {
+	switch token := obj.peek(); token {
+	case "":
+		return nil, fmt.Errorf("unexpected end")
+
+	case "(":
+		obj.pos++
+		node, err := obj.or()
+		if err != nil {
+			return nil, err
+		}
+		if obj.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		obj.pos++
+		return node, nil
+
+	case ")", "AND", "OR", "WITH":
+		return nil, fmt.Errorf("unexpected token: %s", token)
+	}
+
+	id := obj.tokens[obj.pos]
+	obj.pos++
+	if obj.peek() == "WITH" {
+		obj.pos++
+		exception := obj.peek()
+		if exception == "" || exception == "(" || exception == ")" || exception == "AND" || exception == "OR" || exception == "WITH" {
+			return nil, fmt.Errorf("missing exception")
+		}
+		obj.pos++
+		id += " WITH " + exception
+	}
+	return &spdxNode{id: id}, nil
+}
@@ -0,0 +1,252 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// TODO: should this be a subpackage?
+package backend
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidToml is an error used when a toml file can't be parsed.
+var ErrInvalidToml = errors.New("invalid toml")
+
+// tomlTables returns the keys and values of the named toml tables. This is not
+// a complete toml parser, it only understands enough to read the metadata in
+// the package manifests that we look at. Dotted keys are not split. The values
+// are strings, lists, or maps for inline tables, and any other values such as
+// numbers are returned as strings.
+func tomlTables(data []byte, names []string) (map[string]map[string]interface{}, error) {
+	tables, _, err := tomlParse(data, names, nil)
+	return tables, err
+}
+
+// tomlArrays returns each of the tables in the named arrays of tables, such as
+// the ones with a `[[name]]` header. Any keys before the first header are in
+// the array with the empty name. The values are the same as in tomlTables.
+func tomlArrays(data []byte, names []string) (map[string][]map[string]interface{}, error) {
+	_, arrays, err := tomlParse(data, nil, append([]string{""}, names...))
+	return arrays, err
+}
+
+// tomlParse is the parser for tomlTables and tomlArrays. It returns the named
+// tables and the named arrays of tables.
+func tomlParse(data []byte, names, arrayNames []string) (map[string]map[string]interface{}, map[string][]map[string]interface{}, error) {
+	tables := make(map[string]map[string]interface{})
+	arrays := make(map[string][]map[string]interface{})
+	var table map[string]interface{} // nil if we don't want this table
+	for _, x := range arrayNames {
+		if x == "" { // the keys before the first header
+			table = make(map[string]interface{})
+			arrays[x] = append(arrays[x], table)
+		}
+	}
+
+	s := string(data)
+	for s != "" {
+		line := s
+		if ix := strings.Index(s, "\n"); ix > -1 {
+			line, s = s[:ix], s[ix+1:]
+		} else {
+			s = ""
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[[") { // an array of tables header
+			table = nil
+			ix := strings.Index(trimmed, "]]")
+			if ix == -1 {
+				return nil, nil, ErrInvalidToml
+			}
+			name := strings.TrimSpace(trimmed[2:ix])
+			for _, x := range arrayNames {
+				if name == x {
+					table = make(map[string]interface{})
+					arrays[name] = append(arrays[name], table)
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") { // a table header
+			table = nil
+			ix := strings.Index(trimmed, "]")
+			if ix == -1 {
+				return nil, nil, ErrInvalidToml
+			}
+			name := strings.TrimSpace(trimmed[1:ix])
+			for _, x := range names {
+				if name == x {
+					table = make(map[string]interface{})
+					tables[name] = table
+				}
+			}
+			continue
+		}
+
+		ix := strings.Index(trimmed, "=")
+		if ix == -1 {
+			return nil, nil, ErrInvalidToml
+		}
+		key := strings.Trim(strings.TrimSpace(trimmed[:ix]), `"'`)
+		// values can span many lines, so parse from the rest of the data
+		rest := strings.TrimLeft(trimmed[ix+1:], " \t")
+		if s != "" {
+			rest += "\n" + s
+		}
+		value, remain, err := tomlValue(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		// skip over any trailing comment on the last line of the value
+		if ix := strings.Index(remain, "\n"); ix > -1 {
+			remain = remain[ix+1:]
+		} else {
+			remain = ""
+		}
+		s = remain
+		if table != nil {
+			table[key] = value
+		}
+	}
+	return tables, arrays, nil
+}
+
+// tomlValue parses the toml value at the start of the input. It returns
+// the value and the remaining input.
+func tomlValue(s string) (interface{}, string, error) {
+	switch {
+	case strings.HasPrefix(s, `"""`), strings.HasPrefix(s, "'''"):
+		quote := s[:3]
+		end := strings.Index(s[3:], quote)
+		if end == -1 {
+			return nil, "", ErrInvalidToml
+		}
+		value := strings.TrimPrefix(s[3:3+end], "\n") // first newline is trimmed
+		return value, s[3+end+3:], nil
+
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '\n' {
+				break
+			}
+			if s[i] == '"' {
+				value, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					value = s[1:i] // close enough
+				}
+				return value, s[i+1:], nil
+			}
+		}
+		return nil, "", ErrInvalidToml
+
+	case strings.HasPrefix(s, "'"):
+		end := strings.IndexAny(s[1:], "'\n")
+		if end == -1 || s[1+end] != '\'' {
+			return nil, "", ErrInvalidToml
+		}
+		return s[1 : 1+end], s[1+end+1:], nil
+
+	case strings.HasPrefix(s, "["):
+		list := []interface{}{}
+		s = s[1:]
+		for {
+			s = tomlSkip(s)
+			if s == "" {
+				return nil, "", ErrInvalidToml
+			}
+			if s[0] == ']' {
+				return list, s[1:], nil
+			}
+			value, rest, err := tomlValue(s)
+			if err != nil {
+				return nil, "", err
+			}
+			list = append(list, value)
+			s = tomlSkip(rest)
+			if strings.HasPrefix(s, ",") {
+				s = s[1:]
+			}
+		}
+
+	case strings.HasPrefix(s, "{"):
+		m := make(map[string]interface{})
+		s = s[1:]
+		for {
+			s = strings.TrimLeft(s, " \t")
+			if s == "" || s[0] == '\n' {
+				return nil, "", ErrInvalidToml
+			}
+			if s[0] == '}' {
+				return m, s[1:], nil
+			}
+			ix := strings.Index(s, "=")
+			if ix == -1 {
+				return nil, "", ErrInvalidToml
+			}
+			key := strings.Trim(strings.TrimSpace(s[:ix]), `"'`)
+			value, rest, err := tomlValue(strings.TrimLeft(s[ix+1:], " \t"))
+			if err != nil {
+				return nil, "", err
+			}
+			m[key] = value
+			s = strings.TrimLeft(rest, " \t")
+			if strings.HasPrefix(s, ",") {
+				s = s[1:]
+			}
+		}
+	}
+
+	// some other value such as a number, boolean, or date
+	end := strings.IndexAny(s, ",]}\n#")
+	if end == -1 {
+		end = len(s)
+	}
+	value := strings.TrimSpace(s[:end])
+	if value == "" {
+		return nil, "", ErrInvalidToml
+	}
+	return value, s[end:], nil
+}
+
+// tomlSkip skips over whitespace, newlines and comments.
+func tomlSkip(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if !strings.HasPrefix(s, "#") {
+			return s
+		}
+		ix := strings.Index(s, "\n")
+		if ix == -1 {
+			return ""
+		}
+		s = s[ix+1:]
+	}
+}
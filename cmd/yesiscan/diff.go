@@ -0,0 +1,134 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/ansi"
+	"github.com/awslabs/yesiscan/util/errwrap"
+
+	cli "github.com/urfave/cli/v2" // imports as package "cli"
+)
+
+// DefaultDiffOutputType is the diff output type used if none is specified.
+const DefaultDiffOutputType = "text"
+
+// DiffOutputTypes is the list of ways the diff command can render a diff.
+var DiffOutputTypes = map[string]func(*lib.Diff) (string, error){
+	"text": lib.ReturnDiffText,
+	"html": lib.ReturnDiffHtml,
+	"json": lib.ReturnDiffJSON,
+}
+
+// GetDiffOutputType returns the diff render function for the given name. If the
+// name is empty, then the DefaultDiffOutputType is used.
+func GetDiffOutputType(name string) (func(*lib.Diff) (string, error), error) {
+	if name == "" {
+		name = DefaultDiffOutputType
+	}
+	render, exists := DiffOutputTypes[name]
+	if !exists {
+		return nil, fmt.Errorf("invalid diff output type: %s", name)
+	}
+	return render, nil
+}
+
+// diffOutputTypeUsage returns the usage string of the diff output type flag.
+func diffOutputTypeUsage() string {
+	names := []string{}
+	for k := range DiffOutputTypes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("diff output type, one of: %s (defaults to %s)", strings.Join(names, ", "), DefaultDiffOutputType)
+}
+
+// Diff is the entry point for comparing two scans that were saved with the
+// --save-scan option. It loads both, applies the profiles, and renders the
+// files that gained, lost or changed licenses, along with any new licenses that
+// aren't on the SPDX license list.
+func Diff(c *cli.Context, program, version string, debug bool) error {
+	if c.NArg() != 2 {
+		return fmt.Errorf("expected the paths to the older and the newer saved scans")
+	}
+	outputPath := c.String("output-path")
+
+	logf := (&ansi.Logf{
+		Prefix:   "main: ",
+		Ellipsis: "...",
+		Enable:   false,
+		Prefixes: []string{},
+	}).Init()
+	if outputPath == "" || outputPath == "-" { // if output is stdout, noop logs
+		logf = func(format string, v ...interface{}) {
+			// noop
+		}
+	}
+
+	render, err := GetDiffOutputType(c.String("output-type"))
+	if err != nil {
+		return err
+	}
+
+	before, err := lib.ReadScanFile(c.Args().Get(0))
+	if err != nil {
+		return errwrap.Wrapf(err, "could not read older saved scan")
+	}
+	after, err := lib.ReadScanFile(c.Args().Get(1))
+	if err != nil {
+		return errwrap.Wrapf(err, "could not read newer saved scan")
+	}
+	logf("loaded scans of %d and %d paths", len(before.Results), len(after.Results))
+
+	profiles, profilesData := lib.LoadProfiles(program, c.StringSlice("profile"), logf)
+	before.Profiles, before.ProfilesData = profiles, profilesData
+	after.Profiles, after.ProfilesData = profiles, profilesData
+
+	diff, err := lib.BuildDiff(before, after)
+	if err != nil {
+		return err
+	}
+	return writeDiff(render, diff, outputPath)
+}
+
+// writeDiff renders the diff and writes it to the output path, or to stdout if
+// the path is empty or a dash.
+func writeDiff(render func(*lib.Diff) (string, error), diff *lib.Diff, outputPath string) error {
+	s, err := render(diff)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" || outputPath == "-" {
+		_, err := fmt.Print(s) // to stdout
+		return err
+	}
+	// TODO: is this the umask we should use?
+	return os.WriteFile(outputPath, []byte(s), 0660)
+}
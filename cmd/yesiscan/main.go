@@ -55,7 +55,6 @@ import (
 	"github.com/awslabs/yesiscan/util/ansi"
 	"github.com/awslabs/yesiscan/util/errwrap"
 	"github.com/awslabs/yesiscan/util/safepath"
-	"github.com/awslabs/yesiscan/web"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/ssgelm/cookiejarparser"
@@ -146,7 +145,7 @@ func CLI(program, version string, debug bool) error {
 		},
 		&cli.StringFlag{
 			Name:  "output-type",
-			Usage: "output type for reports, one of `html` or `text`",
+			Usage: outputTypeUsage(),
 		},
 		&cli.StringFlag{
 			Name:  "output-path",
@@ -156,6 +155,30 @@ func CLI(program, version string, debug bool) error {
 			Name:  "output-template",
 			Usage: "output templated path for reports (specify a dash for stdout)",
 		},
+		&cli.StringFlag{
+			Name:  "output-format-template",
+			Usage: "path to a go template file to render reports with instead of the output type",
+		},
+		&cli.StringFlag{
+			Name:  "save-scan",
+			Usage: "path to save the full scan results to for the render command",
+		},
+		&cli.BoolFlag{
+			Name:  "tree",
+			Usage: "display the results as a directory tree in console, text and html reports",
+		},
+		&cli.BoolFlag{
+			Name:  "incremental",
+			Usage: "reuse the previous results of local directories for unchanged files",
+		},
+		&cli.StringFlag{
+			Name:  "pr-base",
+			Usage: "git revision to compare against to scan only the changes of a pull request",
+		},
+		&cli.StringFlag{
+			Name:  "pr-head",
+			Usage: "git revision of the pull request to scan the changes of",
+		},
 		&cli.StringFlag{
 			Name:  "output-s3bucket",
 			Usage: "bucket name to upload to s3",
@@ -215,6 +238,60 @@ func CLI(program, version string, debug bool) error {
 					return nil
 				},
 			},
+			{
+				Name:      "render",
+				Aliases:   []string{"render"},
+				Usage:     "render a saved scan without scanning again",
+				ArgsUsage: "<saved scan path>",
+				Action: func(c *cli.Context) error {
+					return Render(c, program, version, debug)
+				},
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "profile",
+						Usage: "license set filtering profile to include",
+					},
+					&cli.StringFlag{
+						Name:  "output-type",
+						Usage: outputTypeUsage(),
+					},
+					&cli.StringFlag{
+						Name:  "output-format-template",
+						Usage: "path to a go template file to render reports with instead of the output type",
+					},
+					&cli.BoolFlag{
+						Name:  "tree",
+						Usage: "display the results as a directory tree in console, text and html reports",
+					},
+					&cli.StringFlag{
+						Name:  "output-path",
+						Usage: "output path for reports (defaults to stdout)",
+					},
+				},
+			},
+			{
+				Name:      "diff",
+				Aliases:   []string{"diff"},
+				Usage:     "compare two saved scans and show the license changes",
+				ArgsUsage: "<older saved scan path> <newer saved scan path>",
+				Action: func(c *cli.Context) error {
+					return Diff(c, program, version, debug)
+				},
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "profile",
+						Usage: "license set filtering profile to include",
+					},
+					&cli.StringFlag{
+						Name:  "output-type",
+						Usage: diffOutputTypeUsage(),
+					},
+					&cli.StringFlag{
+						Name:  "output-path",
+						Usage: "output path for the diff (defaults to stdout)",
+					},
+				},
+			},
 			{
 				Name:    "web",
 				Aliases: []string{"web"},
@@ -255,6 +332,10 @@ func App(c *cli.Context, program, version string, debug bool) error {
 	var outputType string
 	var outputPath string
 	var outputTemplate string
+	var outputFormatTemplate string
+	var saveScan string
+	var tree bool
+	var incremental bool
 	var outputS3Bucket string
 	region := s3.DefaultRegion
 	profiles := []string{}
@@ -307,6 +388,18 @@ func App(c *cli.Context, program, version string, debug bool) error {
 		if config.OutputTemplate != nil {
 			outputTemplate = *config.OutputTemplate
 		}
+		if config.OutputFormatTemplate != nil {
+			outputFormatTemplate = *config.OutputFormatTemplate
+		}
+		if config.SaveScan != nil {
+			saveScan = *config.SaveScan
+		}
+		if config.Tree != nil {
+			tree = *config.Tree
+		}
+		if config.Incremental != nil {
+			incremental = *config.Incremental
+		}
 		if config.OutputS3Bucket != nil {
 			outputS3Bucket = *config.OutputS3Bucket
 		}
@@ -375,6 +468,18 @@ func App(c *cli.Context, program, version string, debug bool) error {
 	if c.IsSet("output-template") {
 		outputTemplate = c.String("output-template")
 	}
+	if c.IsSet("output-format-template") {
+		outputFormatTemplate = c.String("output-format-template")
+	}
+	if c.IsSet("save-scan") {
+		saveScan = c.String("save-scan")
+	}
+	if c.IsSet("tree") {
+		tree = c.Bool("tree")
+	}
+	if c.IsSet("incremental") {
+		incremental = c.Bool("incremental")
+	}
 	if c.IsSet("output-s3bucket") {
 		outputS3Bucket = c.String("output-s3bucket")
 	}
@@ -839,6 +944,32 @@ func App(c *cli.Context, program, version string, debug bool) error {
 		}
 	}
 
+	// pull request mode scans two revisions and renders the diff instead
+	prBase := c.String("pr-base")
+	prHead := c.String("pr-head")
+	isPullRequest := prBase != "" || prHead != ""
+
+	// validate this before we scan, so that we don't waste the scan time
+	var ot *OutputType
+	var diffRender func(*lib.Diff) (string, error)
+	if isPullRequest {
+		if prBase == "" || prHead == "" {
+			return fmt.Errorf("both --pr-base and --pr-head must be specified")
+		}
+		if diffRender, err = GetDiffOutputType(outputType); err != nil {
+			return err
+		}
+	} else {
+		if ot, err = GetOutputType(outputType); err != nil {
+			return err
+		}
+		if outputFormatTemplate != "" { // this replaces the output type
+			if ot, err = TemplateOutputType(outputFormatTemplate); err != nil {
+				return err
+			}
+		}
+	}
+
 	if c.IsSet("noop") {
 		logf("noop!")
 		return nil
@@ -856,6 +987,21 @@ func App(c *cli.Context, program, version string, debug bool) error {
 		Profiles: profiles,
 
 		RegexpPath: regexpPath,
+
+		Incremental: incremental,
+	}
+
+	if isPullRequest {
+		diff, _, after, err := m.PullRequest(ctx, prBase, prHead)
+		if err != nil {
+			return err
+		}
+		if saveScan != "" { // only has the changed files
+			if err := lib.WriteScanFile(saveScan, after); err != nil {
+				logf("could not write saved scan: %+v", err)
+			}
+		}
+		return writeDiff(diffRender, diff, outputPath)
 	}
 
 	output, err := m.Run(ctx)
@@ -863,29 +1009,26 @@ func App(c *cli.Context, program, version string, debug bool) error {
 		return err
 	}
 
+	if saveScan != "" {
+		if err := lib.WriteScanFile(saveScan, output); err != nil {
+			logf("could not write saved scan: %+v", err)
+		}
+	}
+	output.Tree = tree // only changes how it's displayed
+
 	s := ""
 	if outputPath != "" || outputTemplate != "" || outputS3Bucket != "" {
 		var err error
 		// TODO: when we render an html version, should
 		// it look the same as the web `save` output?
-		if outputType == "text" {
-			if s, err = lib.ReturnOutputFile(output); err != nil {
-				return err
-			}
-		} else {
-			if s, err = web.ReturnOutputHtml(output); err != nil {
-				return err
-			}
+		if s, err = ot.Render(output); err != nil {
+			return err
 		}
 	}
 
 	if outputS3Bucket != "" {
-		ext := "html"
-		contentType := "text/html"
-		if outputType == "text" {
-			ext = "txt"
-			contentType = "text/plain"
-		}
+		ext := ot.Ext
+		contentType := ot.ContentType
 
 		// make a unique ID for the file
 		// XXX: we can consider different algorithms or methods here later...
@@ -1038,8 +1181,8 @@ type Config struct {
 	RegexpPath *string `json:"regexp-path"`
 	// config-path makes no sense here
 
-	// OutputType is the format the report will be sent as. Options include
-	// "html" and "text".
+	// OutputType is the format the report will be sent as. The valid
+	// options are the keys of the OutputTypes map.
 	OutputType *string `json:"output-type"`
 
 	// OutputPath is the location where the report will be saved. This will
@@ -1058,6 +1201,27 @@ type Config struct {
 	// "date": Returns the RFC3339 date with colons changed to dashes.
 	OutputTemplate *string `json:"output-template"`
 
+	// OutputFormatTemplate is the path to a go template file which is used
+	// to render the report instead of the OutputType. If the file name ends
+	// in .html or .htm then it is an html/template, otherwise it is a
+	// text/template. Please see lib.TemplateData for the data model.
+	OutputFormatTemplate *string `json:"output-format-template"`
+
+	// SaveScan is the path where the full scan results are saved. This
+	// will overwrite any existing file at this location. The saved scan
+	// can be rendered again with the render command, without scanning.
+	SaveScan *string `json:"save-scan"`
+
+	// Tree specifies that the console and text reports display the results
+	// as a directory tree instead of a flat list of paths, and that the html
+	// report opens in its directory tree mode.
+	Tree *bool `json:"tree"`
+
+	// Incremental specifies that the results of scanning local directories
+	// are stored, so that the next scan only runs the backends on the files
+	// which changed.
+	Incremental *bool `json:"incremental"`
+
 	// OutputS3Bucket prints the report to an S3 bucket with this name. Make
 	// sure you don't have anything important in the bucket as it might
 	// overwrite any file in there as the report name is chosen
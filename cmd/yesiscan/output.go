@@ -0,0 +1,218 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/web"
+)
+
+const (
+	// DefaultOutputType is the output type used if none is specified.
+	DefaultOutputType = "html"
+)
+
+// OutputType describes one of the formats that reports can be rendered in.
+type OutputType struct {
+	// Ext is the file extension (without the dot) used for this type.
+	Ext string
+
+	// ContentType is the mime type used for this type.
+	ContentType string
+
+	// Render builds the report from the scan output.
+	Render func(*lib.Output) (string, error)
+}
+
+// OutputTypes is the list of available output types for the --output-type flag.
+var OutputTypes = map[string]*OutputType{
+	"html": {
+		Ext:         "html",
+		ContentType: "text/html",
+		Render:      web.ReturnOutputHtml,
+	},
+	"text": {
+		Ext:         "txt",
+		ContentType: "text/plain",
+		Render:      lib.ReturnOutputFile,
+	},
+	"json": {
+		Ext:         "json",
+		ContentType: "application/json",
+		Render:      lib.ReturnOutputJSON,
+	},
+	"jsonl": {
+		Ext:         "jsonl",
+		ContentType: "application/jsonl",
+		Render:      lib.ReturnOutputJSONLines,
+	},
+	"spdx": {
+		Ext:         "spdx",
+		ContentType: "text/spdx",
+		Render:      lib.ReturnOutputSPDX,
+	},
+	"spdx-json": {
+		Ext:         "spdx.json",
+		ContentType: "application/spdx+json",
+		Render:      lib.ReturnOutputSPDXJSON,
+	},
+	"cyclonedx-json": {
+		Ext:         "cdx.json",
+		ContentType: "application/vnd.cyclonedx+json",
+		Render:      lib.ReturnOutputCycloneDXJSON,
+	},
+	"cyclonedx-xml": {
+		Ext:         "cdx.xml",
+		ContentType: "application/vnd.cyclonedx+xml",
+		Render:      lib.ReturnOutputCycloneDXXML,
+	},
+	"sarif": {
+		Ext:         "sarif",
+		ContentType: "application/sarif+json",
+		Render:      lib.ReturnOutputSARIF,
+	},
+	"junit": {
+		Ext:         "xml",
+		ContentType: "application/xml",
+		Render:      lib.ReturnOutputJUnit,
+	},
+	"markdown": {
+		Ext:         "md",
+		ContentType: "text/markdown",
+		Render:      lib.ReturnOutputMarkdown,
+	},
+	"csv": {
+		Ext:         "csv",
+		ContentType: "text/csv",
+		Render:      lib.ReturnOutputCSV,
+	},
+	"tsv": {
+		Ext:         "tsv",
+		ContentType: "text/tab-separated-values",
+		Render:      lib.ReturnOutputTSV,
+	},
+	"csv-summary": {
+		Ext:         "summary.csv",
+		ContentType: "text/csv",
+		Render:      lib.ReturnOutputCSVSummary,
+	},
+	"tsv-summary": {
+		Ext:         "summary.tsv",
+		ContentType: "text/tab-separated-values",
+		Render:      lib.ReturnOutputTSVSummary,
+	},
+	"notice": {
+		Ext:         "notice.txt",
+		ContentType: "text/plain",
+		Render:      lib.ReturnOutputNotice,
+	},
+	"notice-html": {
+		Ext:         "notice.html",
+		ContentType: "text/html",
+		Render:      lib.ReturnOutputNoticeHtml,
+	},
+	"reuse": {
+		Ext:         "reuse.txt",
+		ContentType: "text/plain",
+		Render:      lib.ReturnOutputReuse,
+	},
+}
+
+// GetOutputType returns the output type struct for the given name. The empty
+// name returns the default output type.
+func GetOutputType(name string) (*OutputType, error) {
+	if name == "" {
+		name = DefaultOutputType
+	}
+	outputType, exists := OutputTypes[name]
+	if !exists {
+		return nil, fmt.Errorf("invalid output type: %s", name)
+	}
+	return outputType, nil
+}
+
+// OutputTypeNames returns a sorted list of the valid output type names.
+func OutputTypeNames() []string {
+	names := []string{}
+	for k := range OutputTypes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// outputTypeUsage returns the usage string for the --output-type flag.
+func outputTypeUsage() string {
+	names := []string{}
+	for _, x := range OutputTypeNames() {
+		names = append(names, "`"+x+"`")
+	}
+	return fmt.Sprintf("output type for reports, one of %s", strings.Join(names, ", "))
+}
+
+// TemplateOutputType returns an output type which renders reports with the go
+// template file at this path. If the file name ends in .html or .htm, then it
+// is an html/template, otherwise it is a text/template. The file extension and
+// content type come from the file name, ignoring any .tmpl or .tpl suffix. The
+// template is parsed here so that any errors are found before the scan runs.
+func TemplateOutputType(path string) (*OutputType, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "could not read output format template")
+	}
+
+	name := filepath.Base(path)
+	for _, x := range []string{".tmpl", ".tpl"} {
+		name = strings.TrimSuffix(name, x)
+	}
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if ext == "" {
+		ext = "txt"
+	}
+	isHtml := ext == "html" || ext == "htm"
+
+	t, err := lib.ParseOutputTemplate(filepath.Base(path), string(data), isHtml)
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "could not parse output format template")
+	}
+
+	contentType := "text/plain"
+	if x := mime.TypeByExtension("." + ext); x != "" {
+		contentType = x
+	}
+
+	return &OutputType{
+		Ext:         ext,
+		ContentType: contentType,
+		Render:      t.Render,
+	}, nil
+}
@@ -0,0 +1,97 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/ansi"
+	"github.com/awslabs/yesiscan/util/errwrap"
+
+	cli "github.com/urfave/cli/v2" // imports as package "cli"
+)
+
+// Render is the entry point for rendering a scan that was saved with the
+// --save-scan option. It loads the results, applies the profiles, and renders
+// them with the output type, without running any iterators or backends. If no
+// output type or template is specified, then it renders for the console.
+func Render(c *cli.Context, program, version string, debug bool) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected the path to one saved scan")
+	}
+	outputPath := c.String("output-path")
+
+	logf := (&ansi.Logf{
+		Prefix:   "main: ",
+		Ellipsis: "...",
+		Enable:   false,
+		Prefixes: []string{},
+	}).Init()
+	if outputPath == "" || outputPath == "-" { // if output is stdout, noop logs
+		logf = func(format string, v ...interface{}) {
+			// noop
+		}
+	}
+
+	var ot *OutputType
+	if c.IsSet("output-type") {
+		var err error
+		if ot, err = GetOutputType(c.String("output-type")); err != nil {
+			return err
+		}
+	}
+	if c.IsSet("output-format-template") { // this replaces the output type
+		var err error
+		if ot, err = TemplateOutputType(c.String("output-format-template")); err != nil {
+			return err
+		}
+	}
+
+	output, err := lib.ReadScanFile(c.Args().Get(0))
+	if err != nil {
+		return errwrap.Wrapf(err, "could not read saved scan")
+	}
+	logf("loaded scan of %d paths from %s %s", len(output.Results), output.Program, output.Version)
+
+	output.Profiles, output.ProfilesData = lib.LoadProfiles(program, c.StringSlice("profile"), logf)
+	output.Tree = c.Bool("tree")
+
+	render := lib.ReturnOutputConsole
+	if ot != nil {
+		render = ot.Render
+	}
+	s, err := render(output)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" || outputPath == "-" {
+		_, err := fmt.Print(s) // to stdout
+		return err
+	}
+	// TODO: is this the umask we should use?
+	return os.WriteFile(outputPath, []byte(s), 0660)
+}
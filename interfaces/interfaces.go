@@ -134,6 +134,13 @@ type Info struct {
 	// modified with the GenUID function to return something more useful, as
 	// a human readable UID is more valuable than an internal path.
 	UID string
+
+	// Root is the directory that the iterator is walking, or the directory
+	// of the file if it is scanning a single file. Backends which read other
+	// files, such as the license file that a manifest refers to, must not
+	// read anything outside of it, so that the results don't depend on the
+	// machine that the scan runs on.
+	Root safepath.AbsDir
 }
 
 // Backend is the common interface for backends. Any useful backend must also
@@ -158,6 +165,17 @@ type SetupBackend interface {
 	Setup(ctx context.Context) error
 }
 
+// DependentBackend is a backend whose result for a file also depends on the
+// contents of other files, such as the debian copyright file that applies to
+// it. Only the file itself is checked for changes by an incremental scan, so
+// the stored results of this backend are never reused, and it always runs.
+type DependentBackend interface {
+	Backend
+
+	// Dependent returns true if the results depend on other files.
+	Dependent() bool
+}
+
 // DataBackend is the extended backend that is most efficient for receiving data
 // since all the reads are done once, and each backend only has to read from one
 // memory address. You should implement this backend if you can. It assumes that
@@ -248,6 +266,25 @@ type Result struct {
 	// If multiple reasons exist, then this can be a multi-err of any sort.
 	Skip error
 
+	// Regions is an optional list of line spans in the file where each
+	// license was found. Backends that know this should fill it in so that
+	// the locations can be shown in reports. It is nil for backends that
+	// can't determine this.
+	Regions []*Region
+
+	// Component is the name and version of the dependency that this result
+	// is a determination for, such as `golang.org/x/text@v0.3.7`. It is set
+	// by backends which make one determination for a whole dependency,
+	// usually on its directory, so that the results can be grouped by
+	// dependency. It is empty otherwise.
+	Component string
+
+	// Holders is the list of copyright holders that are declared for this
+	// path by some metadata, such as a debian/copyright file. These are
+	// set by the backend, unlike the copyright statements that are found
+	// in the data, which are stored in the Meta field by the engine.
+	Holders []string
+
 	// Meta stores some metadata about a result. This is populated by the
 	// engine for tracking purposes, and isn't meant to be either read or
 	// set by the implemented backend that returns this.
@@ -314,6 +351,19 @@ func (obj *Result) Cmp(result *Result) error {
 	return nil
 }
 
+// Region is a span of lines in a file where a license was found.
+type Region struct {
+	// License is the license that was found in this region.
+	License *licenses.License
+
+	// StartLine is the first line of the region. Lines start at one.
+	StartLine int64
+
+	// EndLine is the last line of the region. It is the same as the start
+	// line for a region of only one line.
+	EndLine int64
+}
+
 // Meta stores some metadata about the scanning operation. It is used to make
 // the results more informative if a display engine or formatter would like to
 // do so.
@@ -331,6 +381,23 @@ type Meta struct {
 	// this field is redundant, but it is here for consistency with the idea
 	// of storing the Result's associated metadata alongside it.
 	Backend Backend
+
+	// SHA1 is the hex encoded sha1 checksum of the data that was scanned.
+	// It is empty for directories.
+	SHA1 string
+
+	// SHA256 is the hex encoded sha256 checksum of the data that was
+	// scanned. It is empty for directories.
+	SHA256 string
+
+	// Copyrights is the list of copyright statements that were found in
+	// the data that was scanned. It is empty if there were none.
+	Copyrights []string
+
+	// LicenseText is the full content of the data that was scanned if it
+	// has the name of a license file, such as LICENSE or COPYING. It is
+	// empty for all other files.
+	LicenseText string
 }
 
 // ResultSet is the organized set of results that is produced after running a
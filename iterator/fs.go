@@ -85,6 +85,13 @@ type Fs struct {
 	// must be confident that your results will be properly unique.
 	GenUID func(safepath.Path) (string, error)
 
+	// Include is an optional function that is called with each path that
+	// is found while walking, and only the paths that it returns true for
+	// are scanned. If it returns false for a directory, then that entire
+	// directory is skipped. The root path is always walked. This is used to
+	// scan only the files which changed between two git revisions.
+	Include func(safepath.Path) bool
+
 	// Unlock is a function that should be called as part of the Close
 	// method once this resource is finished. It can be defined when
 	// building this iterator in case we want a mechanism for the caller of
@@ -164,9 +171,10 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 		info := &interfaces.Info{
 			FileInfo: fileInfo,
 			UID:      uid,
+			Root:     safepath.UnsafeParseIntoAbsDir(filepath.Dir(absFile.Path())),
 		}
 
-		if absFile.HasExtInsensitive(ZipExtension) || absFile.HasExtInsensitive(JarExtension) || absFile.HasExtInsensitive(WhlExtension) {
+		if absFile.HasExtInsensitive(ZipExtension) || absFile.HasExtInsensitive(JarExtension) || absFile.HasExtInsensitive(WhlExtension) || absFile.HasExtInsensitive(NupkgExtension) {
 			iterator := &Zip{
 				Debug: obj.Debug,
 				Logf: func(format string, v ...interface{}) {
@@ -183,6 +191,7 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 					ZipExtension,
 					JarExtension,
 					WhlExtension,
+					NupkgExtension,
 				},
 			}
 
@@ -192,7 +201,7 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 			return iterators, nil
 		}
 
-		if absFile.HasExtInsensitive(TarExtension) {
+		if absFile.HasExtInsensitive(TarExtension) || absFile.HasExtInsensitive(GemExtension) {
 			iterator := &Tar{
 				Debug: obj.Debug,
 				Logf: func(format string, v ...interface{}) {
@@ -205,6 +214,10 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 				Path: absFile,
 
 				//AllowAnyExtension: false, // not helpful here
+				AllowedExtensions: []string{
+					TarExtension,
+					GemExtension,
+				},
 			}
 
 			mu.Lock()
@@ -280,6 +293,8 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 		return iterators, nil // iterators should be empty
 	}
 
+	root := safepath.UnsafeParseIntoAbsDir(obj.Path.Path())
+
 	// TODO: Replace this with a parallel walk for performance
 	// TODO: Maybe add a separate flag/switch for it in the options?
 	// TODO: Make sure result aggregation and skipdir support still works!
@@ -311,6 +326,14 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 			return nil
 		}
 
+		// Skip anything that we weren't asked to include.
+		if obj.Include != nil && path != obj.Path.Path() && !obj.Include(safePath) {
+			if safePath.IsDir() {
+				return interfaces.SkipDir
+			}
+			return nil
+		}
+
 		// Check for a .gitmodules file.
 		gitIterators, err := obj.GitSubmodulesHelper(ctx, safePath)
 		if err != nil {
@@ -339,7 +362,7 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 			// connect into this fs iterator... This will avoid a
 			// lot of code duplication and also prevent us from
 			// forgetting to add these everywhere...
-			if absFile.HasExtInsensitive(ZipExtension) || absFile.HasExtInsensitive(JarExtension) || absFile.HasExtInsensitive(WhlExtension) {
+			if absFile.HasExtInsensitive(ZipExtension) || absFile.HasExtInsensitive(JarExtension) || absFile.HasExtInsensitive(WhlExtension) || absFile.HasExtInsensitive(NupkgExtension) {
 				iterator := &Zip{
 					Debug: obj.Debug,
 					Logf: func(format string, v ...interface{}) {
@@ -356,6 +379,7 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 						ZipExtension,
 						JarExtension,
 						WhlExtension,
+						NupkgExtension,
 					},
 				}
 
@@ -367,7 +391,7 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 				// whole .zip file in one go specially...
 			}
 
-			if absFile.HasExtInsensitive(TarExtension) {
+			if absFile.HasExtInsensitive(TarExtension) || absFile.HasExtInsensitive(GemExtension) {
 				iterator := &Tar{
 					Debug: obj.Debug,
 					Logf: func(format string, v ...interface{}) {
@@ -380,6 +404,10 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 					Path: absFile,
 
 					//AllowAnyExtension: false, // not helpful here
+					AllowedExtensions: []string{
+						TarExtension,
+						GemExtension,
+					},
 				}
 
 				mu.Lock()
@@ -467,6 +495,7 @@ func (obj *Fs) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interfa
 		info := &interfaces.Info{
 			FileInfo: fileInfo,
 			UID:      uid,
+			Root:     root,
 		}
 		// We want to ignore the ErrUnknownLicense results, and error if
 		// we hit any actual errors that we should bubble upwards.
@@ -0,0 +1,96 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package iterator_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestFsInclude(t *testing.T) {
+	dir := t.TempDir()
+	for _, x := range []string{"a/x.c", "a/y.c", "b/z.c", "top.c"} {
+		p := filepath.Join(dir, x)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Errorf("err: %+v", err)
+			return
+		}
+		if err := os.WriteFile(p, []byte("hello\n"), 0600); err != nil {
+			t.Errorf("err: %+v", err)
+			return
+		}
+	}
+	absDir, err := safepath.ParseIntoAbsDir(dir + "/")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+
+	include := map[string]struct{}{
+		"a/":     {},
+		"a/x.c":  {},
+		"top.c":  {},
+		"b/z.c":  {}, // its directory isn't included
+		"absent": {},
+	}
+	fs := &iterator.Fs{
+		Logf:   func(format string, v ...interface{}) {},
+		Prefix: absDir,
+		Path:   absDir,
+		Include: func(p safepath.Path) bool {
+			relPath, err := safepath.StripPrefix(p, absDir)
+			if err != nil {
+				return false
+			}
+			_, exists := include[relPath.String()]
+			return exists
+		},
+	}
+
+	got := []string{}
+	scan := func(ctx context.Context, p safepath.Path, info *interfaces.Info) error {
+		got = append(got, strings.TrimPrefix(info.UID, iterator.FileScheme+absDir.String()))
+		return nil
+	}
+	if _, err := fs.Recurse(context.Background(), scan); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	sort.Strings(got)
+
+	exp := []string{"", "a/", "a/x.c", "top.c"} // the root is always walked
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("exp: %+v", exp)
+		t.Errorf("got: %+v", got)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"strings"
 	"sync"
 	"syscall"
@@ -134,6 +135,12 @@ type Git struct {
 	// because you can have weirdly named branches that can trick you.
 	Rev string
 
+	// Since is an optional revision to compare against. If it is set, then
+	// only the files which differ between it and the revision that we
+	// scan are walked. This is useful for scanning only the changes in a
+	// pull request. It accepts anything that Rev does.
+	Since string
+
 	// iterators store the list of which iterators we created, so we know
 	// which ones we have to close!
 	iterators []interfaces.Iterator
@@ -202,6 +209,9 @@ func (obj *Git) Validate() error {
 			return err
 		}
 	}
+	if obj.Since != "" && strings.Contains(obj.Since, separator) {
+		return fmt.Errorf("provided since is invalid")
+	}
 
 	// At most one must be true.
 	a := obj.Hash != ""
@@ -287,6 +297,7 @@ func (obj *Git) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interf
 	// XXX: we can consider different algorithms or methods here later...
 	// uniqueString is used to make a unique hash to store repositories with a
 	// different hash or ref or rev separately.
+	// NOTE: Since doesn't change what gets checked out, so it's not here.
 	uniqueString := obj.URL + separator + obj.Hash + separator + obj.Ref + separator + obj.Rev
 	sum := sha256.Sum256([]byte(uniqueString))
 	hashRelDir, err := safepath.ParseIntoRelDir(fmt.Sprintf("%x", sum))
@@ -342,6 +353,16 @@ func (obj *Git) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interf
 			return nil, errwrap.Wrapf(err, "error opening repository")
 		}
 
+		// A Rev or Since could be a branch that has since moved.
+		if obj.Rev != "" || obj.Since != "" {
+			obj.Logf("fetching %s", obj.String())
+			err := repository.FetchContext(ctx, &git.FetchOptions{})
+			if err != nil && err != git.NoErrAlreadyUpToDate {
+				obj.unlock()
+				return nil, errwrap.Wrapf(err, "error fetching repository %s", obj.String())
+			}
+		}
+
 	} else if err != nil {
 		obj.unlock()
 		return nil, errwrap.Wrapf(err, "error cloning repository %s", obj.String())
@@ -487,9 +508,10 @@ func (obj *Git) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interf
 			return nil, err
 		}
 
-		checkoutOptions := &git.CheckoutOptions{}
-		if obj.Hash != "" {
-			checkoutOptions.Hash = hash
+		// We always checkout the hash that we found, since otherwise
+		// we'd get the master branch when using a Ref or a Rev.
+		checkoutOptions := &git.CheckoutOptions{
+			Hash: hash,
 		}
 		// We use the consistent hash approach to identify the repo so
 		// that we have a unique identifier to use everywhere...
@@ -503,6 +525,30 @@ func (obj *Git) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interf
 		}
 	}
 
+	var include func(safepath.Path) bool
+	if obj.Since != "" {
+		sinceHash, err := repository.ResolveRevision(plumbing.Revision(obj.Since))
+		if err != nil {
+			obj.unlock()
+			return nil, errwrap.Wrapf(err, "could not resolve since: %s", obj.Since)
+		}
+		changed, err := gitChangedPaths(repository, *sinceHash, hash)
+		if err != nil {
+			obj.unlock()
+			return nil, errwrap.Wrapf(err, "could not compare with since: %s", obj.Since)
+		}
+		obj.Logf("found %d changed paths since: %s", len(changed), obj.Since)
+
+		include = func(safePath safepath.Path) bool {
+			relPath, err := safepath.StripPrefix(safePath, repoAbsDir)
+			if err != nil {
+				return false // programming error
+			}
+			_, exists := changed[relPath.String()]
+			return exists
+		}
+	}
+
 	obj.iterators = []interfaces.Iterator{}
 
 	u, err := url.Parse(obj.URL) // build a url to modify
@@ -563,6 +609,8 @@ func (obj *Git) Recurse(ctx context.Context, scan interfaces.ScanFunc) ([]interf
 			return x.String(), nil
 		},
 
+		Include: include,
+
 		//Unlock: unlock,
 	}
 	obj.iterators = append(obj.iterators, iterator)
@@ -585,6 +633,45 @@ func (obj *Git) Close() error {
 	return errs
 }
 
+// gitChangedPaths returns the set of relative paths which differ between the
+// trees of two commits, including the paths that were added or removed. The
+// parent directories of each of these paths are also included, with a trailing
+// slash, so that they can be walked to find them.
+// TODO: changes to git submodules aren't found, since they aren't in the tree.
+func gitChangedPaths(repository *git.Repository, a, b plumbing.Hash) (map[string]struct{}, error) {
+	trees := []*object.Tree{}
+	for _, h := range []plumbing.Hash{a, b} {
+		commit, err := repository.CommitObject(h)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
+
+	changes, err := object.DiffTree(trees[0], trees[1])
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]struct{})
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name == "" { // added or removed
+				continue
+			}
+			changed[name] = struct{}{}
+			for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+				changed[dir+"/"] = struct{}{}
+			}
+		}
+	}
+	return changed, nil
+}
+
 // modified from: https://github.com/go-git/go-git/blob/2f7c4ae04d62705c98db0cf900410b5e6f6d5021/worktree.go#L211
 // formerly: func (w *Worktree) getCommitFromCheckoutOptions(opts *CheckoutOptions) (plumbing.Hash, error)
 func getCommitFromRef(repository *git.Repository, ref plumbing.ReferenceName) (plumbing.Hash, error) {
@@ -43,6 +43,10 @@ import (
 const (
 	// TarExtension is the standard extension used for tar URI's.
 	TarExtension = ".tar"
+
+	// GemExtension is used for ruby .gem files. This is included here since
+	// they are actually tar files.
+	GemExtension = ".gem"
 )
 
 var (
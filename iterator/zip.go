@@ -50,6 +50,10 @@ const (
 	// WhlExtension is used for python .whl files. This is included here since
 	// they are just zip files that are named differently.
 	WhlExtension = ".whl"
+
+	// NupkgExtension is used for nuget .nupkg files. This is included here
+	// since they are just zip files that are named differently.
+	NupkgExtension = ".nupkg"
 )
 
 var (
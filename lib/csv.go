@@ -0,0 +1,301 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util"
+)
+
+const (
+	// CSVVerdictPass is the verdict of a result that matched no profile
+	// other than the default one.
+	CSVVerdictPass = "pass"
+
+	// csvFormulaChars are the characters which start a formula in a
+	// spreadsheet.
+	csvFormulaChars = "=+-@\t\r"
+)
+
+// CSVHeader is the header row of the csv and tsv output types.
+var CSVHeader = []string{
+	"path",
+	"uid",
+	"smart_uri",
+	"backend",
+	"license_expression",
+	"confidence",
+	"weighted_confidence",
+	"profiles",
+	"verdict",
+	"skip",
+	"provenance",
+}
+
+// CSVSummaryHeader is the header row of the csv and tsv summary output types.
+var CSVSummaryHeader = []string{
+	"profile",
+	"license",
+	"count",
+	"files",
+}
+
+// ReturnOutputCSV returns a string of output, formatted as csv with one row for
+// each backend result of each scanned path.
+func ReturnOutputCSV(output *Output) (string, error) {
+	records, err := BuildCSVRecords(output)
+	if err != nil {
+		return "", err
+	}
+	return writeRecords(records, ',')
+}
+
+// ReturnOutputTSV is the same as ReturnOutputCSV except that it uses tabs.
+func ReturnOutputTSV(output *Output) (string, error) {
+	records, err := BuildCSVRecords(output)
+	if err != nil {
+		return "", err
+	}
+	return writeRecords(records, '\t')
+}
+
+// ReturnOutputCSVSummary returns a string of output, formatted as csv with one
+// row for each license that was found by each profile.
+func ReturnOutputCSVSummary(output *Output) (string, error) {
+	records, err := BuildCSVSummaryRecords(output)
+	if err != nil {
+		return "", err
+	}
+	return writeRecords(records, ',')
+}
+
+// ReturnOutputTSVSummary is the same as ReturnOutputCSVSummary except that it
+// uses tabs.
+func ReturnOutputTSVSummary(output *Output) (string, error) {
+	records, err := BuildCSVSummaryRecords(output)
+	if err != nil {
+		return "", err
+	}
+	return writeRecords(records, '\t')
+}
+
+// BuildCSVRecords builds the rows of the csv output, starting with the header.
+// There is one row for each backend result of each scanned path. The rows are
+// sorted by uid, and then by decreasing weighted confidence and backend name.
+// The path is relative to the root of the scanned input and includes the path
+// of any archive that it was found in. The profiles are the names of those that
+// this particular result matched, and the verdict is the highest severity of
+// those profiles, or `pass` if only the default profile matched.
+func BuildCSVRecords(output *Output) ([][]string, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	type location struct {
+		path       string
+		provenance string
+	}
+	locations := make(map[string]*location) // keyed by uid
+	for _, p := range buildProvenance(output) {
+		for _, f := range p.files {
+			locations[f.uid] = &location{
+				path:       provenancePath(p, f),
+				provenance: csvProvenance(p),
+			}
+		}
+	}
+
+	uids := []string{}
+	for uid := range output.Results {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids) // deterministic order
+
+	records := [][]string{CSVHeader}
+	for _, uid := range uids {
+		m := output.Results[uid]
+		bs, _, err := annotateBackends(m, output.BackendWeights)
+		if err != nil {
+			return nil, err
+		}
+
+		loc, exists := locations[uid]
+		if !exists { // a directory, or something we can't place
+			loc = &location{path: stripQuery(uid)}
+		}
+
+		for _, b := range bs {
+			result := m[b.Backend]
+			single := map[interfaces.Backend]*interfaces.Result{b.Backend: result}
+
+			ids := []string{}
+			for _, x := range profileLicenses(nil, single) {
+				ids = append(ids, SPDXLicenseID(x))
+			}
+
+			profiles := []string{}
+			verdict := CSVVerdictPass
+			for _, name := range output.Profiles {
+				profile := output.ProfilesData[name]
+				if len(profileLicenses(profile, single)) == 0 {
+					continue
+				}
+				profiles = append(profiles, name)
+				if profile == nil { // the default profile is only a note
+					continue
+				}
+				if severity := ProfileSeverity(profile); verdict == CSVVerdictPass || severityIndex(severity) < severityIndex(verdict) {
+					verdict = severity
+				}
+			}
+
+			skip := ""
+			if result.Skip != nil {
+				skip = result.Skip.Error()
+			}
+
+			records = append(records, []string{
+				loc.path,
+				uid,
+				util.SmartURI(uid),
+				b.Backend.String(),
+				strings.Join(ids, " AND "),
+				fmt.Sprintf("%.4f", result.Confidence),
+				fmt.Sprintf("%.4f", b.ScaledConfidence),
+				strings.Join(profiles, "; "),
+				verdict,
+				skip,
+				loc.provenance,
+			})
+		}
+	}
+
+	return records, nil
+}
+
+// BuildCSVSummaryRecords builds the rows of the csv summary output, starting
+// with the header. There is one row for each license that was found in the
+// files that each profile matched, in profile order and then by license name.
+// The count is the number of backend results that found that license, which is
+// the same accounting as the summary in SimpleProfiles, and the files are the
+// number of matched files that it was found in.
+func BuildCSVSummaryRecords(output *Output) ([][]string, error) {
+	report, err := BuildReport(output)
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{CSVSummaryHeader}
+	for _, profile := range report.Profiles {
+		summary := report.Summary.Profiles[profile.Name]
+
+		files := make(map[string]int)
+		for _, file := range report.Files {
+			if !util.StrInList(profile.Name, file.Profiles) {
+				continue
+			}
+			for _, x := range file.Licenses {
+				files[x]++
+			}
+		}
+
+		names := []string{}
+		for k := range summary.Licenses {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, x := range names {
+			records = append(records, []string{
+				profile.Name,
+				x,
+				fmt.Sprintf("%d", summary.Licenses[x]),
+				fmt.Sprintf("%d", files[x]),
+			})
+		}
+	}
+
+	return records, nil
+}
+
+// writeRecords writes the records with the given field separator. Each cell is
+// neutralised first, since these files are usually opened in a spreadsheet.
+func writeRecords(records [][]string, comma rune) (string, error) {
+	safe := [][]string{}
+	for _, record := range records {
+		row := []string{}
+		for _, x := range record {
+			row = append(row, csvNeutralise(x))
+		}
+		safe = append(safe, row)
+	}
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Comma = comma
+	if err := w.WriteAll(safe); err != nil { // also flushes
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// csvNeutralise prefixes a cell with a single quote if it starts with one of the
+// characters that makes spreadsheet software run it as a formula. A path or a
+// custom license name could otherwise be used to inject one.
+func csvNeutralise(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaChars, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvProvenance returns a compact description of where a file came from. Each
+// level of nesting is separated by a `>`, starting with the original input.
+func csvProvenance(p *provenance) string {
+	xs := []string{}
+	for x := p; x != nil; x = x.parent {
+		s := fmt.Sprintf("%s:%s", x.kind, x.name)
+		if x.version != "" {
+			s += "@" + x.version
+		}
+		xs = append([]string{s}, xs...)
+	}
+	return strings.Join(xs, " > ")
+}
+
+// severityIndex returns the position of a severity in the Severities list, so
+// that a lower index is more severe. Unknown severities sort last.
+func severityIndex(severity string) int {
+	for i, x := range Severities {
+		if x == severity {
+			return i
+		}
+	}
+	return len(Severities)
+}
@@ -0,0 +1,75 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/lib"
+)
+
+func TestReturnOutputCSV(t *testing.T) {
+	output := testOutput()
+	b1 := &testBackend{name: "b1"}
+	output.BackendWeights[b1] = 1.0
+	output.Results["file:///tmp/d"] = map[interfaces.Backend]*interfaces.Result{
+		b1: {Confidence: 1.0, Skip: fmt.Errorf("=HYPERLINK(\"http://example.com/\")")},
+	}
+
+	s, err := lib.ReturnOutputCSV(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	records, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if len(records) != 6 { // the header and one row for each backend result
+		t.Errorf("expected 6 records, got: %d", len(records))
+		return
+	}
+	for _, record := range records {
+		for _, x := range record {
+			if x != "" && strings.ContainsAny(x[:1], "=+-@\t\r") {
+				t.Errorf("cell is not neutralised: %q", x)
+			}
+		}
+	}
+
+	found := false
+	for _, record := range records {
+		if record[3] == "b1" && record[9] == "'=HYPERLINK(\"http://example.com/\")" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the row was not found in: %v", records)
+	}
+}
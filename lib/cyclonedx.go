@@ -0,0 +1,430 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// CycloneDXSpecVersion is the version of the CycloneDX specification
+	// that we output.
+	CycloneDXSpecVersion = "1.5"
+
+	// CycloneDXXMLNamespace is the xml namespace of the above version.
+	CycloneDXXMLNamespace = "http://cyclonedx.org/schema/bom/1.5"
+
+	// CycloneDXPropertyPrefix is the namespace used for the names of all
+	// the properties that we add.
+	CycloneDXPropertyPrefix = "yesiscan:"
+)
+
+// CycloneDXBom is a CycloneDX 1.5 bill of materials. The same struct is used to
+// produce both the json and the xml formats.
+type CycloneDXBom struct {
+	XMLName xml.Name `json:"-" xml:"bom"`
+	Xmlns   string   `json:"-" xml:"xmlns,attr"`
+
+	BomFormat    string `json:"bomFormat" xml:"-"`
+	SpecVersion  string `json:"specVersion" xml:"-"`
+	SerialNumber string `json:"serialNumber" xml:"serialNumber,attr"`
+	Version      int    `json:"version" xml:"version,attr"`
+
+	Metadata   *CycloneDXMetadata  `json:"metadata" xml:"metadata"`
+	Components CycloneDXComponents `json:"components" xml:"components"`
+}
+
+// CycloneDXMetadata is the metadata section of the bom.
+type CycloneDXMetadata struct {
+	Timestamp string          `json:"timestamp" xml:"timestamp"`
+	Tools     *CycloneDXTools `json:"tools" xml:"tools"`
+}
+
+// CycloneDXTools is the list of tools that built the bom.
+type CycloneDXTools struct {
+	Components CycloneDXComponents `json:"components" xml:"components"`
+}
+
+// CycloneDXComponent is a component. We build one for each of the inputs that
+// we scanned, such as a git repository, a downloaded url or an archive. Each of
+// these contains the nested components of the files that were found in it, as
+// well as any nested inputs such as archives.
+type CycloneDXComponent struct {
+	Type   string `json:"type" xml:"type,attr"`
+	BomRef string `json:"bom-ref,omitempty" xml:"bom-ref,attr,omitempty"`
+
+	Name               string                      `json:"name" xml:"name"`
+	Version            string                      `json:"version,omitempty" xml:"version,omitempty"`
+	Description        string                      `json:"description,omitempty" xml:"description,omitempty"`
+	Hashes             CycloneDXHashes             `json:"hashes,omitempty" xml:"hashes,omitempty"`
+	Licenses           CycloneDXLicenses           `json:"licenses,omitempty" xml:"licenses,omitempty"`
+	ExternalReferences CycloneDXExternalReferences `json:"externalReferences,omitempty" xml:"externalReferences,omitempty"`
+	Properties         CycloneDXProperties         `json:"properties,omitempty" xml:"properties,omitempty"`
+	Components         CycloneDXComponents         `json:"components,omitempty" xml:"components,omitempty"`
+	Evidence           *CycloneDXEvidence          `json:"evidence,omitempty" xml:"evidence,omitempty"`
+}
+
+// CycloneDXHash is a checksum of a component.
+type CycloneDXHash struct {
+	Alg     string `json:"alg" xml:"alg,attr"`
+	Content string `json:"content" xml:",chardata"`
+}
+
+// CycloneDXLicenses is a list of licenses or license expressions. It has its
+// own xml marshaller since the elements in the list have different names.
+type CycloneDXLicenses []*CycloneDXLicenseChoice
+
+// MarshalXML writes the list of licenses as a licenses element that contains a
+// license or expression element for each entry.
+func (obj CycloneDXLicenses) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, x := range obj {
+		if x.License != nil {
+			if err := e.EncodeElement(x.License, xml.StartElement{Name: xml.Name{Local: "license"}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.EncodeElement(x.Expression, xml.StartElement{Name: xml.Name{Local: "expression"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// CycloneDXLicenseChoice is either a single license or a license expression.
+type CycloneDXLicenseChoice struct {
+	License    *CycloneDXLicense `json:"license,omitempty"`
+	Expression string            `json:"expression,omitempty"`
+}
+
+// CycloneDXLicense is a single license. The ID is used if it's on the SPDX
+// license list, otherwise the name is used.
+type CycloneDXLicense struct {
+	ID         string              `json:"id,omitempty" xml:"id,omitempty"`
+	Name       string              `json:"name,omitempty" xml:"name,omitempty"`
+	Properties CycloneDXProperties `json:"properties,omitempty" xml:"properties,omitempty"`
+}
+
+// CycloneDXExternalReference is a link to somewhere outside of the bom.
+type CycloneDXExternalReference struct {
+	Type string `json:"type" xml:"type,attr"`
+	URL  string `json:"url" xml:"url"`
+}
+
+// CycloneDXProperty is a name and value pair.
+type CycloneDXProperty struct {
+	Name  string `json:"name" xml:"name,attr"`
+	Value string `json:"value" xml:",chardata"`
+}
+
+// CycloneDXEvidence is the evidence that supports the licenses of a component.
+type CycloneDXEvidence struct {
+	Occurrences CycloneDXOccurrences `json:"occurrences,omitempty" xml:"occurrences,omitempty"`
+	Licenses    CycloneDXLicenses    `json:"licenses,omitempty" xml:"licenses,omitempty"`
+}
+
+// CycloneDXOccurrence is a location where the evidence was found.
+type CycloneDXOccurrence struct {
+	Location string `json:"location" xml:"location"`
+}
+
+// CycloneDXComponents is a list of components.
+type CycloneDXComponents []*CycloneDXComponent
+
+// MarshalXML writes the list with a component element for each entry.
+func (obj CycloneDXComponents) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalXMLList(e, start, "component", len(obj), func(i int) interface{} { return obj[i] })
+}
+
+// CycloneDXHashes is a list of hashes.
+type CycloneDXHashes []*CycloneDXHash
+
+// MarshalXML writes the list with a hash element for each entry.
+func (obj CycloneDXHashes) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalXMLList(e, start, "hash", len(obj), func(i int) interface{} { return obj[i] })
+}
+
+// CycloneDXExternalReferences is a list of external references.
+type CycloneDXExternalReferences []*CycloneDXExternalReference
+
+// MarshalXML writes the list with a reference element for each entry.
+func (obj CycloneDXExternalReferences) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalXMLList(e, start, "reference", len(obj), func(i int) interface{} { return obj[i] })
+}
+
+// CycloneDXProperties is a list of properties.
+type CycloneDXProperties []*CycloneDXProperty
+
+// MarshalXML writes the list with a property element for each entry.
+func (obj CycloneDXProperties) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalXMLList(e, start, "property", len(obj), func(i int) interface{} { return obj[i] })
+}
+
+// CycloneDXOccurrences is a list of occurrences.
+type CycloneDXOccurrences []*CycloneDXOccurrence
+
+// MarshalXML writes the list with an occurrence element for each entry.
+func (obj CycloneDXOccurrences) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalXMLList(e, start, "occurrence", len(obj), func(i int) interface{} { return obj[i] })
+}
+
+// ReturnOutputCycloneDXJSON returns a string of output, formatted as a
+// CycloneDX bom in the json format.
+func ReturnOutputCycloneDXJSON(output *Output) (string, error) {
+	bom, err := BuildCycloneDXBom(output)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(bom, "", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	return string(b) + "\n", nil
+}
+
+// ReturnOutputCycloneDXXML returns a string of output, formatted as a CycloneDX
+// bom in the xml format.
+func ReturnOutputCycloneDXXML(output *Output) (string, error) {
+	bom, err := BuildCycloneDXBom(output)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := xml.MarshalIndent(bom, "", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(b) + "\n", nil
+}
+
+// BuildCycloneDXBom builds a CycloneDX bom from an Output. Each of the inputs
+// that we scanned becomes a component, and nested inputs such as an archive
+// inside a git repository are nested inside the component they were found in.
+// Each scanned file becomes a nested file component with its checksums. The
+// licenses of a file are the ones that the backends agreed on most strongly,
+// after weighting, and every individual backend result is listed as evidence
+// with the backend name, the confidence, and the file it was found in. The
+// licenses of the input components are the combination of those of its files.
+// Directories are not included since they can't have checksums.
+func BuildCycloneDXBom(output *Output) (*CycloneDXBom, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	bom := &CycloneDXBom{
+		Xmlns:       CycloneDXXMLNamespace,
+		BomFormat:   "CycloneDX",
+		SpecVersion: CycloneDXSpecVersion,
+		Version:     1,
+		Components:  CycloneDXComponents{},
+	}
+
+	components := make(map[*provenance]*CycloneDXComponent)
+	i := 0 // counter for bom-ref's
+	for _, p := range buildProvenance(output) {
+		i++
+		component := &CycloneDXComponent{
+			Type:        "library",
+			BomRef:      fmt.Sprintf("component-%d", i),
+			Name:        p.name,
+			Version:     p.version,
+			Description: p.comment,
+			Properties: CycloneDXProperties{
+				{Name: CycloneDXPropertyPrefix + "kind", Value: p.kind},
+			},
+		}
+		switch p.kind {
+		case ProvenanceGit:
+			if p.url != "" {
+				component.ExternalReferences = append(component.ExternalReferences, &CycloneDXExternalReference{
+					Type: "vcs",
+					URL:  p.url,
+				})
+			}
+		case ProvenanceHttp:
+			component.ExternalReferences = append(component.ExternalReferences, &CycloneDXExternalReference{
+				Type: "distribution",
+				URL:  p.url,
+			})
+		case ProvenanceArchive:
+			component.Type = "file"
+		}
+
+		ids := []string{}
+		found := make(map[string]struct{})
+		for _, f := range p.files {
+			i++
+			file, concluded, err := cycloneDXFile(f, output.BackendWeights)
+			if err != nil {
+				return nil, err
+			}
+			file.BomRef = fmt.Sprintf("component-%d", i)
+			component.Components = append(component.Components, file)
+
+			for _, x := range concluded {
+				if _, exists := found[x]; exists {
+					continue
+				}
+				found[x] = struct{}{}
+				ids = append(ids, x)
+			}
+		}
+		sort.Strings(ids)
+		if len(ids) > 0 {
+			component.Licenses = CycloneDXLicenses{
+				{Expression: strings.Join(ids, " AND ")},
+			}
+		}
+
+		components[p] = component
+		if p.parent == nil {
+			bom.Components = append(bom.Components, component)
+			continue
+		}
+		parent := components[p.parent]
+		parent.Components = append(parent.Components, component)
+	}
+
+	// Build a serial number from the contents so that it's unique per
+	// scan, and the same if the same things are scanned with the same
+	// results.
+	b, err := json.Marshal(bom)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5 style
+	sum[8] = (sum[8] & 0x3f) | 0x80 // variant
+	bom.SerialNumber = fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+
+	bom.Metadata = &CycloneDXMetadata{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Tools: &CycloneDXTools{
+			Components: CycloneDXComponents{
+				{
+					Type:    "application",
+					Name:    output.Program,
+					Version: output.Version,
+				},
+			},
+		},
+	}
+
+	return bom, nil
+}
+
+// cycloneDXFile builds the file component for a scanned file. It also returns
+// the list of license ids that were concluded for it.
+func cycloneDXFile(f *provenanceFile, backendWeights map[interfaces.Backend]float64) (*CycloneDXComponent, []string, error) {
+	component := &CycloneDXComponent{
+		Type: "file",
+		Name: strings.TrimPrefix(f.name, "./"),
+		Hashes: CycloneDXHashes{
+			{Alg: "SHA-1", Content: f.sha1},
+			{Alg: "SHA-256", Content: f.sha256},
+		},
+		Properties: CycloneDXProperties{
+			{Name: CycloneDXPropertyPrefix + "uid", Value: f.uid},
+		},
+	}
+
+	concluded, err := ConsensusLicenses(f.results, backendWeights)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := []string{}
+	for _, license := range concluded {
+		ids = append(ids, SPDXLicenseID(license))
+	}
+	if len(ids) > 0 {
+		component.Licenses = CycloneDXLicenses{
+			{Expression: strings.Join(ids, " AND ")},
+		}
+	}
+
+	bs, _, err := annotateBackends(f.results, backendWeights)
+	if err != nil {
+		return nil, nil, err
+	}
+	evidence := &CycloneDXEvidence{
+		Occurrences: CycloneDXOccurrences{
+			{Location: f.name},
+		},
+	}
+	for _, b := range bs {
+		result := f.results[b.Backend]
+		for _, license := range result.Licenses {
+			x := cycloneDXLicense(license)
+			x.Properties = CycloneDXProperties{
+				{Name: CycloneDXPropertyPrefix + "backend", Value: b.Backend.String()},
+				{Name: CycloneDXPropertyPrefix + "confidence", Value: fmt.Sprintf("%.4f", result.Confidence)},
+				{Name: CycloneDXPropertyPrefix + "file", Value: f.uid},
+			}
+			evidence.Licenses = append(evidence.Licenses, &CycloneDXLicenseChoice{License: x})
+		}
+	}
+	if len(evidence.Licenses) > 0 {
+		component.Evidence = evidence
+	}
+
+	return component, ids, nil
+}
+
+// cycloneDXLicense returns the representation of a single license.
+func cycloneDXLicense(license *licenses.License) *CycloneDXLicense {
+	if len(license.Or) == 0 && license.IsSPDX() {
+		return &CycloneDXLicense{ID: license.SPDX}
+	}
+	return &CycloneDXLicense{Name: license.String()}
+}
+
+// marshalXMLList writes a list of n elements inside of the start element. Each
+// of them is named child. The xml package can do this with the `a>b` syntax in
+// a struct tag, but it then adds the empty parent element for empty lists.
+func marshalXMLList(e *xml.Encoder, start xml.StartElement, child string, n int, get func(int) interface{}) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := e.EncodeElement(get(i), xml.StartElement{Name: xml.Name{Local: child}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
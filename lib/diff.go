@@ -0,0 +1,499 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+// Diff is the set of license changes between two scans. The changes are listed
+// for each profile, so that only the licenses which are relevant to a profile
+// are considered. This is the documented schema for the `json` diff output, so
+// be careful when changing it.
+type Diff struct {
+	// Before describes the older scan.
+	Before *DiffScan `json:"before"`
+
+	// After describes the newer scan.
+	After *DiffScan `json:"after"`
+
+	// Profiles is the list of changes for each profile, in order.
+	Profiles []*DiffProfile `json:"profiles"`
+
+	// Unknown is the list of licenses which aren't on the SPDX license list,
+	// that are in the newer scan, but which weren't in the older one.
+	Unknown []*DiffUnknown `json:"unknown"`
+}
+
+// DiffScan describes one of the scans that was compared.
+type DiffScan struct {
+	Program string   `json:"program"`
+	Version string   `json:"version"`
+	Args    []string `json:"args"`
+}
+
+// DiffProfile is the list of changes that are relevant to a single profile.
+type DiffProfile struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+
+	// Added is the list of files that are only in the newer scan, and which
+	// have licenses that match the profile.
+	Added []*DiffFile `json:"added"`
+
+	// Removed is the list of files that are only in the older scan, and
+	// which had licenses that matched the profile.
+	Removed []*DiffFile `json:"removed"`
+
+	// Changed is the list of files that are in both scans, but where the
+	// licenses that match the profile are different.
+	Changed []*DiffFile `json:"changed"`
+}
+
+// DiffFile is a file that gained or lost licenses.
+type DiffFile struct {
+	// Path is the path of the file relative to the root of the scanned
+	// input, or the uid if that isn't known.
+	Path string `json:"path"`
+
+	// UID is the uid in the newer scan. It is empty if it was removed.
+	UID string `json:"uid,omitempty"`
+
+	// BeforeUID is the uid in the older scan. It is empty if it was added.
+	BeforeUID string `json:"before_uid,omitempty"`
+
+	// SmartURI is a (hopefully) clickable version of the newest uid.
+	SmartURI string `json:"smart_uri"`
+
+	// Before is the sorted list of licenses that matched in the older scan.
+	Before []string `json:"before"`
+
+	// After is the sorted list of licenses that matched in the newer scan.
+	After []string `json:"after"`
+
+	// Gained is the list of licenses that are only in the newer scan.
+	Gained []string `json:"gained"`
+
+	// Lost is the list of licenses that are only in the older scan.
+	Lost []string `json:"lost"`
+
+	// ContentChanged is true if the file is in both scans, and it has a
+	// different checksum.
+	ContentChanged bool `json:"content_changed"`
+
+	// Moved is true if the file was matched by its checksum because it is
+	// at a different path.
+	Moved bool `json:"moved"`
+}
+
+// DiffUnknown is a newly found license that isn't on the SPDX license list.
+type DiffUnknown struct {
+	License string `json:"license"`
+
+	// Paths is the sorted list of files that it was found in.
+	Paths []string `json:"paths"`
+}
+
+// diffEntry is a scanned path in one of the scans that are being compared.
+type diffEntry struct {
+	uid    string
+	key    string // the uid without any query string
+	path   string
+	sha256 string
+	m      map[interfaces.Backend]*interfaces.Result
+}
+
+// diffPair is an entry in the newer scan and the matching one in the older. One
+// of these is nil if the path was added or removed.
+type diffPair struct {
+	before *diffEntry
+	after  *diffEntry
+	moved  bool
+}
+
+// BuildDiff compares two scans and returns the changes for each of the profiles
+// of the newer scan. Paths are matched by their uid, ignoring any query string
+// such as the git hash, then by their path relative to the scanned input, and
+// finally by their content checksum so that files which moved are detected.
+func BuildDiff(before, after *Output) (*Diff, error) {
+	if before == nil || after == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	diff := &Diff{
+		Before:   &DiffScan{Program: before.Program, Version: before.Version, Args: []string{}},
+		After:    &DiffScan{Program: after.Program, Version: after.Version, Args: []string{}},
+		Profiles: []*DiffProfile{},
+		Unknown:  []*DiffUnknown{},
+	}
+	diff.Before.Args = append(diff.Before.Args, before.Args...)
+	diff.After.Args = append(diff.After.Args, after.Args...)
+
+	pairs := diffPairs(diffEntries(before), diffEntries(after))
+
+	for _, name := range after.Profiles {
+		profile := after.ProfilesData[name]
+		p := &DiffProfile{
+			Name:     name,
+			Severity: ProfileSeverity(profile),
+			Added:    []*DiffFile{},
+			Removed:  []*DiffFile{},
+			Changed:  []*DiffFile{},
+		}
+		for _, pair := range pairs {
+			file := diffFile(profile, pair)
+			if file == nil {
+				continue
+			}
+			switch {
+			case pair.before == nil:
+				p.Added = append(p.Added, file)
+			case pair.after == nil:
+				p.Removed = append(p.Removed, file)
+			default:
+				p.Changed = append(p.Changed, file)
+			}
+		}
+		diff.Profiles = append(diff.Profiles, p)
+	}
+
+	known := make(map[string]struct{}) // every license in the older scan
+	for _, m := range before.Results {
+		for _, x := range profileLicenses(nil, m) {
+			known[x.String()] = struct{}{}
+		}
+	}
+	unknown := make(map[string]*DiffUnknown)
+	for _, pair := range pairs {
+		if pair.after == nil {
+			continue
+		}
+		for _, x := range profileLicenses(nil, pair.after.m) {
+			if x.IsSPDX() {
+				continue
+			}
+			if _, exists := known[x.String()]; exists {
+				continue
+			}
+			u, exists := unknown[x.String()]
+			if !exists {
+				u = &DiffUnknown{License: x.String(), Paths: []string{}}
+				unknown[x.String()] = u
+				diff.Unknown = append(diff.Unknown, u)
+			}
+			u.Paths = append(u.Paths, pair.after.path)
+		}
+	}
+	sort.Slice(diff.Unknown, func(i, j int) bool {
+		return diff.Unknown[i].License < diff.Unknown[j].License
+	})
+	for _, u := range diff.Unknown {
+		sort.Strings(u.Paths)
+	}
+
+	return diff, nil
+}
+
+// diffEntries returns the list of scanned paths in a scan, sorted by uid.
+func diffEntries(output *Output) []*diffEntry {
+	type location struct {
+		path   string
+		sha256 string
+	}
+	locations := make(map[string]*location) // keyed by uid
+	for _, p := range buildProvenance(output) {
+		for _, f := range p.files {
+			locations[f.uid] = &location{
+				path:   provenancePath(p, f),
+				sha256: f.sha256,
+			}
+		}
+	}
+
+	uids := []string{}
+	for uid := range output.Results {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids) // deterministic order
+
+	entries := []*diffEntry{}
+	for _, uid := range uids {
+		entry := &diffEntry{
+			uid:  uid,
+			key:  stripQuery(uid),
+			path: stripQuery(uid),
+			m:    output.Results[uid],
+		}
+		if loc, exists := locations[uid]; exists {
+			entry.path = loc.path
+			entry.sha256 = loc.sha256
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// diffPairs matches the entries of the two scans. The result is sorted by path.
+func diffPairs(before, after []*diffEntry) []*diffPair {
+	byKey := make(map[string]*diffEntry)
+	byPath := make(map[string]*diffEntry)
+	bySum := make(map[string][]*diffEntry)
+	for _, x := range before {
+		byKey[x.key] = x
+		byPath[x.path] = x
+		if x.sha256 != "" {
+			bySum[x.sha256] = append(bySum[x.sha256], x)
+		}
+	}
+
+	pairs := []*diffPair{}
+	matched := make(map[*diffEntry]struct{})
+	unmatched := []*diffEntry{}
+	for _, x := range after {
+		match := byKey[x.key]
+		if match == nil {
+			match = byPath[x.path]
+		}
+		if _, exists := matched[match]; match == nil || exists {
+			unmatched = append(unmatched, x)
+			continue
+		}
+		matched[match] = struct{}{}
+		pairs = append(pairs, &diffPair{before: match, after: x})
+	}
+
+	// look for files that moved, after the exact matches are all taken
+	for _, x := range unmatched {
+		var match *diffEntry
+		for _, y := range bySum[x.sha256] {
+			if _, exists := matched[y]; !exists && x.sha256 != "" {
+				match = y
+				break
+			}
+		}
+		if match == nil {
+			pairs = append(pairs, &diffPair{after: x})
+			continue
+		}
+		matched[match] = struct{}{}
+		pairs = append(pairs, &diffPair{before: match, after: x, moved: true})
+	}
+
+	for _, x := range before {
+		if _, exists := matched[x]; !exists {
+			pairs = append(pairs, &diffPair{before: x})
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return diffPairPath(pairs[i]) < diffPairPath(pairs[j])
+	})
+	return pairs
+}
+
+// diffPairPath returns the newest path of a pair.
+func diffPairPath(pair *diffPair) string {
+	if pair.after != nil {
+		return pair.after.path
+	}
+	return pair.before.path
+}
+
+// diffFile returns the change for a pair of entries with this profile, or nil
+// if the licenses that match the profile didn't change.
+func diffFile(profile *ProfileData, pair *diffPair) *DiffFile {
+	file := &DiffFile{
+		Path:   diffPairPath(pair),
+		Before: []string{},
+		After:  []string{},
+		Gained: []string{},
+		Lost:   []string{},
+		Moved:  pair.moved,
+	}
+	if pair.before != nil {
+		file.BeforeUID = pair.before.uid
+		file.SmartURI = util.SmartURI(pair.before.uid)
+		file.Before = diffLicenseNames(profileLicenses(profile, pair.before.m))
+	}
+	if pair.after != nil {
+		file.UID = pair.after.uid
+		file.SmartURI = util.SmartURI(pair.after.uid)
+		file.After = diffLicenseNames(profileLicenses(profile, pair.after.m))
+	}
+	if pair.before != nil && pair.after != nil {
+		file.ContentChanged = pair.before.sha256 != pair.after.sha256
+	}
+
+	for _, x := range file.After {
+		if !util.StrInList(x, file.Before) {
+			file.Gained = append(file.Gained, x)
+		}
+	}
+	for _, x := range file.Before {
+		if !util.StrInList(x, file.After) {
+			file.Lost = append(file.Lost, x)
+		}
+	}
+	if len(file.Gained) == 0 && len(file.Lost) == 0 {
+		return nil
+	}
+	return file
+}
+
+// diffLicenseNames returns the names of a sorted list of licenses.
+func diffLicenseNames(ls []*licenses.License) []string {
+	names := []string{}
+	for _, x := range ls {
+		names = append(names, x.String())
+	}
+	return names
+}
+
+// ReturnDiffJSON returns a string of the diff, formatted as json.
+func ReturnDiffJSON(diff *Diff) (string, error) {
+	b, err := json.MarshalIndent(diff, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+// ReturnDiffText returns a string of the diff, formatted as plain text. Added
+// files are prefixed with a `+`, removed ones with a `-`, changed ones with a
+// `~`, and new unknown licenses with a `?`.
+func ReturnDiffText(diff *Diff) (string, error) {
+	s := fmt.Sprintf("before: %s (%s %s)\n", strings.Join(diff.Before.Args, " "), diff.Before.Program, diff.Before.Version)
+	s += fmt.Sprintf("after: %s (%s %s)\n", strings.Join(diff.After.Args, " "), diff.After.Program, diff.After.Version)
+
+	for _, p := range diff.Profiles {
+		s += fmt.Sprintf("\nprofile %s (%s):\n", p.Name, p.Severity)
+		if len(p.Added) == 0 && len(p.Removed) == 0 && len(p.Changed) == 0 {
+			s += "no changes\n"
+			continue
+		}
+		for _, x := range p.Added {
+			s += fmt.Sprintf("+ %s: %s\n", x.Path, strings.Join(x.After, ", "))
+		}
+		for _, x := range p.Removed {
+			s += fmt.Sprintf("- %s: %s\n", x.Path, strings.Join(x.Before, ", "))
+		}
+		for _, x := range p.Changed {
+			s += fmt.Sprintf("~ %s: %s\n", x.Path, diffChangeString(x))
+		}
+	}
+
+	if len(diff.Unknown) > 0 {
+		s += "\nnew unknown licenses:\n"
+	}
+	for _, x := range diff.Unknown {
+		s += fmt.Sprintf("? %s: %s\n", x.License, strings.Join(x.Paths, ", "))
+	}
+
+	return s, nil
+}
+
+// ReturnDiffHtml returns a string of the diff, formatted as a standalone html
+// page.
+func ReturnDiffHtml(diff *Diff) (string, error) {
+	e := html.EscapeString
+
+	s := "<!DOCTYPE html>\n"
+	s += "<html>\n<head>\n"
+	s += `<meta charset="utf-8">` + "\n"
+	s += "<title>License changes</title>\n"
+	s += "<style>body { font-family: sans-serif; } td, th { padding: 0.2em 0.5em; text-align: left; } .added { color: green; } .removed { color: red; } .changed { color: darkorange; }</style>\n"
+	s += "</head>\n<body>\n"
+	s += "<h1>License changes</h1>\n"
+	s += fmt.Sprintf("<p>before: <code>%s</code> (%s %s)<br />\n", e(strings.Join(diff.Before.Args, " ")), e(diff.Before.Program), e(diff.Before.Version))
+	s += fmt.Sprintf("after: <code>%s</code> (%s %s)</p>\n", e(strings.Join(diff.After.Args, " ")), e(diff.After.Program), e(diff.After.Version))
+
+	row := func(class, sign string, x *DiffFile, licenses string) string {
+		link := util.HtmlHyperlinkEncode(e(x.Path), e(x.SmartURI))
+		return fmt.Sprintf(`<tr class="%s"><td>%s</td><td>%s</td><td>%s</td></tr>`+"\n", class, sign, link, e(licenses))
+	}
+	for _, p := range diff.Profiles {
+		s += fmt.Sprintf("<h2>profile <i>%s</i> (%s)</h2>\n", e(p.Name), e(p.Severity))
+		if len(p.Added) == 0 && len(p.Removed) == 0 && len(p.Changed) == 0 {
+			s += "<p>no changes</p>\n"
+			continue
+		}
+		s += "<table>\n<tr><th></th><th>file</th><th>licenses</th></tr>\n"
+		for _, x := range p.Added {
+			s += row("added", "+", x, strings.Join(x.After, ", "))
+		}
+		for _, x := range p.Removed {
+			s += row("removed", "-", x, strings.Join(x.Before, ", "))
+		}
+		for _, x := range p.Changed {
+			s += row("changed", "~", x, diffChangeString(x))
+		}
+		s += "</table>\n"
+	}
+
+	if len(diff.Unknown) > 0 {
+		s += "<h2>new unknown licenses</h2>\n<ul>\n"
+	}
+	for _, x := range diff.Unknown {
+		s += fmt.Sprintf("<li>%s: %s</li>\n", e(x.License), e(strings.Join(x.Paths, ", ")))
+	}
+	if len(diff.Unknown) > 0 {
+		s += "</ul>\n"
+	}
+
+	s += "</body>\n</html>\n"
+	return s, nil
+}
+
+// diffChangeString returns a human readable description of a changed file.
+func diffChangeString(x *DiffFile) string {
+	s := fmt.Sprintf("%s -> %s", diffNone(x.Before), diffNone(x.After))
+	if len(x.Gained) > 0 {
+		s += fmt.Sprintf(" (gained: %s)", strings.Join(x.Gained, ", "))
+	}
+	if len(x.Lost) > 0 {
+		s += fmt.Sprintf(" (lost: %s)", strings.Join(x.Lost, ", "))
+	}
+	if x.Moved {
+		s += fmt.Sprintf(" (moved from: %s)", x.BeforeUID)
+	} else if x.ContentChanged {
+		s += " (content changed)"
+	}
+	return s
+}
+
+// diffNone returns the joined list, or a placeholder if it's empty.
+func diffNone(xs []string) string {
+	if len(xs) == 0 {
+		return "(none)"
+	}
+	return strings.Join(xs, ", ")
+}
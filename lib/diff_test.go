@@ -0,0 +1,161 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+// testDiffOutput builds an output of a git repo at a particular hash, with the
+// given license and content checksum for each relative file path.
+func testDiffOutput(t *testing.T, b interfaces.Backend, hash string, files map[string][2]string) *lib.Output {
+	git := &iterator.Git{
+		URL:  "https://example.com/project.git",
+		Hash: hash,
+	}
+	repoAbsDir, err := safepath.ParseIntoAbsDir("/tmp/repo/")
+	if err != nil {
+		t.Fatalf("err: %+v", err)
+	}
+	fs := &iterator.Fs{
+		Iterator: git,
+		Path:     repoAbsDir,
+		GenUID: func(p safepath.Path) (string, error) {
+			rel := strings.TrimPrefix(p.String(), repoAbsDir.String())
+			return "git://example.com/project.git/" + rel + "?sha1=" + hash, nil
+		},
+	}
+
+	results := interfaces.ResultSet{}
+	for rel, x := range files {
+		uid := "git://example.com/project.git/" + rel + "?sha1=" + hash
+		results[uid] = map[interfaces.Backend]*interfaces.Result{
+			b: {
+				Licenses:   []*licenses.License{{SPDX: x[0]}},
+				Confidence: 1.0,
+				Meta:       &interfaces.Meta{Iterator: fs, SHA1: x[1], SHA256: x[1]},
+			},
+		}
+	}
+	return &lib.Output{
+		Program:  "yesiscan",
+		Version:  "test",
+		Args:     []string{"https://example.com/project.git"},
+		Backends: map[string]bool{"b1": true},
+		Results:  results,
+		Profiles: []string{lib.DefaultProfileName, "gpl"},
+		ProfilesData: map[string]*lib.ProfileData{
+			lib.DefaultProfileName: nil,
+			"gpl": {
+				Licenses: []*licenses.License{{SPDX: "GPL-2.0-only"}},
+				Severity: "error",
+			},
+		},
+	}
+}
+
+func TestBuildDiff(t *testing.T) {
+	b1 := &testBackend{name: "b1"}
+	before := testDiffOutput(t, b1, "1111111111111111111111111111111111111111", map[string][2]string{
+		"main.c":      {"MIT", "aaaa"},
+		"util.c":      {"Apache-2.0", "bbbb"},
+		"old.c":       {"GPL-2.0-only", "cccc"},
+		"unchanged.c": {"MIT", "dddd"},
+	})
+	after := testDiffOutput(t, b1, "2222222222222222222222222222222222222222", map[string][2]string{
+		"main.c":      {"GPL-2.0-only", "eeee"},
+		"lib/util.c":  {"Apache-2.0", "bbbb"}, // moved
+		"new.c":       {"LicenseRef-Weird", "ffff"},
+		"unchanged.c": {"MIT", "dddd"},
+	})
+
+	diff, err := lib.BuildDiff(before, after)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if len(diff.Profiles) != 2 {
+		t.Errorf("expected 2 profiles, got: %d", len(diff.Profiles))
+		return
+	}
+
+	paths := func(files []*lib.DiffFile) []string {
+		xs := []string{}
+		for _, x := range files {
+			xs = append(xs, x.Path)
+		}
+		return xs
+	}
+
+	def := diff.Profiles[0]
+	if got, exp := paths(def.Added), []string{"new.c"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("default added: expected %v, got %v", exp, got)
+	}
+	if got, exp := paths(def.Removed), []string{"old.c"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("default removed: expected %v, got %v", exp, got)
+	}
+	if got, exp := paths(def.Changed), []string{"main.c"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("default changed: expected %v, got %v", exp, got)
+	} else if x := def.Changed[0]; !x.ContentChanged || x.Moved || !reflect.DeepEqual(x.Gained, []string{"GPL-2.0-only"}) || !reflect.DeepEqual(x.Lost, []string{"MIT"}) {
+		t.Errorf("default changed: unexpected change: %+v", x)
+	}
+
+	// only the gpl changes are relevant to this profile
+	gpl := diff.Profiles[1]
+	if len(gpl.Added) != 0 {
+		t.Errorf("gpl added: expected none, got %v", paths(gpl.Added))
+	}
+	if got, exp := paths(gpl.Removed), []string{"old.c"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("gpl removed: expected %v, got %v", exp, got)
+	}
+	if got, exp := paths(gpl.Changed), []string{"main.c"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("gpl changed: expected %v, got %v", exp, got)
+	}
+
+	if len(diff.Unknown) != 1 || !reflect.DeepEqual(diff.Unknown[0].Paths, []string{"new.c"}) {
+		t.Errorf("expected one new unknown license in new.c, got: %+v", diff.Unknown)
+	}
+
+	s, err := lib.ReturnDiffText(diff)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	for _, x := range []string{"+ new.c", "- old.c: GPL-2.0-only", "~ main.c: MIT -> GPL-2.0-only"} {
+		if !strings.Contains(s, x) {
+			t.Errorf("expected text output to contain: %s", x)
+		}
+	}
+	if strings.Contains(s, "util.c") {
+		t.Errorf("unexpected moved file in text output: %s", s)
+	}
+}
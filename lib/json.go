@@ -0,0 +1,147 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+const (
+	// ReportLineHeader is the type of the first line in the jsonl output.
+	ReportLineHeader = "header"
+
+	// ReportLineFile is the type of a line containing a ReportFile.
+	ReportLineFile = "file"
+
+	// ReportLineSkipped is the type of a line containing a skipped path.
+	ReportLineSkipped = "skipped"
+
+	// ReportLineError is the type of a line containing a backend error.
+	ReportLineError = "error"
+
+	// ReportLineWarning is the type of a line containing an iterator
+	// warning.
+	ReportLineWarning = "warning"
+
+	// ReportLineSummary is the type of the last line in the jsonl output.
+	ReportLineSummary = "summary"
+)
+
+// ReportHeader is the first record in the jsonl output. It contains the fields
+// of the Report which aren't lists of results.
+type ReportHeader struct {
+	Schema   int              `json:"schema"`
+	Program  string           `json:"program"`
+	Version  string           `json:"version"`
+	Args     []string         `json:"args"`
+	Backends map[string]bool  `json:"backends"`
+	Profiles []*ReportProfile `json:"profiles"`
+}
+
+// ReportLine is a single record in the jsonl output. The Type field says which
+// one of the other fields is set. The header is always the first line and the
+// summary is always the last one, so a consumer can stream everything between.
+type ReportLine struct {
+	Type string `json:"type"`
+
+	Header  *ReportHeader  `json:"header,omitempty"`
+	File    *ReportFile    `json:"file,omitempty"`
+	Skipped string         `json:"skipped,omitempty"`
+	Error   *ReportError   `json:"error,omitempty"`
+	Warning *ReportError   `json:"warning,omitempty"`
+	Summary *ReportSummary `json:"summary,omitempty"`
+}
+
+// ReturnOutputJSON returns a string of output, formatted as a single indented
+// json document. The schema is described by the Report struct.
+func ReturnOutputJSON(output *Output) (string, error) {
+	report, err := BuildReport(output)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	return string(b) + "\n", nil
+}
+
+// ReturnOutputJSONLines returns a string of output, formatted as json lines.
+// Each line is a ReportLine.
+func ReturnOutputJSONLines(output *Output) (string, error) {
+	buf := new(bytes.Buffer) // we'll write to here
+	if err := WriteOutputJSONLines(buf, output); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// WriteOutputJSONLines writes the json lines output to a writer, one record at
+// a time. Each line is a ReportLine.
+func WriteOutputJSONLines(w io.Writer, output *Output) error {
+	report, err := BuildReport(output)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w) // Encode adds the trailing newline
+
+	header := &ReportHeader{
+		Schema:   report.Schema,
+		Program:  report.Program,
+		Version:  report.Version,
+		Args:     report.Args,
+		Backends: report.Backends,
+		Profiles: report.Profiles,
+	}
+	if err := encoder.Encode(&ReportLine{Type: ReportLineHeader, Header: header}); err != nil {
+		return err
+	}
+	for _, x := range report.Files {
+		if err := encoder.Encode(&ReportLine{Type: ReportLineFile, File: x}); err != nil {
+			return err
+		}
+	}
+	for _, x := range report.Skipped {
+		if err := encoder.Encode(&ReportLine{Type: ReportLineSkipped, Skipped: x}); err != nil {
+			return err
+		}
+	}
+	for _, x := range report.Errors {
+		if err := encoder.Encode(&ReportLine{Type: ReportLineError, Error: x}); err != nil {
+			return err
+		}
+	}
+	for _, x := range report.Warnings {
+		if err := encoder.Encode(&ReportLine{Type: ReportLineWarning, Warning: x}); err != nil {
+			return err
+		}
+	}
+
+	return encoder.Encode(&ReportLine{Type: ReportLineSummary, Summary: report.Summary})
+}
@@ -0,0 +1,167 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// JUnitTestSuites is the top-level element of a JUnit xml report.
+type JUnitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Suites   []*JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite is a test suite. We build one for each profile.
+type JUnitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Cases    []*JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is a test case. We build one for each scanned file, and it
+// fails if the file is flagged by the profile. If the profile is only
+// informational, then it is skipped instead.
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Skipped   *JUnitSkipped `xml:"skipped,omitempty"`
+}
+
+// JUnitFailure is the failure of a test case.
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitSkipped marks a test case as skipped.
+type JUnitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// ReturnOutputJUnit returns a string of output, formatted as a JUnit xml report.
+// Each profile is a test suite, and each scanned file is a test case in every
+// suite. A test case fails if the file has a license and it is matched by that
+// profile. The failure type is the profile severity. Only the error and warning
+// severities fail, so a match of the default profile, which matches everything,
+// or of a profile with the note severity, is a skipped test case instead, and
+// the CI job stays green.
+func ReturnOutputJUnit(output *Output) (string, error) {
+	report, err := BuildReport(output)
+	if err != nil {
+		return "", err
+	}
+
+	suites := &JUnitTestSuites{
+		Name:   output.Program,
+		Suites: []*JUnitTestSuite{},
+	}
+	for _, profile := range report.Profiles {
+		suite := &JUnitTestSuite{
+			Name:  profile.Name,
+			Cases: []*JUnitTestCase{},
+		}
+		for _, file := range report.Files {
+			testCase := &JUnitTestCase{
+				Name:      file.UID,
+				ClassName: fmt.Sprintf("%s.%s", output.Program, profile.Name),
+			}
+			suite.Cases = append(suite.Cases, testCase)
+			suite.Tests++
+
+			if !reportFileFlagged(file, profile.Name) {
+				continue
+			}
+			message := fmt.Sprintf("found: %s", strings.Join(file.Licenses, ", "))
+			severity := ProfileSeverity(output.ProfilesData[profile.Name])
+			if !junitFails(severity) {
+				suite.Skipped++
+				testCase.Skipped = &JUnitSkipped{
+					Message: message,
+				}
+				continue
+			}
+			suite.Failures++
+			testCase.Failure = &JUnitFailure{
+				Message: message,
+				Type:    severity,
+				Text:    junitText(file),
+			}
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	b, err := xml.MarshalIndent(suites, "", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(b) + "\n", nil
+}
+
+// reportFileFlagged returns true if the file has a license and was matched by
+// the named profile.
+func reportFileFlagged(file *ReportFile, profile string) bool {
+	if len(file.Licenses) == 0 {
+		return false
+	}
+	for _, x := range file.Profiles {
+		if x == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// junitFails returns true if a match of a profile with this severity should
+// fail the test case. Anything less than a warning is only informational.
+func junitFails(severity string) bool {
+	return severity == SeverityError || severity == SeverityWarning
+}
+
+// junitText returns the body of a failure with the result of each backend.
+func junitText(file *ReportFile) string {
+	s := fmt.Sprintf("%s\n", file.SmartURI)
+	for _, r := range file.Results {
+		s += fmt.Sprintf("%s (%.2f%%): %s", r.Backend, r.ScaledConfidence*100.0, strings.Join(r.Licenses, ", "))
+		if r.Skip != "" {
+			s += fmt.Sprintf(" (error: %s)", r.Skip)
+		}
+		s += "\n"
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/lib"
+)
+
+func TestReturnOutputJUnit(t *testing.T) {
+	output := testOutput()
+	output.ProfilesData["gpl"].Severity = lib.SeverityError
+
+	s, err := lib.ReturnOutputJUnit(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	suites := &lib.JUnitTestSuites{}
+	if err := xml.Unmarshal([]byte(s), suites); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if len(suites.Suites) != 2 {
+		t.Errorf("expected two suites, got: %d", len(suites.Suites))
+		return
+	}
+
+	// The default profile matches everything, so it must never fail.
+	def := suites.Suites[0]
+	if def.Name != "default" || def.Failures != 0 || def.Skipped != 2 || def.Tests != 3 {
+		t.Errorf("unexpected default suite: %+v", def)
+	}
+	for _, x := range def.Cases {
+		if x.Failure != nil {
+			t.Errorf("unexpected failure of %s", x.Name)
+		}
+	}
+
+	gpl := suites.Suites[1]
+	if gpl.Failures != 1 || gpl.Skipped != 0 {
+		t.Errorf("unexpected gpl suite: %+v", gpl)
+	}
+	if c := gpl.Cases[2]; c.Name != "file:///tmp/c" || c.Failure == nil || c.Failure.Type != lib.SeverityError {
+		t.Errorf("unexpected gpl case: %+v", c)
+	}
+	if suites.Failures != 1 || suites.Skipped != 2 {
+		t.Errorf("unexpected totals: %d failures, %d skipped", suites.Failures, suites.Skipped)
+	}
+
+	// A profile with the note severity is only informational.
+	output.ProfilesData["gpl"].Severity = lib.SeverityNote
+	if s, err = lib.ReturnOutputJUnit(output); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if strings.Contains(s, "<failure") {
+		t.Errorf("unexpected failure in: %s", s)
+	}
+}
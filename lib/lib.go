@@ -25,13 +25,18 @@ package lib
 
 import (
 	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"sort"
 	"sync"
 
 	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/copyrights"
 	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
 	"github.com/awslabs/yesiscan/util/safepath"
 )
 
@@ -49,6 +54,12 @@ type Core struct {
 	Backends        []interfaces.Backend
 	Iterators       []interfaces.Iterator // TODO: should this be passed into Run instead?
 	ShutdownOnError bool
+
+	// Manifests are the optional stored results of previous scans, for any
+	// of the iterators above. If an iterator has one, then the files which
+	// haven't changed since then aren't scanned again, and the manifest is
+	// updated with the new results of the rest.
+	Manifests map[interfaces.Iterator]*Manifest
 }
 
 // Init initializes and validates the core struct before use.
@@ -175,6 +186,7 @@ func (obj *Core) Run(ctx context.Context) (interfaces.ResultSet, []string, map[s
 			},
 
 			Backends: obj.Backends,
+			Manifest: obj.Manifests[x],
 		}
 		if err := scanner.Init(); err != nil {
 			return nil, nil, nil, errwrap.Wrapf(err, "scanner init failed")
@@ -281,6 +293,11 @@ type Scanner struct {
 
 	Backends []interfaces.Backend
 
+	// Manifest is an optional store of previous results. If it's set, then
+	// the files which haven't changed reuse those results instead of being
+	// scanned again.
+	Manifest *Manifest
+
 	wg *sync.WaitGroup
 	mu *sync.Mutex
 
@@ -340,6 +357,17 @@ func (obj *Scanner) Scan(ctx context.Context, path safepath.Path, info *interfac
 	// TODO: we could switch and avoid doing this if we knew that
 	// zero backends were going to need it, but we know most will,
 	// so avoid optimizing early, and skip pre-checking for this.
+	// The backends whose results depend on other files always run again,
+	// even if the results of the others were restored from the manifest.
+	backends := obj.Backends
+	isManifest := obj.Manifest != nil && !info.FileInfo.IsDir()
+	restored := isManifest && obj.restore(path, info, "") // cheap stat check
+	if restored {
+		if backends = dependentBackends(obj.Backends); len(backends) == 0 {
+			return nil
+		}
+	}
+
 	var data []byte
 	var err error
 	if !info.FileInfo.IsDir() {
@@ -348,11 +376,21 @@ func (obj *Scanner) Scan(ctx context.Context, path safepath.Path, info *interfac
 			return err // TODO: errwrap?
 		}
 	}
+	sum1, sum256 := checksums(data, info)
+	if isManifest && !restored && obj.restore(path, info, sum256) { // only touched
+		restored = true
+		if backends = dependentBackends(obj.Backends); len(backends) == 0 {
+			return nil
+		}
+	}
+	statements, text := attribution(data, info)
 
 	obj.Logf("scanning: %s", path)
 
+	found := make(map[interfaces.Backend]*interfaces.Result) // for manifest
+
 Loop:
-	for _, backend := range obj.Backends {
+	for _, backend := range backends {
 		// Some backends aren't particularly well-behaved with
 		// regards to obeying the context cancellation signal.
 		// In an effort to short-circuit things if needed, we
@@ -440,6 +478,9 @@ Loop:
 			}
 			// tag (annotate) the result
 			tagResultBackend(result, backend)
+			tagResultChecksums(result, sum1, sum256)
+			tagResultAttribution(result, statements, text)
+			sortResultMore(result)
 
 			// store results
 			obj.mu.Lock()
@@ -464,6 +505,10 @@ Loop:
 			obj.results[info.UID][backend] = result
 			obj.mu.Unlock()
 
+			mu.Lock()
+			found[backend] = result
+			mu.Unlock()
+
 			// XXX: cache results
 			//	if x, ok := backend.(interfaces.CachedDataBackend); ok {
 			//		result, err = x.LookupData(ctx, data, info)
@@ -483,9 +528,77 @@ Loop:
 		return errwrap.Wrapf(ea, "scan func errored")
 	}
 
+	if isManifest && !restored {
+		for backend := range found {
+			if isDependentBackend(backend) {
+				delete(found, backend) // these are never restored
+			}
+		}
+		obj.Manifest.Store(info.UID, info.FileInfo, sum256, found)
+	}
+
 	return nil
 }
 
+// restore uses the results of a file from the manifest if it hasn't changed
+// since they were stored. It returns true if it did. The checksum is optional.
+// The results of the dependent backends aren't restored, since they can change
+// even if the file didn't, and so the caller must run those again.
+func (obj *Scanner) restore(path safepath.Path, info *interfaces.Info, sum256 string) bool {
+	stored, ok := obj.Manifest.Lookup(info.UID, info.FileInfo, sum256)
+	if !ok {
+		return false
+	}
+	backends := make(map[string]interfaces.Backend)
+	for _, backend := range obj.Backends {
+		backends[backend.String()] = backend
+	}
+
+	for name := range stored {
+		if _, exists := backends[name]; !exists {
+			return false // programming error, since the key matched
+		}
+	}
+	if obj.Debug {
+		obj.Logf("unchanged: %s", path)
+	}
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if len(stored) == 0 {
+		obj.passes[info.UID] = struct{}{}
+		return true
+	}
+	if _, exists := obj.results[info.UID]; !exists {
+		obj.results[info.UID] = make(map[interfaces.Backend]*interfaces.Result)
+	}
+	for name, r := range stored {
+		if isDependentBackend(backends[name]) {
+			continue // only in manifests from older versions
+		}
+		obj.results[info.UID][backends[name]] = scanFileOutputResult(r, backends[name], nil)
+	}
+	return true
+}
+
+// dependentBackends returns the backends whose results depend on other files.
+func dependentBackends(backends []interfaces.Backend) []interfaces.Backend {
+	result := []interfaces.Backend{}
+	for _, backend := range backends {
+		if isDependentBackend(backend) {
+			result = append(result, backend)
+		}
+	}
+	return result
+}
+
+// isDependentBackend returns true if the results of the backend depend on other
+// files, and so they can't be restored from a manifest.
+func isDependentBackend(backend interfaces.Backend) bool {
+	x, ok := backend.(interfaces.DependentBackend)
+	return ok && x.Dependent()
+}
+
 // Result returns the results after a Scan operation is run. It contains a Wait
 // the blocks until all the Scan work has finished. To cancel and unblock this,
 // cancel the context that was passed in to the Scan function. Do *not* call
@@ -546,3 +659,71 @@ func tagResultIterator(result *interfaces.Result, iterator interfaces.Iterator)
 		tagResultIterator(x, iterator)
 	}
 }
+
+func tagResultChecksums(result *interfaces.Result, sha1, sha256 string) {
+	if result.Meta == nil {
+		result.Meta = &interfaces.Meta{}
+	}
+	result.Meta.SHA1 = sha1 // tag it!
+	result.Meta.SHA256 = sha256
+	if result.More == nil || len(result.More) == 0 {
+		return
+	}
+	for _, x := range result.More {
+		tagResultChecksums(x, sha1, sha256)
+	}
+}
+
+// sortResultMore sorts the less likely results by decreasing confidence, with
+// ties broken by the license names, so that they're always shown in the same
+// order.
+func sortResultMore(result *interfaces.Result) {
+	sort.SliceStable(result.More, func(i, j int) bool {
+		a, b := result.More[i], result.More[j]
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		return licenses.Join(a.Licenses) < licenses.Join(b.Licenses)
+	})
+	for _, x := range result.More {
+		sortResultMore(x)
+	}
+}
+
+// checksums returns the hex encoded sha1 and sha256 sums of the data. They are
+// empty for directories.
+func checksums(data []byte, info *interfaces.Info) (string, string) {
+	if info.FileInfo.IsDir() {
+		return "", ""
+	}
+	sum1 := sha1.Sum(data)
+	sum256 := sha256.Sum256(data)
+	return hex.EncodeToString(sum1[:]), hex.EncodeToString(sum256[:])
+}
+
+func tagResultAttribution(result *interfaces.Result, statements []string, text string) {
+	if result.Meta == nil {
+		result.Meta = &interfaces.Meta{}
+	}
+	result.Meta.Copyrights = statements // tag it!
+	result.Meta.LicenseText = text
+	if result.More == nil || len(result.More) == 0 {
+		return
+	}
+	for _, x := range result.More {
+		tagResultAttribution(x, statements, text)
+	}
+}
+
+// attribution returns the copyright statements found in the data, and the data
+// as a string if this is a license file. They are empty for directories.
+func attribution(data []byte, info *interfaces.Info) ([]string, string) {
+	if info.FileInfo.IsDir() {
+		return nil, ""
+	}
+	statements := copyrights.Find(data)
+	if !licenses.IsLicenseFile(info.FileInfo.Name()) {
+		return statements, ""
+	}
+	return statements, string(data)
+}
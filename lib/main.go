@@ -24,9 +24,8 @@
 package lib
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
@@ -35,9 +34,9 @@ import (
 
 	"github.com/awslabs/yesiscan/backend"
 	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
 	"github.com/awslabs/yesiscan/parser"
 	"github.com/awslabs/yesiscan/util/errwrap"
-	"github.com/awslabs/yesiscan/util/licenses"
 	"github.com/awslabs/yesiscan/util/safepath"
 )
 
@@ -47,6 +46,18 @@ var Backends = []string{
 	"licenseclassifier",
 	"cran",
 	"pom",
+	"jar",
+	"npm",
+	"python",
+	"cargo",
+	"gomod",
+	"dep5",
+	"rpm",
+	"gem",
+	"nuget",
+	"composer",
+	"cocoapods",
+	"reuse",
 	"spdx",
 	"askalono",
 	"scancode",
@@ -77,6 +88,21 @@ type Main struct {
 
 	// RegexpPath specifies a path the regular expressions to use.
 	RegexpPath string
+
+	// Rev is an optional git revision to scan. It is used for every input,
+	// all of which must be git repositories. If it's empty, then the HEAD
+	// of the default branch is scanned.
+	Rev string
+
+	// Since is an optional git revision to compare against. If it is set,
+	// then only the files which differ between it and the scanned revision
+	// are scanned. All of the inputs must be git repositories.
+	Since string
+
+	// Incremental specifies that the results of each local directory input
+	// should be stored in a manifest, so that the next scan of it only runs
+	// the backends on the files which changed.
+	Incremental bool
 }
 
 // Run is the main method for the Main struct. We use a struct as a way to pass
@@ -145,6 +171,20 @@ func (obj *Main) Run(ctx context.Context) (*Output, error) {
 		iterators = append(iterators, ixs...)
 	}
 
+	if obj.Rev != "" || obj.Since != "" {
+		for _, x := range iterators {
+			it, ok := x.(*iterator.Git)
+			if !ok {
+				return nil, fmt.Errorf("a revision can only be used with git inputs, got: %s", x)
+			}
+			if it.Hash != "" {
+				return nil, fmt.Errorf("a revision can't be used with a git input that has a hash: %s", it.URL)
+			}
+			it.Rev = obj.Rev
+			it.Since = obj.Since
+		}
+	}
+
 	backends := []interfaces.Backend{}
 	backendWeights := make(map[interfaces.Backend]float64)
 
@@ -183,6 +223,138 @@ func (obj *Main) Run(ctx context.Context) (*Output, error) {
 		backendWeights[pomBackend] = 2.0 // TODO: adjust as needed
 	}
 
+	if enabled, _ := obj.Backends["jar"]; enabled {
+		jarBackend := &backend.Jar{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, jarBackend)
+		backendWeights[jarBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["npm"]; enabled {
+		npmBackend := &backend.Npm{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, npmBackend)
+		backendWeights[npmBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["python"]; enabled {
+		pythonBackend := &backend.Python{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, pythonBackend)
+		backendWeights[pythonBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["cargo"]; enabled {
+		cargoBackend := &backend.Cargo{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, cargoBackend)
+		backendWeights[cargoBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["gomod"]; enabled {
+		gomodBackend := &backend.GoMod{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, gomodBackend)
+		backendWeights[gomodBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["dep5"]; enabled {
+		dep5Backend := &backend.Dep5{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, dep5Backend)
+		backendWeights[dep5Backend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["rpm"]; enabled {
+		rpmBackend := &backend.Rpm{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, rpmBackend)
+		backendWeights[rpmBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["gem"]; enabled {
+		gemBackend := &backend.Gem{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, gemBackend)
+		backendWeights[gemBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["nuget"]; enabled {
+		nugetBackend := &backend.Nuget{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, nugetBackend)
+		backendWeights[nugetBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["composer"]; enabled {
+		composerBackend := &backend.Composer{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, composerBackend)
+		backendWeights[composerBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["cocoapods"]; enabled {
+		cocoapodsBackend := &backend.Cocoapods{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, cocoapodsBackend)
+		backendWeights[cocoapodsBackend] = 2.0 // TODO: adjust as needed
+	}
+
+	if enabled, _ := obj.Backends["reuse"]; enabled {
+		reuseBackend := &backend.Reuse{
+			Debug: obj.Debug,
+			Logf: func(format string, v ...interface{}) {
+				obj.Logf("backend: "+format, v...)
+			},
+		}
+		backends = append(backends, reuseBackend)
+		backendWeights[reuseBackend] = 2.0 // TODO: adjust as needed
+	}
+
 	if enabled, _ := obj.Backends["spdx"]; enabled {
 		spdxBackend := &backend.Spdx{
 			Debug: obj.Debug,
@@ -267,53 +439,34 @@ func (obj *Main) Run(ctx context.Context) (*Output, error) {
 	//}
 
 	// load the profiles earlier than needed to catch json typos and commas
-	profilesData := make(map[string]*ProfileData)
-	profilesData[DefaultProfileName] = nil // add a "default" profile for fun
-	// TODO: implement proper XDG and maybe path precedence?
-	for _, x := range obj.Profiles {
-		var err error
-		data := []byte{}
-		if home != "" {
-			p := fmt.Sprintf("%s.json", x) // TODO: validate input string?
-			profilePath := filepath.Join(home, ".config/", obj.Program+"/profiles/", p)
-			profilePath = filepath.Clean(profilePath)
-			data, err = os.ReadFile(profilePath)
-			// check errors below...
-		}
-		if os.IsNotExist(err) || home == "" {
-			data, err = os.ReadFile(x)
+	profiles, profilesData := LoadProfiles(obj.Program, obj.Profiles, obj.Logf)
+
+	manifests := make(map[interfaces.Iterator]*Manifest)
+	manifestPaths := make(map[*Manifest]string)
+	if obj.Incremental {
+		extra := []string{}
+		// a change to the rules changes the results, and if we can't
+		// read them, then the backend will error during setup anyways
+		if b, err := os.ReadFile(regexpPath); regexpPath != "" && err == nil {
+			extra = append(extra, fmt.Sprintf("%x", sha256.Sum256(b)))
 		}
+		key := ManifestKey(obj.Version, obj.Backends, extra...)
 
-		if err != nil {
-			obj.Logf("profile %s: %s", x, err)
-			err = nil // reset
-			continue
-		}
-
-		buffer := bytes.NewBuffer(data)
-		if buffer.Len() == 0 {
-			// TODO: should this be an error, or just a silent ignore?
-			obj.Logf("profile %s: empty input file", x)
-			continue
-		}
-		decoder := json.NewDecoder(buffer)
-
-		var profileConfig ProfileConfig // this gets populated during decode
-		if err := decoder.Decode(&profileConfig); err != nil {
-			// TODO: should this be an error, or just a silent ignore?
-			obj.Logf("profile %s: error decoding json output: %+v", err)
-			continue
-		}
-
-		list, err := licenses.StringsToLicenses(profileConfig.Licenses)
-		if err != nil {
-			obj.Logf("profile %s: error parsing license: %+v", err)
-			continue
-		}
-
-		profilesData[x] = &ProfileData{
-			Licenses: list,
-			Exclude:  profileConfig.Exclude,
+		for _, x := range iterators {
+			it, ok := x.(*iterator.Fs)
+			if !ok || !it.Path.IsDir() { // only local directories
+				continue
+			}
+			root := it.Path.String()
+			p := ManifestPath(safePrefixAbsDir, root)
+			manifest, err := LoadManifest(p, root, key)
+			if err != nil {
+				obj.Logf("could not load manifest, scanning everything: %+v", err)
+				manifest = NewManifest(root, key)
+			}
+			obj.Logf("loaded manifest of %d files for: %s", len(manifest.Files), root)
+			manifests[it] = manifest
+			manifestPaths[manifest] = p
 		}
 	}
 
@@ -326,6 +479,7 @@ func (obj *Main) Run(ctx context.Context) (*Output, error) {
 		Iterators: iterators, // TODO: should this be passed into Run instead?
 		// XXX: deprecate this because we have IteratorError now...
 		ShutdownOnError: false, // set to true for "perfect" scanning.
+		Manifests:       manifests,
 	}
 
 	if err := core.Init(ctx); err != nil {
@@ -337,17 +491,11 @@ func (obj *Main) Run(ctx context.Context) (*Output, error) {
 		return nil, errwrap.Wrapf(err, "core run failed")
 	}
 
-	// remove all the invalid/missing profiles, keep in the original order
-	profiles := []string{}
-	for _, x := range obj.Profiles {
-		if _, exists := profilesData[x]; exists {
-			profiles = append(profiles, x)
+	for manifest, p := range manifestPaths {
+		if err := manifest.Save(p); err != nil {
+			obj.Logf("could not save manifest: %+v", err)
 		}
 	}
-	if len(profiles) == 0 {
-		// add a default profile
-		profiles = append(profiles, DefaultProfileName)
-	}
 
 	return &Output{
 		Program:        obj.Program,
@@ -378,14 +526,23 @@ type Output struct {
 	Profiles       []string
 	ProfilesData   map[string]*ProfileData
 	BackendWeights map[interfaces.Backend]float64
+
+	// Tree specifies that the console and text reports display the results
+	// as a directory tree instead of a flat list of paths, and that the html
+	// report opens in its directory tree mode.
+	Tree bool
 }
 
 // ReturnOutputConsole returns a string of output, formatted for the console.
 func ReturnOutputConsole(output *Output) (string, error) {
 	s := ""
 	summary := true // TODO: perhaps configure this somewhere or as a flag?
+	display := SimpleProfiles
+	if output.Tree {
+		display = TreeProfiles
+	}
 	for _, x := range output.Profiles {
-		pro, err := SimpleProfiles(output.Results, output.Passes, output.Warnings, output.ProfilesData[x], summary, output.BackendWeights, "ansi")
+		pro, err := display(output.Results, output.Passes, output.Warnings, output.ProfilesData[x], summary, output.BackendWeights, "ansi")
 		if err != nil {
 			return "", err
 		}
@@ -400,8 +557,12 @@ func ReturnOutputConsole(output *Output) (string, error) {
 func ReturnOutputFile(output *Output) (string, error) {
 	s := ""
 	summary := true // TODO: perhaps configure this somewhere or as a flag?
+	display := SimpleProfiles
+	if output.Tree {
+		display = TreeProfiles
+	}
 	for _, x := range output.Profiles {
-		pro, err := SimpleProfiles(output.Results, output.Passes, output.Warnings, output.ProfilesData[x], summary, output.BackendWeights, "text")
+		pro, err := display(output.Results, output.Passes, output.Warnings, output.ProfilesData[x], summary, output.BackendWeights, "text")
 		if err != nil {
 			return "", err
 		}
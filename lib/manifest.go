@@ -0,0 +1,229 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// ManifestSchemaVersion is the version of the Manifest structure below.
+	// It must be incremented whenever an incompatible change is made to it,
+	// so that we don't reuse stored results incorrectly.
+	ManifestSchemaVersion = 1
+
+	// ManifestDir is the child directory of the cache prefix where the
+	// manifests are stored.
+	ManifestDir = "manifests/"
+)
+
+// Manifest stores the results of the previous scan of a local directory, so
+// that the next scan of it only needs to run the backends on the files which
+// changed. A file is unchanged if it has the same size and modification time,
+// or if it has the same content checksum. Directories are always scanned again,
+// since their results can depend on the contents of any of their files, and so
+// are the dependent backends, whose results aren't stored. It is safe for
+// concurrent use.
+type Manifest struct {
+	// Schema is the ManifestSchemaVersion that this was written with.
+	Schema int `json:"schema"`
+
+	// Key identifies the program version and the backend configuration
+	// that these results came from. If it changes, then they're discarded.
+	Key string `json:"key"`
+
+	// Root is the path of the directory that was scanned.
+	Root string `json:"root"`
+
+	// Files is the stored state of each file, keyed by uid.
+	Files map[string]*ManifestFile `json:"files"`
+
+	mu *sync.Mutex
+
+	// seen is the set of uids that were looked up successfully or stored
+	// during this run. Only these are saved, so that deleted files and
+	// files with errors are pruned.
+	seen map[string]struct{}
+}
+
+// ManifestFile is the stored state of a single file.
+type ManifestFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	SHA256  string    `json:"sha256"`
+
+	// Results is the result of each backend, keyed by backend name. It is
+	// empty if no backend found anything.
+	Results map[string]*ScanFileResult `json:"results"`
+}
+
+// NewManifest returns a new empty manifest for this root and key.
+func NewManifest(root, key string) *Manifest {
+	return &Manifest{
+		Schema: ManifestSchemaVersion,
+		Key:    key,
+		Root:   root,
+		Files:  make(map[string]*ManifestFile),
+		mu:     &sync.Mutex{},
+		seen:   make(map[string]struct{}),
+	}
+}
+
+// ManifestPath returns the path where the manifest for this root is stored.
+func ManifestPath(prefix safepath.AbsDir, root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(prefix.Path(), ManifestDir, fmt.Sprintf("%x.json", sum))
+}
+
+// ManifestKey returns the key that identifies the program version and backend
+// configuration. Any extra strings, such as the checksum of a rules file that a
+// backend uses, are included as well.
+func ManifestKey(version string, backends map[string]bool, extra ...string) string {
+	names := []string{}
+	for name, enabled := range backends {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	xs := append([]string{version, strings.Join(names, ",")}, extra...)
+	return strings.Join(xs, "\n")
+}
+
+// LoadManifest loads the manifest at this path. If it doesn't exist, or if it
+// was stored for a different root, key or schema, then a new empty manifest is
+// returned instead.
+func LoadManifest(path, root, key string) (*Manifest, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewManifest(root, key), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	manifest := NewManifest(root, key)
+	loaded := &Manifest{}
+	if err := json.Unmarshal(b, loaded); err != nil {
+		return nil, errwrap.Wrapf(err, "could not decode manifest")
+	}
+	if loaded.Schema != ManifestSchemaVersion || loaded.Root != root || loaded.Key != key {
+		return manifest, nil // start again
+	}
+	for uid, file := range loaded.Files {
+		manifest.Files[uid] = file
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest to this path. Only the files which were seen during
+// this run are included.
+func (obj *Manifest) Save(path string) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	manifest := &Manifest{
+		Schema: obj.Schema,
+		Key:    obj.Key,
+		Root:   obj.Root,
+		Files:  make(map[string]*ManifestFile),
+	}
+	for uid := range obj.seen {
+		if file, exists := obj.Files[uid]; exists {
+			manifest.Files[uid] = file
+		}
+	}
+
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), interfaces.Umask); err != nil {
+		return err
+	}
+	// TODO: is this the umask we should use?
+	return os.WriteFile(path, append(b, '\n'), 0660)
+}
+
+// Lookup returns the stored results of a file if it hasn't changed since they
+// were stored, and false otherwise. If the checksum is empty, then only the
+// size and modification time are compared, otherwise the checksum is. This
+// lets the caller avoid reading the file at all in the common case.
+func (obj *Manifest) Lookup(uid string, fileInfo os.FileInfo, sum256 string) (map[string]*ScanFileResult, bool) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	file, exists := obj.Files[uid]
+	if !exists {
+		return nil, false
+	}
+	if sum256 == "" {
+		if file.Size != fileInfo.Size() || !file.ModTime.Equal(fileInfo.ModTime()) {
+			return nil, false
+		}
+	} else {
+		if file.SHA256 != sum256 {
+			return nil, false
+		}
+		// it was only touched, so make the next lookup cheaper
+		file.Size = fileInfo.Size()
+		file.ModTime = fileInfo.ModTime()
+	}
+
+	obj.seen[uid] = struct{}{}
+	return file.Results, true
+}
+
+// Store saves the results of a file that was just scanned. The results map may
+// be empty if no backend found anything.
+func (obj *Manifest) Store(uid string, fileInfo os.FileInfo, sum256 string, results map[interfaces.Backend]*interfaces.Result) {
+	builder := &scanFileBuilder{
+		ids: make(map[interfaces.Iterator]int), // the iterator isn't stored
+	}
+	file := &ManifestFile{
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime(),
+		SHA256:  sum256,
+		Results: make(map[string]*ScanFileResult),
+	}
+	for backend, result := range results {
+		file.Results[backend.String()] = builder.result(result)
+	}
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.Files[uid] = file
+	obj.seen[uid] = struct{}{}
+}
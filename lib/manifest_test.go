@@ -0,0 +1,220 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+// testDataBackend finds the MIT license in every file, and counts its scans.
+type testDataBackend struct {
+	scans int
+}
+
+func (obj *testDataBackend) String() string { return "data" }
+
+func (obj *testDataBackend) ScanData(ctx context.Context, data []byte, info *interfaces.Info) (*interfaces.Result, error) {
+	obj.scans++
+	return &interfaces.Result{Licenses: []*licenses.License{{SPDX: "MIT"}}, Confidence: 1.0}, nil
+}
+
+// testDependentBackend finds the license that is named in the "license" file
+// next to each file, like the dep5 backend does with the copyright file.
+type testDependentBackend struct{}
+
+func (obj *testDependentBackend) String() string { return "dependent" }
+
+func (obj *testDependentBackend) Dependent() bool { return true }
+
+func (obj *testDependentBackend) ScanPath(ctx context.Context, path safepath.Path, info *interfaces.Info) (*interfaces.Result, error) {
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(path.Path()), "license"))
+	if err != nil {
+		return nil, err
+	}
+	return &interfaces.Result{Licenses: []*licenses.License{{SPDX: strings.TrimSpace(string(data))}}, Confidence: 1.0}, nil
+}
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.c")
+	if err := os.WriteFile(p, []byte("hello\n"), 0600); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	fileInfo, err := os.Stat(p)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+
+	b1 := &testBackend{name: "b1"}
+	key := lib.ManifestKey("test", map[string]bool{"b1": true, "b2": false})
+	manifest := lib.NewManifest(dir, key)
+	manifest.Store("file://"+p, fileInfo, "aaaa", map[interfaces.Backend]*interfaces.Result{
+		b1: {
+			Licenses:   []*licenses.License{{SPDX: "MIT"}},
+			Confidence: 1.0,
+			Meta:       &interfaces.Meta{Backend: b1, SHA256: "aaaa"},
+		},
+	})
+	manifest.Store("file:///deleted", fileInfo, "bbbb", nil)
+
+	mp := filepath.Join(dir, "manifests", "test.json")
+	if err := manifest.Save(mp); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+
+	// a different key starts again
+	if m, err := lib.LoadManifest(mp, dir, key+"x"); err != nil || len(m.Files) != 0 {
+		t.Errorf("expected an empty manifest for a different key, got: %+v, %+v", m, err)
+	}
+
+	loaded, err := lib.LoadManifest(mp, dir, key)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if len(loaded.Files) != 2 {
+		t.Errorf("expected 2 files, got: %d", len(loaded.Files))
+		return
+	}
+
+	results, ok := loaded.Lookup("file://"+p, fileInfo, "") // stat only
+	if !ok || len(results) != 1 || results["b1"] == nil || results["b1"].Licenses[0].SPDX != "MIT" {
+		t.Errorf("unexpected lookup: %+v, %t", results, ok)
+	}
+
+	// a touched file only matches by checksum
+	if err := os.Chtimes(p, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	touched, err := os.Stat(p)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if _, ok := loaded.Lookup("file://"+p, touched, ""); ok {
+		t.Errorf("expected a touched file to not match by stat")
+	}
+	if _, ok := loaded.Lookup("file://"+p, touched, "cccc"); ok {
+		t.Errorf("expected a changed file to not match")
+	}
+	if _, ok := loaded.Lookup("file://"+p, touched, "aaaa"); !ok {
+		t.Errorf("expected a touched file to match by checksum")
+	}
+
+	// only the files which were seen are saved again
+	if err := loaded.Save(mp); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	saved, err := lib.LoadManifest(mp, dir, key)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if len(saved.Files) != 1 {
+		t.Errorf("expected the unseen file to be pruned, got: %d files", len(saved.Files))
+	}
+	if _, ok := saved.Lookup("file://"+p, touched, ""); !ok {
+		t.Errorf("expected the touched file to match by stat after it was updated")
+	}
+}
+
+func TestScannerManifest(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.c")
+	if err := os.WriteFile(p, []byte("hello\n"), 0600); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	fileInfo, err := os.Stat(p)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	info := &interfaces.Info{
+		FileInfo: fileInfo,
+		UID:      "file://" + p,
+		Root:     safepath.UnsafeParseIntoAbsDir(dir),
+	}
+
+	data := &testDataBackend{}
+	dependent := &testDependentBackend{}
+	manifest := lib.NewManifest(dir, "test")
+	scan := func(license string) string {
+		if err := os.WriteFile(filepath.Join(dir, "license"), []byte(license), 0600); err != nil {
+			t.Fatalf("err: %+v", err)
+		}
+		scanner := &lib.Scanner{
+			Logf:     func(format string, v ...interface{}) { t.Logf("scanner: "+format, v...) },
+			Backends: []interfaces.Backend{data, dependent},
+			Manifest: manifest,
+		}
+		if err := scanner.Init(); err != nil {
+			t.Fatalf("err: %+v", err)
+		}
+		if err := scanner.Scan(context.Background(), safepath.UnsafeParseIntoAbsFile(p), info); err != nil {
+			t.Fatalf("err: %+v", err)
+		}
+		results, err := scanner.Result()
+		if err != nil {
+			t.Fatalf("err: %+v", err)
+		}
+		if r := results[info.UID][data]; r == nil || licenses.Join(r.Licenses) != "MIT" {
+			t.Errorf("unexpected data result: %+v", r)
+		}
+		r := results[info.UID][dependent]
+		if r == nil {
+			return ""
+		}
+		return licenses.Join(r.Licenses)
+	}
+
+	if s := scan("ISC"); s != "ISC" {
+		t.Errorf("expected ISC, got: %s", s)
+	}
+	// the file didn't change, but the file that its license depends on did
+	if s := scan("Apache-2.0"); s != "Apache-2.0" {
+		t.Errorf("expected the dependent backend to run again, got: %s", s)
+	}
+	if data.scans != 1 {
+		t.Errorf("expected the unchanged file to be restored, got: %d scans", data.scans)
+	}
+	if results, ok := manifest.Lookup(info.UID, fileInfo, ""); !ok || results["dependent"] != nil {
+		t.Errorf("expected only the data result to be stored, got: %+v", results)
+	}
+}
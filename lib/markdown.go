@@ -0,0 +1,193 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReturnOutputMarkdown returns a string of output, formatted as compact
+// markdown that is suitable for posting as a pull request comment. It starts
+// with a summary table of the profiles, and then for each profile it has a
+// collapsible section for each directory with a table of the flagged files.
+func ReturnOutputMarkdown(output *Output) (string, error) {
+	report, err := BuildReport(output)
+	if err != nil {
+		return "", err
+	}
+
+	s := fmt.Sprintf("## %s report\n\n", output.Program)
+
+	s += "| profile | severity | flagged files | licenses |\n"
+	s += "| --- | --- | ---: | --- |\n"
+	flagged := make(map[string][]*ReportFile)
+	for _, profile := range report.Profiles {
+		files := []*ReportFile{}
+		found := make(map[string]int64)
+		for _, file := range report.Files {
+			if !reportFileFlagged(file, profile.Name) {
+				continue
+			}
+			files = append(files, file)
+			for _, x := range file.Licenses {
+				found[x]++
+			}
+		}
+		flagged[profile.Name] = files
+
+		s += fmt.Sprintf("| %s | %s | %d | %s |\n",
+			markdownEscape(profile.Name),
+			ProfileSeverity(output.ProfilesData[profile.Name]),
+			len(files),
+			markdownEscape(markdownCounts(found)),
+		)
+	}
+	s += "\n"
+	s += fmt.Sprintf("Scanned %d files with results, %d were skipped, ", report.Summary.Files, report.Summary.Skipped)
+	s += fmt.Sprintf("with %d errors and %d warnings.\n", report.Summary.Errors, report.Summary.Warnings)
+
+	for _, profile := range report.Profiles {
+		files := flagged[profile.Name]
+		if len(files) == 0 {
+			continue
+		}
+		s += fmt.Sprintf("\n### profile %s\n", markdownEscape(profile.Name))
+
+		dirs := []string{}
+		byDir := make(map[string][]*ReportFile)
+		for _, file := range files {
+			dir, _ := markdownSplit(file.UID)
+			if _, exists := byDir[dir]; !exists {
+				dirs = append(dirs, dir)
+			}
+			byDir[dir] = append(byDir[dir], file)
+		}
+		sort.Strings(dirs)
+
+		for _, dir := range dirs {
+			s += "\n<details>\n"
+			s += fmt.Sprintf("<summary><code>%s</code> (%d files)</summary>\n\n", markdownEscapeHTML(dir), len(byDir[dir]))
+			s += "| file | licenses | confidence | backends |\n"
+			s += "| --- | --- | ---: | --- |\n"
+			for _, file := range byDir[dir] {
+				_, name := markdownSplit(file.UID)
+				backends := []string{}
+				for _, r := range file.Results {
+					backends = append(backends, r.Backend)
+				}
+				s += fmt.Sprintf("| [%s](%s) | %s | %.2f%% | %s |\n",
+					markdownEscape(name),
+					markdownEscapeURL(file.SmartURI),
+					markdownEscape(strings.Join(file.Licenses, ", ")),
+					file.Confidence*100.0,
+					markdownEscape(strings.Join(backends, ", ")),
+				)
+			}
+			s += "\n</details>\n"
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		s += "\n<details>\n"
+		s += fmt.Sprintf("<summary>errors (%d)</summary>\n\n", len(report.Errors))
+		s += "| file | backend | error |\n"
+		s += "| --- | --- | --- |\n"
+		for _, x := range report.Errors {
+			s += fmt.Sprintf("| %s | %s | %s |\n", markdownEscape(x.UID), markdownEscape(x.Backend), markdownEscape(x.Error))
+		}
+		s += "\n</details>\n"
+	}
+
+	return s, nil
+}
+
+// markdownSplit splits a uid into the directory and the file name. Any query
+// string, such as the git hash, is kept on the directory.
+func markdownSplit(uid string) (string, string) {
+	query := ""
+	if ix := strings.Index(uid, "?"); ix > -1 {
+		query = uid[ix:]
+		uid = uid[:ix]
+	}
+	ix := strings.LastIndex(strings.TrimSuffix(uid, "/"), "/")
+	if ix == -1 {
+		return query, uid
+	}
+	return uid[:ix+1] + query, uid[ix+1:]
+}
+
+// markdownCounts returns a compact string of license counts, sorted by name.
+func markdownCounts(m map[string]int64) string {
+	names := []string{}
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	xs := []string{}
+	for _, k := range names {
+		xs = append(xs, fmt.Sprintf("%s (%d)", k, m[k]))
+	}
+	return strings.Join(xs, ", ")
+}
+
+// markdownEscape escapes the characters that would break a markdown table cell
+// or be interpreted as formatting.
+func markdownEscape(s string) string {
+	s = markdownEscapeHTML(s)
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"|", `\|`,
+		"*", `\*`,
+		"_", `\_`,
+		"`", "\\`",
+		"[", `\[`,
+		"]", `\]`,
+		"\n", " ",
+	).Replace(s)
+}
+
+// markdownEscapeURL escapes the characters that would end the destination of a
+// markdown link early or break the table cell that it's in.
+func markdownEscapeURL(s string) string {
+	return strings.NewReplacer(
+		" ", "%20",
+		"(", "%28",
+		")", "%29",
+		"<", "%3C",
+		">", "%3E",
+		"|", "%7C",
+		"\n", "%0A",
+	).Replace(s)
+}
+
+// markdownEscapeHTML escapes the characters that would be interpreted as html.
+func markdownEscapeHTML(s string) string {
+	return strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	).Replace(s)
+}
@@ -0,0 +1,60 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+func TestReturnOutputMarkdown(t *testing.T) {
+	output := testOutput()
+	b1 := &testBackend{name: "b1"}
+	output.BackendWeights[b1] = 1.0
+	output.Results["file:///tmp/d/x (copy)|1.c"] = map[interfaces.Backend]*interfaces.Result{
+		b1: {Licenses: []*licenses.License{{SPDX: "GPL-2.0-only"}}, Confidence: 1.0},
+	}
+
+	s, err := lib.ReturnOutputMarkdown(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	for _, exp := range []string{
+		"| default | note | 3 | GPL-2.0-only (2), MIT (1) |\n",
+		"| gpl | warning | 2 | GPL-2.0-only (2) |\n",
+		"### profile gpl\n",
+		"<summary><code>file:///tmp/d/</code> (1 files)</summary>",
+		"| [x (copy)\\|1.c](file:///tmp/d/x%20%28copy%29%7C1.c) | GPL-2.0-only | 100.00% | b1 |\n",
+		"<summary>errors (1)</summary>",
+	} {
+		if !strings.Contains(s, exp) {
+			t.Errorf("expected %q in: %s", exp, s)
+		}
+	}
+}
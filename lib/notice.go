@@ -0,0 +1,513 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// NoticeSourceSPDX is the source of license texts that come from the
+	// embedded SPDX license list.
+	NoticeSourceSPDX = "SPDX License List"
+
+	// noticeRule is the separator between sections of the text notice.
+	noticeRule = "========================================================================"
+
+	// noticeSubRule is the separator between license texts in the text
+	// notice.
+	noticeSubRule = "------------------------------------------------------------------------"
+)
+
+// NoticeManifestFiles are the names of package manifest files. A directory that
+// contains one of these is considered to be a separate component in a notice.
+var NoticeManifestFiles = []string{
+	"pom.xml",        // maven
+	"DESCRIPTION",    // cran
+	"package.json",   // npm
+	"pyproject.toml", // python
+	"setup.cfg",      // python
+	"PKG-INFO",       // python
+	"Cargo.toml",     // cargo
+	"go.mod",         // gomod
+	"composer.json",  // composer
+}
+
+// NoticeManifestExts are the file extensions of package manifest files.
+var NoticeManifestExts = []string{
+	".bb",           // bitbake
+	".spec",         // rpm
+	".gemspec",      // gem
+	".nuspec",       // nuget
+	".podspec",      // cocoapods
+	".podspec.json", // cocoapods
+}
+
+// Notice is a third-party attribution notice. It lists every component that was
+// found along with its licenses and copyright statements. The full text of each
+// license is only included once, and each component refers to the texts that
+// apply to it.
+type Notice struct {
+	Program string
+	Version string
+
+	// Components is the list of components that had any licenses or
+	// copyright statements.
+	Components []*NoticeComponent
+
+	// Texts is the deduplicated list of license texts.
+	Texts []*NoticeText
+}
+
+// NoticeComponent is a single third-party component, such as a git repository,
+// an archive, or a directory with a package manifest.
+type NoticeComponent struct {
+	Name    string
+	Version string
+	URL     string
+
+	// Comment is a human readable description of where this came from.
+	Comment string
+
+	// Licenses is the sorted list of every license found in this component.
+	Licenses []string
+
+	// Copyrights is the sorted list of every copyright statement found in
+	// this component.
+	Copyrights []string
+
+	// Texts is the list of license text ids that apply to this component.
+	Texts []int
+}
+
+// NoticeText is the full text of one or more licenses.
+type NoticeText struct {
+	// ID is the number used to refer to this text. They start at one.
+	ID int
+
+	// Licenses is the sorted list of licenses that this text is for.
+	Licenses []string
+
+	// Source is where the text came from. It is either the path of the
+	// license file that it was found in, or NoticeSourceSPDX.
+	Source string
+
+	Text string
+}
+
+// ReturnOutputNotice returns a string of output, formatted as a plain text
+// NOTICE file.
+func ReturnOutputNotice(output *Output) (string, error) {
+	notice, err := BuildNotice(output)
+	if err != nil {
+		return "", err
+	}
+
+	s := "THIRD-PARTY SOFTWARE NOTICES AND INFORMATION\n\n"
+	s += fmt.Sprintf("This file lists the components that were found by %s %s, along\n", notice.Program, notice.Version)
+	s += "with their licenses and copyright statements. The full license texts\n"
+	s += "are at the end.\n"
+
+	for _, c := range notice.Components {
+		s += "\n" + noticeRule + "\n"
+		s += c.Name
+		if c.Version != "" {
+			s += " @ " + c.Version
+		}
+		s += "\n"
+		if c.URL != "" && c.URL != c.Name {
+			s += c.URL + "\n"
+		}
+		s += c.Comment + "\n\n"
+
+		if len(c.Licenses) > 0 {
+			s += fmt.Sprintf("Licenses: %s\n", strings.Join(c.Licenses, ", "))
+		}
+		for _, x := range c.Copyrights {
+			s += x + "\n"
+		}
+		if len(c.Texts) > 0 {
+			refs := []string{}
+			for _, id := range c.Texts {
+				text := notice.Texts[id-1]
+				refs = append(refs, fmt.Sprintf("[%d] %s", id, strings.Join(text.Licenses, ", ")))
+			}
+			s += fmt.Sprintf("License texts: %s\n", strings.Join(refs, "; "))
+		}
+	}
+
+	if len(notice.Texts) > 0 {
+		s += "\n" + noticeRule + "\n"
+		s += "LICENSE TEXTS\n"
+	}
+	for _, x := range notice.Texts {
+		s += noticeSubRule + "\n"
+		s += fmt.Sprintf("[%d] %s (from %s)\n\n", x.ID, strings.Join(x.Licenses, ", "), x.Source)
+		s += strings.TrimSpace(x.Text) + "\n"
+	}
+
+	return s, nil
+}
+
+// ReturnOutputNoticeHtml returns a string of output, formatted as a standalone
+// html NOTICE page.
+func ReturnOutputNoticeHtml(output *Output) (string, error) {
+	notice, err := BuildNotice(output)
+	if err != nil {
+		return "", err
+	}
+	e := html.EscapeString
+
+	s := "<!DOCTYPE html>\n"
+	s += "<html>\n<head>\n"
+	s += `<meta charset="utf-8">` + "\n"
+	s += "<title>Third-party software notices and information</title>\n"
+	s += "<style>body { font-family: sans-serif; } pre { white-space: pre-wrap; }</style>\n"
+	s += "</head>\n<body>\n"
+	s += "<h1>Third-party software notices and information</h1>\n"
+	s += fmt.Sprintf("<p>This page lists the components that were found by %s %s, along with their licenses and copyright statements.</p>\n", e(notice.Program), e(notice.Version))
+
+	for _, c := range notice.Components {
+		name := e(c.Name)
+		if c.URL != "" {
+			name = fmt.Sprintf(`<a href="%s">%s</a>`, e(c.URL), name)
+		}
+		if c.Version != "" {
+			name += fmt.Sprintf(" @ <code>%s</code>", e(c.Version))
+		}
+		s += fmt.Sprintf("<h2>%s</h2>\n", name)
+		s += fmt.Sprintf("<p><i>%s</i></p>\n", e(c.Comment))
+
+		if len(c.Licenses) > 0 {
+			s += fmt.Sprintf("<p>Licenses: %s</p>\n", e(strings.Join(c.Licenses, ", ")))
+		}
+		if len(c.Copyrights) > 0 {
+			s += "<ul>\n"
+			for _, x := range c.Copyrights {
+				s += fmt.Sprintf("<li>%s</li>\n", e(x))
+			}
+			s += "</ul>\n"
+		}
+		if len(c.Texts) > 0 {
+			refs := []string{}
+			for _, id := range c.Texts {
+				text := notice.Texts[id-1]
+				refs = append(refs, fmt.Sprintf(`<a href="#text-%d">[%d] %s</a>`, id, id, e(strings.Join(text.Licenses, ", "))))
+			}
+			s += fmt.Sprintf("<p>License texts: %s</p>\n", strings.Join(refs, "; "))
+		}
+	}
+
+	if len(notice.Texts) > 0 {
+		s += "<h2>License texts</h2>\n"
+	}
+	for _, x := range notice.Texts {
+		s += fmt.Sprintf(`<h3 id="text-%d">[%d] %s</h3>`+"\n", x.ID, x.ID, e(strings.Join(x.Licenses, ", ")))
+		s += fmt.Sprintf("<p><i>from %s</i></p>\n", e(x.Source))
+		s += fmt.Sprintf("<pre>%s</pre>\n", e(strings.TrimSpace(x.Text)))
+	}
+
+	s += "</body>\n</html>\n"
+	return s, nil
+}
+
+// BuildNotice builds the attribution notice from an Output. Each scanned input,
+// such as a git repository, a downloaded url, or an archive, is a component. A
+// directory inside of one of these that has a package manifest file is also a
+// separate component, which contains the files below it. The license texts come
+// from any license files that were found in the component, such as LICENSE or
+// COPYING, and the embedded SPDX license list is used for any other licenses.
+// Components that have no licenses or copyright statements are omitted.
+func BuildNotice(output *Output) (*Notice, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	notice := &Notice{
+		Program:    output.Program,
+		Version:    output.Version,
+		Components: []*NoticeComponent{},
+		Texts:      []*NoticeText{},
+	}
+	texts := make(map[string]*NoticeText) // keyed by the trimmed text
+
+	addText := func(text, source string, ls []string) int {
+		key := strings.TrimSpace(text)
+		x, exists := texts[key]
+		if !exists {
+			x = &NoticeText{
+				ID:       len(notice.Texts) + 1,
+				Licenses: []string{},
+				Source:   source,
+				Text:     text,
+			}
+			texts[key] = x
+			notice.Texts = append(notice.Texts, x)
+		}
+		for _, l := range ls {
+			if !util.StrInList(l, x.Licenses) {
+				x.Licenses = append(x.Licenses, l)
+			}
+		}
+		sort.Strings(x.Licenses)
+		return x.ID
+	}
+
+	for _, p := range buildProvenance(output) {
+		for _, group := range noticeGroups(p) {
+			c := &NoticeComponent{
+				Name:       p.name,
+				Version:    p.version,
+				URL:        p.url,
+				Comment:    p.comment,
+				Licenses:   []string{},
+				Copyrights: []string{},
+				Texts:      []int{},
+			}
+			if group.manifest != nil {
+				c.Name = provenancePath(p, group.manifest)
+				c.Comment = fmt.Sprintf("package manifest in %s", p.comment)
+			}
+
+			found := []*licenses.License{}
+			if group.component != nil {
+				c.Name, c.Version = group.component.component, ""
+				if ix := strings.LastIndex(c.Name, "@"); ix > 0 {
+					c.Name, c.Version = c.Name[:ix], c.Name[ix+1:]
+				}
+				c.URL = ""
+				c.Comment = fmt.Sprintf("dependency in %s", provenancePath(p, group.component))
+				found = append(found, profileLicenses(nil, group.component.results)...)
+			}
+			covered := []*licenses.License{} // licenses with a text file
+			for _, f := range group.files {
+				ls := profileLicenses(nil, f.results)
+				for _, x := range ls {
+					if !licenses.InList(x, found) {
+						found = append(found, x)
+					}
+				}
+
+				statements, text := noticeMeta(f.results)
+				for _, x := range statements {
+					if !util.StrInList(x, c.Copyrights) {
+						c.Copyrights = append(c.Copyrights, x)
+					}
+				}
+				if text == "" || len(ls) == 0 {
+					continue
+				}
+				names := []string{}
+				for _, x := range ls {
+					names = append(names, x.String())
+					covered = append(covered, x)
+				}
+				id := addText(text, provenancePath(p, f), names)
+				if !noticeIntInList(id, c.Texts) {
+					c.Texts = append(c.Texts, id)
+				}
+			}
+
+			sort.Slice(found, func(i, j int) bool {
+				return found[i].String() < found[j].String()
+			})
+			for _, x := range found {
+				c.Licenses = append(c.Licenses, x.String())
+				// each license of a choice gets its text
+				for _, y := range x.Alternatives() {
+					if licenses.InList(y, covered) || y.SPDX == "" {
+						continue
+					}
+					spdx, err := licenses.ID(y.SPDX)
+					if err != nil {
+						continue // no text available
+					}
+					id := addText(spdx.Text, NoticeSourceSPDX, []string{y.String()})
+					if !noticeIntInList(id, c.Texts) {
+						c.Texts = append(c.Texts, id)
+					}
+				}
+			}
+			sort.Strings(c.Copyrights)
+			sort.Ints(c.Texts)
+
+			if len(c.Licenses) == 0 && len(c.Copyrights) == 0 {
+				continue
+			}
+			notice.Components = append(notice.Components, c)
+		}
+	}
+
+	return notice, nil
+}
+
+// noticeGroup is a list of files that belong to the same component.
+type noticeGroup struct {
+	// manifest is the package manifest file of this group, or nil if this
+	// is the group for the whole provenance.
+	manifest *provenanceFile
+
+	// component is the directory of this group if a backend said that it
+	// is a whole dependency, or nil if none did.
+	component *provenanceFile
+
+	files []*provenanceFile
+}
+
+// noticeGroups splits the files of a provenance by the package manifests that
+// they are found under, and by the directories that a backend said are a whole
+// dependency, such as a vendored go module. Each file belongs to the group of
+// the deepest of these directories that contains it. The group for the files
+// which aren't under any of them comes first, and the others are sorted by
+// path.
+func noticeGroups(p *provenance) []*noticeGroup {
+	dirs := []string{}
+	groups := make(map[string]*noticeGroup) // keyed by directory
+	for _, f := range p.files {
+		if !noticeManifest(path.Base(f.name)) {
+			continue
+		}
+		dir := path.Dir(strings.TrimPrefix(f.name, "./"))
+		if _, exists := groups[dir]; exists {
+			continue // more than one manifest in a directory
+		}
+		dirs = append(dirs, dir)
+		groups[dir] = &noticeGroup{
+			manifest: f,
+			files:    []*provenanceFile{},
+		}
+	}
+	for _, f := range p.dirs {
+		dir := strings.TrimSuffix(strings.TrimPrefix(f.name, "./"), "/")
+		if group, exists := groups[dir]; exists {
+			group.component = f
+			continue
+		}
+		dirs = append(dirs, dir)
+		groups[dir] = &noticeGroup{
+			component: f,
+			files:     []*provenanceFile{},
+		}
+	}
+	// longest first so that we find the deepest match
+	sort.Slice(dirs, func(i, j int) bool {
+		if len(dirs[i]) != len(dirs[j]) {
+			return len(dirs[i]) > len(dirs[j])
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	base := &noticeGroup{
+		files: []*provenanceFile{},
+	}
+	for _, f := range p.files {
+		group := base
+		for _, dir := range dirs {
+			if dir == "." || strings.HasPrefix(strings.TrimPrefix(f.name, "./"), dir+"/") {
+				group = groups[dir]
+				break
+			}
+		}
+		group.files = append(group.files, f)
+	}
+
+	sort.Strings(dirs)
+	result := []*noticeGroup{base}
+	for _, dir := range dirs {
+		result = append(result, groups[dir])
+	}
+	return result
+}
+
+// noticeManifest returns true if this is the name of a package manifest file.
+func noticeManifest(name string) bool {
+	for _, x := range NoticeManifestFiles {
+		if name == x {
+			return true
+		}
+	}
+	for _, x := range NoticeManifestExts {
+		if strings.HasSuffix(name, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// noticeMeta returns the copyright statements and license text of a file. They
+// are the same for every backend, so it uses the first one that has them. The
+// copyright holders that any backend declared are added to the statements.
+func noticeMeta(m map[interfaces.Backend]*interfaces.Result) ([]string, string) {
+	statements := []string{}
+	text := ""
+	holders := []string{}
+	for _, result := range m {
+		for _, x := range result.Holders {
+			if !util.StrInList(x, holders) {
+				holders = append(holders, x)
+			}
+		}
+		if result.Meta == nil {
+			continue
+		}
+		if len(result.Meta.Copyrights) > len(statements) {
+			statements = result.Meta.Copyrights
+		}
+		if result.Meta.LicenseText != "" {
+			text = result.Meta.LicenseText
+		}
+	}
+	if len(holders) == 0 {
+		return statements, text
+	}
+	sort.Strings(holders)                          // deterministic order
+	statements = append([]string{}, statements...) // don't modify the meta
+	for _, x := range holders {
+		if s := strings.ToLower(x); !strings.HasPrefix(s, "copyright") && !strings.HasPrefix(s, "©") {
+			x = "Copyright " + x
+		}
+		if !util.StrInList(x, statements) {
+			statements = append(statements, x)
+		}
+	}
+	return statements, text
+}
+
+// noticeIntInList returns true if the int is in the list.
+func noticeIntInList(needle int, haystack []int) bool {
+	for _, x := range haystack {
+		if x == needle {
+			return true
+		}
+	}
+	return false
+}
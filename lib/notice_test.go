@@ -0,0 +1,177 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestBuildNotice(t *testing.T) {
+	absDir, err := safepath.ParseIntoAbsDir("/tmp/project/")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	fs := &iterator.Fs{
+		Path: absDir,
+	}
+	meta := func(copyrights []string, text string) *interfaces.Meta {
+		return &interfaces.Meta{
+			Iterator:    fs,
+			SHA1:        "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+			Copyrights:  copyrights,
+			LicenseText: text,
+		}
+	}
+
+	b1 := &testBackend{name: "b1"}
+	mit := &licenses.License{SPDX: "MIT"}
+	apache := &licenses.License{SPDX: "Apache-2.0"}
+	output := &lib.Output{
+		Program: "yesiscan",
+		Version: "test",
+		Results: interfaces.ResultSet{
+			"file:///tmp/project/LICENSE": {
+				b1: {Licenses: []*licenses.License{mit}, Confidence: 1.0, Meta: meta([]string{"Copyright (c) 2023 Alice"}, "MIT License\n...\n")},
+			},
+			"file:///tmp/project/main.c": {
+				b1: {Licenses: []*licenses.License{mit}, Confidence: 1.0, Meta: meta([]string{"Copyright (c) 2023 Alice"}, "")},
+			},
+			"file:///tmp/project/vendor/foo/pom.xml": {
+				b1: {Licenses: []*licenses.License{apache}, Confidence: 1.0, Meta: meta(nil, "")},
+			},
+			"file:///tmp/project/vendor/foo/src/foo.c": {
+				b1: {Licenses: []*licenses.License{apache}, Confidence: 1.0, Meta: meta([]string{"Copyright 2020 Foo Corp."}, "")},
+			},
+		},
+		BackendWeights: map[interfaces.Backend]float64{
+			b1: 1.0,
+		},
+	}
+
+	notice, err := lib.BuildNotice(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+
+	if len(notice.Components) != 2 {
+		t.Errorf("expected two components, got: %d", len(notice.Components))
+		return
+	}
+	c1, c2 := notice.Components[0], notice.Components[1]
+	if exp := []string{"MIT"}; !reflect.DeepEqual(c1.Licenses, exp) {
+		t.Errorf("licenses: %v, exp: %v", c1.Licenses, exp)
+	}
+	if exp := []string{"Copyright (c) 2023 Alice"}; !reflect.DeepEqual(c1.Copyrights, exp) {
+		t.Errorf("copyrights: %v, exp: %v", c1.Copyrights, exp)
+	}
+	if exp := "vendor/foo/pom.xml"; c2.Name != exp {
+		t.Errorf("name: %s, exp: %s", c2.Name, exp)
+	}
+	if exp := []string{"Copyright 2020 Foo Corp."}; !reflect.DeepEqual(c2.Copyrights, exp) {
+		t.Errorf("copyrights: %v, exp: %v", c2.Copyrights, exp)
+	}
+
+	if len(notice.Texts) != 2 {
+		t.Errorf("expected two license texts, got: %d", len(notice.Texts))
+		return
+	}
+	if x := notice.Texts[0]; x.Source != "LICENSE" || x.Text != "MIT License\n...\n" {
+		t.Errorf("unexpected text from license file: %+v", x)
+	}
+	if x := notice.Texts[1]; x.Source != lib.NoticeSourceSPDX || x.Text == "" {
+		t.Errorf("unexpected text from spdx: %+v", x)
+	}
+}
+
+func TestBuildNoticeComponents(t *testing.T) {
+	absDir, err := safepath.ParseIntoAbsDir("/tmp/project/")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	fs := &iterator.Fs{
+		Path: absDir,
+	}
+	file := &interfaces.Meta{
+		Iterator: fs,
+		SHA1:     "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+	}
+	dir := &interfaces.Meta{
+		Iterator: fs,
+	}
+
+	b1 := &testBackend{name: "b1"}
+	b2 := &testBackend{name: "b2"}
+	bsd := &licenses.License{SPDX: "BSD-3-Clause"}
+	output := &lib.Output{
+		Program: "yesiscan",
+		Version: "test",
+		Results: interfaces.ResultSet{
+			"file:///tmp/project/main.go": {
+				b1: {Licenses: []*licenses.License{}, Confidence: 1.0, Meta: file},
+			},
+			"file:///tmp/project/vendor/golang.org/x/text/": {
+				b2: {Licenses: []*licenses.License{bsd}, Confidence: 1.0, Component: "golang.org/x/text@v0.3.7", Meta: dir},
+			},
+			"file:///tmp/project/vendor/golang.org/x/text/unicode/tables.go": {
+				b1: {Licenses: []*licenses.License{}, Confidence: 1.0, Meta: &interfaces.Meta{Iterator: fs, SHA1: "da39a3ee5e6b4b0d3255bfef95601890afd80709", Copyrights: []string{"Copyright 2017 The Go Authors."}}},
+				b2: {Licenses: []*licenses.License{bsd}, Confidence: 1.0, Holders: []string{"2017 The Go Authors.", "2020 Someone"}},
+			},
+		},
+		BackendWeights: map[interfaces.Backend]float64{
+			b1: 1.0,
+			b2: 1.0,
+		},
+	}
+
+	notice, err := lib.BuildNotice(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+
+	if len(notice.Components) != 1 {
+		t.Errorf("expected one component, got: %d", len(notice.Components))
+		return
+	}
+	c := notice.Components[0]
+	if c.Name != "golang.org/x/text" || c.Version != "v0.3.7" {
+		t.Errorf("name: %s, version: %s", c.Name, c.Version)
+	}
+	if exp := []string{"BSD-3-Clause"}; !reflect.DeepEqual(c.Licenses, exp) {
+		t.Errorf("licenses: %v, exp: %v", c.Licenses, exp)
+	}
+	if exp := []string{"Copyright 2017 The Go Authors.", "Copyright 2020 Someone"}; !reflect.DeepEqual(c.Copyrights, exp) {
+		t.Errorf("copyrights: %v, exp: %v", c.Copyrights, exp)
+	}
+}
@@ -24,7 +24,11 @@
 package lib
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -42,8 +46,26 @@ const (
 	// DefaultProfileName is the name given to the built-in "include all"
 	// profile.
 	DefaultProfileName = "default"
+
+	// SeverityError is the severity for findings that must be fixed.
+	SeverityError = "error"
+
+	// SeverityWarning is the severity for findings that should be looked
+	// at. It is the default severity of a profile.
+	SeverityWarning = "warning"
+
+	// SeverityNote is the severity for findings that are informational.
+	// It is the severity of the built-in default profile.
+	SeverityNote = "note"
 )
 
+// Severities is the list of valid profile severities in decreasing order.
+var Severities = []string{
+	SeverityError,
+	SeverityWarning,
+	SeverityNote,
+}
+
 // ProfileConfig is the datastructure representing the profile config that is
 // used for the .json files on disk.
 type ProfileConfig struct {
@@ -54,6 +76,10 @@ type ProfileConfig struct {
 	// Exclude these licenses from match instead of including by default.
 	Exclude bool `json:"exclude"`
 
+	// Severity is how important a match of this profile is. It must be one
+	// of the Severities, and it defaults to SeverityWarning if empty.
+	Severity string `json:"severity"`
+
 	// Comment adds a user friendly comment for this file.
 	Comment string `json:"comment"`
 }
@@ -66,6 +92,108 @@ type ProfileData struct {
 
 	// Exclude these licenses from match instead of including by default.
 	Exclude bool
+
+	// Severity is how important a match of this profile is.
+	Severity string
+}
+
+// ProfileSeverity returns the severity of a profile. The nil profile is the
+// default profile, which is only informational.
+func ProfileSeverity(profile *ProfileData) string {
+	if profile == nil {
+		return SeverityNote
+	}
+	if profile.Severity == "" {
+		return SeverityWarning
+	}
+	return profile.Severity
+}
+
+// LoadProfiles loads the named profiles. Each is either the name of a file in
+// ~/.config/<program>/profiles/<name>.json or a full path. It returns the list
+// of profile names that loaded successfully in the original order, and the map
+// of parsed profile data, which also contains the built-in default profile. If
+// no profiles loaded, then the list contains the default profile. Any errors
+// are logged and that profile is skipped.
+func LoadProfiles(program string, names []string, logf func(format string, v ...interface{})) ([]string, map[string]*ProfileData) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		logf("error finding home directory: %+v", err)
+	}
+
+	profilesData := make(map[string]*ProfileData)
+	profilesData[DefaultProfileName] = nil // add a "default" profile for fun
+	// TODO: implement proper XDG and maybe path precedence?
+	for _, x := range names {
+		var err error
+		data := []byte{}
+		if home != "" {
+			p := fmt.Sprintf("%s.json", x) // TODO: validate input string?
+			profilePath := filepath.Join(home, ".config/", program+"/profiles/", p)
+			profilePath = filepath.Clean(profilePath)
+			data, err = os.ReadFile(profilePath)
+			// check errors below...
+		}
+		if os.IsNotExist(err) || home == "" {
+			data, err = os.ReadFile(x)
+		}
+
+		if err != nil {
+			logf("profile %s: %s", x, err)
+			err = nil // reset
+			continue
+		}
+
+		buffer := bytes.NewBuffer(data)
+		if buffer.Len() == 0 {
+			// TODO: should this be an error, or just a silent ignore?
+			logf("profile %s: empty input file", x)
+			continue
+		}
+		decoder := json.NewDecoder(buffer)
+
+		var profileConfig ProfileConfig // this gets populated during decode
+		if err := decoder.Decode(&profileConfig); err != nil {
+			// TODO: should this be an error, or just a silent ignore?
+			logf("profile %s: error decoding json output: %+v", x, err)
+			continue
+		}
+
+		list, err := licenses.StringsToLicenses(profileConfig.Licenses)
+		if err != nil {
+			logf("profile %s: error parsing license: %+v", x, err)
+			continue
+		}
+
+		severity := profileConfig.Severity
+		if severity == "" {
+			severity = SeverityWarning
+		}
+		if !util.StrInList(severity, Severities) {
+			logf("profile %s: invalid severity: %s", x, severity)
+			continue
+		}
+
+		profilesData[x] = &ProfileData{
+			Licenses: list,
+			Exclude:  profileConfig.Exclude,
+			Severity: severity,
+		}
+	}
+
+	// remove all the invalid/missing profiles, keep in the original order
+	profiles := []string{}
+	for _, x := range names {
+		if _, exists := profilesData[x]; exists {
+			profiles = append(profiles, x)
+		}
+	}
+	if len(profiles) == 0 {
+		// add a default profile
+		profiles = append(profiles, DefaultProfileName)
+	}
+
+	return profiles, profilesData
 }
 
 // SimpleProfiles is a simple way to filter the results. This is the first
@@ -73,6 +201,21 @@ type ProfileData struct {
 // more complicated successor to the SimpleResults function. Style can be
 // `ansi`, `html`, or `text`.
 func SimpleProfiles(results interfaces.ResultSet, passes []string, warnings map[string]error, profile *ProfileData, summary bool, backendWeights map[interfaces.Backend]float64, style string) (string, error) {
+	return simpleProfiles(results, passes, warnings, profile, summary, backendWeights, style, false)
+}
+
+// TreeProfiles is like SimpleProfiles, except that the results are displayed as
+// a directory tree instead of a flat list of paths. Each directory shows the
+// licenses found beneath it, directories which are uniformly one license are
+// collapsed, and only the subtrees with flagged, conflicted, or unknown licenses
+// are expanded. The tree includes every result, not only those that the profile
+// matches, but nothing is displayed if the profile doesn't match anything.
+func TreeProfiles(results interfaces.ResultSet, passes []string, warnings map[string]error, profile *ProfileData, summary bool, backendWeights map[interfaces.Backend]float64, style string) (string, error) {
+	return simpleProfiles(results, passes, warnings, profile, summary, backendWeights, style, true)
+}
+
+// simpleProfiles is the implementation of SimpleProfiles and TreeProfiles.
+func simpleProfiles(results interfaces.ResultSet, passes []string, warnings map[string]error, profile *ProfileData, summary bool, backendWeights map[interfaces.Backend]float64, style string, tree bool) (string, error) {
 	if style != "ansi" && style != "html" && style != "text" {
 		return "", fmt.Errorf("invalid style: %s", style)
 	}
@@ -110,8 +253,13 @@ func SimpleProfiles(results interfaces.ResultSet, passes []string, warnings map[
 	}) // for recording found skip errors
 	// XXX: handle dir's in here specially and merge in their weights with child paths!
 Loop:
-	for uri, m := range results { // FIXME: sort and process properly
-		bs := []*AnnotatedBackend{}
+	for _, uri := range sortedURIs(results) {
+		m := results[uri]
+		// NOTE: f is the confidence *if* the different results agree!
+		bs, f, err := annotateBackends(m, backendWeights)
+		if err != nil {
+			return "", err
+		}
 		ttl := 0.0      // total weight for the set of backends at this uri
 		skipUri := true // assume we skip
 		innerLicenseMap := make(map[string]int64)
@@ -119,8 +267,12 @@ Loop:
 			val, _ := innerLicenseMap[name] // defaults to zero!
 			innerLicenseMap[name] = val + 1
 		}
-		for backend, result := range m {
-			if result.Skip != nil {
+		for _, b := range bs { // in a stable order
+			backend := b.Backend
+			result := m[backend]
+			ttl += b.Weight
+			// the first error in the sorted order wins
+			if _, exists := errorMap[uri]; !exists && result.Skip != nil {
 				errorMap[uri] = struct {
 					backend string
 					err     error
@@ -149,44 +301,26 @@ Loop:
 					skipUri = false
 				}
 			}
-
-			weight, exists := backendWeights[backend]
-			if !exists {
-				return "", fmt.Errorf("no weight found for backend: %s", backend.String())
-			}
-			b := &AnnotatedBackend{
-				Backend: backend,
-				Weight:  weight,
-			}
-			bs = append(bs, b)
-			ttl += weight
 		}
 		if skipUri { // we don't want to display this Uri (this file)
 			continue Loop
 		}
-		f := 0.0 // NOTE: confidence *if* the different results agree!
-		//for backend, result := range m {
-		for _, b := range bs { // for backend, result := range m
-			backend := b.Backend
-			weight := b.Weight // backendWeights[backend]
-			result := m[backend]
-			scale := weight / ttl
-			b.ScaledConfidence = result.Confidence * scale
-			f = f + b.ScaledConfidence
-		}
 
 		// merge into to parent accounting
 		for k, v := range innerLicenseMap { // map[string]int64
 			val, _ := licenseMap[k] // defaults to zero!
 			licenseMap[k] = val + v
 		}
+		hasResults = true
+		if tree { // the tree is built from all of the results below
+			continue Loop
+		}
 
 		// start table row here after the above continue...
 		if style == "html" {
 			str += "<tr><td>"
 		}
 
-		sort.Sort(sort.Reverse(SortedBackends(bs)))
 		smartURI := util.SmartURI(uri) // make it useful to click on
 		if style == "ansi" {
 			hyperlink := util.ShellHyperlinkEncode(uri, smartURI)
@@ -216,7 +350,7 @@ Loop:
 				// only colour the matched ones!
 				for _, x := range result.Licenses {
 					r := x.String()
-					inList := licenses.InList(x, profile.Licenses)
+					inList := licenses.Matches(x, profile.Licenses)
 					if inList && !profile.Exclude || !inList && profile.Exclude {
 						r = x.String()
 						r = redString(r)
@@ -226,6 +360,11 @@ Loop:
 				}
 				l = strings.Join(ll, ", ")
 			}
+			if result.Component != "" && l != "" { // name the dependency
+				l = result.Component + ": " + l
+			} else if result.Component != "" {
+				l = result.Component
+			}
 
 			s := ""
 			if style == "ansi" {
@@ -264,6 +403,14 @@ Loop:
 		}
 	}
 
+	if tree && hasResults {
+		s, err := treeResults(results, profile, backendWeights, style, redString)
+		if err != nil {
+			return "", err
+		}
+		str = s
+	}
+
 	skippedStr := ""
 	if style == "ansi" {
 		skippedStr = fmt.Sprintf("skipped: %s files/directories\n", countStr)
@@ -275,7 +422,7 @@ Loop:
 		skippedStr = s
 	}
 	if style == "text" {
-		skippedStr = fmt.Sprintf("skipped: %d files/directories\n", countStr)
+		skippedStr = fmt.Sprintf("skipped: %s files/directories\n", countStr)
 	}
 
 	erroredStr := ""
@@ -0,0 +1,339 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// ProvenanceGit is the kind of provenance for a git repository.
+	ProvenanceGit = "git"
+
+	// ProvenanceHttp is the kind of provenance for a downloaded url.
+	ProvenanceHttp = "http"
+
+	// ProvenanceArchive is the kind of provenance for an extracted archive.
+	ProvenanceArchive = "archive"
+
+	// ProvenanceFs is the kind of provenance for a local path.
+	ProvenanceFs = "fs"
+
+	// ProvenanceUnknown is the kind of provenance for results which weren't
+	// tagged with an iterator.
+	ProvenanceUnknown = "unknown"
+)
+
+// provenance is one of the inputs that was scanned, such as a git repository at
+// a specific hash, a downloaded url, an archive, or a local path. It is built
+// from the chain of iterators that the results were tagged with. These are what
+// the different software bill of materials formats call packages or components.
+type provenance struct {
+	// iterator is the iterator that this was built from. It is nil if the
+	// kind is unknown.
+	iterator interfaces.Iterator
+
+	// parent is the provenance that this one was found in, or nil if this
+	// was one of the original inputs.
+	parent *provenance
+
+	kind    string
+	name    string
+	version string // the git hash
+
+	// url is where this can be downloaded from, without any credentials.
+	url string
+
+	// fileName is the base name of an archive.
+	fileName string
+
+	// path is the location of an archive relative to the root of the
+	// input that it was found in. It is empty if this isn't known.
+	path string
+
+	// comment is a human readable description of where this came from.
+	comment string
+
+	// files is the list of files that directly belong to this, sorted by
+	// uid. Files inside of nested archives belong to those instead.
+	files []*provenanceFile
+
+	// dirs is the list of directories that a backend made a determination
+	// for as a whole dependency, sorted by uid.
+	dirs []*provenanceFile
+}
+
+// provenanceFile is a scanned file that returned some results.
+type provenanceFile struct {
+	uid string
+
+	// name is the path relative to the root of the provenance, prefixed
+	// with `./`.
+	name string
+
+	sha1   string
+	sha256 string
+
+	// component is the dependency that a backend said this directory is.
+	// It is only set for the directories in the dirs list.
+	component string
+
+	results map[interfaces.Backend]*interfaces.Result
+}
+
+// buildProvenance groups the scanned files by the input that they came from. It
+// returns the list of provenances with each parent before its children. It only
+// includes files, since directories have no checksums and most formats don't
+// have a way to represent results for them. The exception is directories which
+// a backend said are a whole dependency, which are kept separately.
+func buildProvenance(output *Output) []*provenance {
+	obj := &provenanceBuilder{
+		output: output,
+		list:   []*provenance{},
+		m:      make(map[interfaces.Iterator]*provenance),
+	}
+
+	uids := []string{}
+	for uid := range output.Results {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids) // deterministic order
+
+	for _, uid := range uids {
+		m := output.Results[uid]
+
+		// pick the metadata in a deterministic way
+		names := []string{}
+		metas := make(map[string]*interfaces.Meta)
+		for backend, result := range m {
+			if result.Meta == nil {
+				continue
+			}
+			names = append(names, backend.String())
+			metas[backend.String()] = result.Meta
+		}
+		sort.Strings(names)
+		meta := &interfaces.Meta{}
+		for _, name := range names {
+			if metas[name].SHA1 != "" {
+				meta = metas[name]
+				break
+			}
+		}
+		if meta.SHA1 == "" { // a directory
+			component, it := provenanceComponent(m)
+			if component == "" {
+				continue
+			}
+			p := obj.provenanceFor(it)
+			p.dirs = append(p.dirs, &provenanceFile{
+				uid:       uid,
+				name:      provenanceFileName(it, uid),
+				component: component,
+				results:   m,
+			})
+			continue
+		}
+
+		p := obj.provenanceFor(meta.Iterator)
+		p.files = append(p.files, &provenanceFile{
+			uid:     uid,
+			name:    provenanceFileName(meta.Iterator, uid),
+			sha1:    meta.SHA1,
+			sha256:  meta.SHA256,
+			results: m,
+		})
+	}
+
+	return obj.list
+}
+
+// provenanceBuilder holds the state needed while building the provenance list.
+type provenanceBuilder struct {
+	output *Output
+	list   []*provenance
+	m      map[interfaces.Iterator]*provenance
+}
+
+// provenanceFor returns the provenance of a file that was scanned by this
+// iterator, building it and its parents if they don't already exist. An fs
+// iterator that was built by another iterator, such as the one that walks a git
+// clone, is part of the provenance of that parent iterator.
+func (obj *provenanceBuilder) provenanceFor(it interfaces.Iterator) *provenance {
+	key := it
+	if fs, ok := it.(*iterator.Fs); ok && fs.Iterator != nil {
+		key = fs.Iterator
+	}
+	if p, exists := obj.m[key]; exists {
+		return p
+	}
+
+	// build the parent first to get a more natural ordering
+	var parent *provenance
+	if key != nil && key.GetIterator() != nil {
+		parent = obj.provenanceFor(key.GetIterator())
+	}
+
+	p := &provenance{
+		iterator: key,
+		parent:   parent,
+		kind:     ProvenanceUnknown,
+		name:     obj.output.Program,
+		comment:  "unknown origin",
+		files:    []*provenanceFile{},
+	}
+	obj.m[key] = p
+	obj.list = append(obj.list, p)
+
+	var archive safepath.AbsFile // local path of an archive
+	switch x := key.(type) {
+	case *iterator.Git:
+		p.kind = ProvenanceGit
+		p.name = x.URL
+		if u, err := url.Parse(x.URL); err == nil {
+			u.User = nil // don't publish any credentials
+			p.name = u.String()
+			if u.Scheme != "" && u.Host != "" {
+				p.url = p.name
+			}
+		}
+
+		p.version = x.Hash
+		if fs, ok := it.(*iterator.Fs); ok && p.version == "" {
+			// the hash that was actually checked out is in the uid
+			if uid, err := fsUID(fs, fs.Path); err == nil {
+				if v, err := url.Parse(uid); err == nil {
+					p.version = v.Query().Get("sha1")
+				}
+			}
+		}
+		p.comment = fmt.Sprintf("git repository: %s", x.String())
+
+	case *iterator.Http:
+		p.kind = ProvenanceHttp
+		p.name = x.URL
+		p.url = x.URL
+		p.comment = fmt.Sprintf("downloaded from: %s", x.URL)
+
+	case *iterator.Zip:
+		archive = x.Path
+	case *iterator.Tar:
+		archive = x.Path
+	case *iterator.Gzip:
+		archive = x.Path
+	case *iterator.Bzip2:
+		archive = x.Path
+
+	case *iterator.Fs:
+		p.kind = ProvenanceFs
+		p.name = x.Path.String()
+		p.comment = fmt.Sprintf("local path: %s", x.Path.String())
+	}
+
+	if archive.String() != "" {
+		p.kind = ProvenanceArchive
+		p.name = filepath.Base(archive.Path())
+		p.fileName = p.name
+		p.comment = "archive"
+		if fs, ok := key.GetIterator().(*iterator.Fs); ok {
+			if uid, err := fsUID(fs, archive); err == nil {
+				p.comment = fmt.Sprintf("archive: %s", uid)
+				p.path = strings.TrimPrefix(provenanceFileName(fs, uid), "./")
+			}
+		}
+	}
+
+	return p
+}
+
+// provenanceComponent returns the dependency that a backend said a directory
+// is, and the iterator that found it. The backends are checked in order of name
+// so that this is deterministic. It returns an empty string if there isn't one.
+func provenanceComponent(m map[interfaces.Backend]*interfaces.Result) (string, interfaces.Iterator) {
+	names := []string{}
+	results := make(map[string]*interfaces.Result)
+	for backend, result := range m {
+		names = append(names, backend.String())
+		results[backend.String()] = result
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if result := results[name]; result.Component != "" && result.Meta != nil {
+			return result.Component, result.Meta.Iterator
+		}
+	}
+	return "", nil
+}
+
+// provenancePath returns the path of a file relative to the root of the
+// original input, including the paths of any archives that it's nested inside.
+func provenancePath(p *provenance, f *provenanceFile) string {
+	s := strings.TrimPrefix(f.name, "./")
+	for x := p; x != nil && x.parent != nil; x = x.parent {
+		if x.path == "" {
+			break // we don't know where this is
+		}
+		s = x.path + "/" + s
+	}
+	return s
+}
+
+// provenanceFileName returns the file name relative to the root of the fs
+// iterator that scanned it, prefixed with a `./`.
+func provenanceFileName(it interfaces.Iterator, uid string) string {
+	name := stripQuery(uid)
+	if fs, ok := it.(*iterator.Fs); ok {
+		if root, err := fsUID(fs, fs.Path); err == nil {
+			root = stripQuery(root)
+			if strings.HasPrefix(name, root) && name != root {
+				return "./" + strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+			}
+		}
+	}
+	return "./" + filepath.Base(name)
+}
+
+// fsUID returns the uid that the fs iterator would use for a path.
+func fsUID(fs *iterator.Fs, p safepath.Path) (string, error) {
+	if fs.GenUID != nil {
+		return fs.GenUID(p)
+	}
+	return iterator.FileScheme + p.String(), nil
+}
+
+// stripQuery removes the query string from a uid if it has one.
+func stripQuery(uid string) string {
+	if ix := strings.Index(uid, "?"); ix > -1 {
+		return uid[:ix]
+	}
+	return uid
+}
@@ -0,0 +1,68 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/yesiscan/util/errwrap"
+)
+
+// PullRequest scans the changes between two revisions of each git input. It
+// runs one scan at the base revision and one at the head revision, each of
+// which only includes the files that differ between the two, so that the
+// unchanged files, which can't change the licenses, aren't scanned at all. It
+// returns the diff of the licenses, and both of the outputs. The revisions are
+// anything that git can resolve in a clone, such as a hash, a tag, or a branch
+// like `origin/feature`. The Rev and Since fields of the struct are ignored.
+func (obj *Main) PullRequest(ctx context.Context, base, head string) (*Diff, *Output, *Output, error) {
+	if base == "" || head == "" {
+		return nil, nil, nil, fmt.Errorf("must specify a base and a head revision")
+	}
+
+	m := *obj // copy
+	m.Rev = base
+	m.Since = head
+	obj.Logf("scanning base revision: %s", base)
+	before, err := m.Run(ctx)
+	if err != nil {
+		return nil, nil, nil, errwrap.Wrapf(err, "could not scan base revision")
+	}
+
+	m = *obj // copy
+	m.Rev = head
+	m.Since = base
+	obj.Logf("scanning head revision: %s", head)
+	after, err := m.Run(ctx)
+	if err != nil {
+		return nil, nil, nil, errwrap.Wrapf(err, "could not scan head revision")
+	}
+
+	diff, err := BuildDiff(before, after)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return diff, before, after, nil
+}
@@ -0,0 +1,441 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// ReportSchemaVersion is the version of the Report structure below. It
+	// must be incremented whenever an incompatible change is made to it, so
+	// that consumers of the machine-readable output can detect this.
+	ReportSchemaVersion = 1
+)
+
+// Report is the structured, machine-readable form of an Output. It contains
+// every scanned file, whether or not any profile would display it, so that
+// other tooling can apply its own filtering. All of the lists are sorted so
+// that the result is deterministic. This is the documented schema for the
+// `json` and `jsonl` output types, so be careful when changing it.
+type Report struct {
+	// Schema is the ReportSchemaVersion that this report was built with.
+	Schema int `json:"schema"`
+
+	Program string `json:"program"`
+	Version string `json:"version"`
+
+	// Args are the input strings that were scanned.
+	Args []string `json:"args"`
+
+	// Backends is the map of backend names, and whether they were enabled.
+	Backends map[string]bool `json:"backends"`
+
+	// Profiles is the list of profiles that were used, in order.
+	Profiles []*ReportProfile `json:"profiles"`
+
+	// Files is the list of every scanned path that returned a result.
+	Files []*ReportFile `json:"files"`
+
+	// Skipped is the list of paths which were scanned, but for which no
+	// backend returned any result.
+	Skipped []string `json:"skipped"`
+
+	// Errors is the list of results that a backend returned with a skip
+	// error. The same path may appear here more than once.
+	Errors []*ReportError `json:"errors"`
+
+	// Warnings is the list of non-fatal iterator errors.
+	Warnings []*ReportError `json:"warnings"`
+
+	// Summary contains some counts for the above data.
+	Summary *ReportSummary `json:"summary"`
+}
+
+// ReportProfile is the representation of a profile used in a Report.
+type ReportProfile struct {
+	// Name is the name of the profile as it was specified.
+	Name string `json:"name"`
+
+	// Licenses is the list of licenses to match. It is empty for the
+	// default profile which matches everything.
+	Licenses []string `json:"licenses"`
+
+	// Exclude is true if this is an exclude list instead of an include list.
+	Exclude bool `json:"exclude"`
+}
+
+// ReportFile is the set of results for a single path.
+type ReportFile struct {
+	// UID is the unique identifier of the path, as used in the ResultSet.
+	UID string `json:"uid"`
+
+	// SmartURI is a (hopefully) clickable version of the UID.
+	SmartURI string `json:"smart_uri"`
+
+	// SHA1 is the hex encoded sha1 checksum of the file. It is empty for
+	// directories.
+	SHA1 string `json:"sha1,omitempty"`
+
+	// SHA256 is the hex encoded sha256 checksum of the file. It is empty
+	// for directories.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Confidence is the weighted confidence across all the backends, if
+	// they were to all agree.
+	Confidence float64 `json:"confidence"`
+
+	// Licenses is the sorted union of every license found by any backend.
+	Licenses []string `json:"licenses"`
+
+	// Profiles is the list of profile names that this file matched. These
+	// are the profiles that would display this file.
+	Profiles []string `json:"profiles"`
+
+	// Results is the list of backend results, sorted by decreasing scaled
+	// confidence, and then by backend name.
+	Results []*ReportResult `json:"results"`
+}
+
+// ReportResult is the representation of a single backend result.
+type ReportResult struct {
+	// Backend is the name of the backend that produced this result.
+	Backend string `json:"backend"`
+
+	// Weight is the weight that was given to this backend.
+	Weight float64 `json:"weight"`
+
+	// Confidence is the confidence that the backend returned.
+	Confidence float64 `json:"confidence"`
+
+	// ScaledConfidence is the confidence scaled by the backend weight as a
+	// fraction of the total weight of all backends for this path.
+	ScaledConfidence float64 `json:"scaled_confidence"`
+
+	// Licenses is the list of licenses found. Each is in the SPDX ID form,
+	// or in the `name(origin)` form if it is not a known SPDX ID.
+	Licenses []string `json:"licenses"`
+
+	// Skip is the skip error string if there was one.
+	Skip string `json:"skip,omitempty"`
+
+	// Component is the dependency that this result is a determination for,
+	// if the backend made one for a whole dependency.
+	Component string `json:"component,omitempty"`
+
+	// Holders is the list of copyright holders that the backend found in
+	// some metadata about this path.
+	Holders []string `json:"holders,omitempty"`
+
+	// More is the list of additional, less likely results. These never
+	// have a weight or scaled confidence.
+	More []*ReportResult `json:"more,omitempty"`
+}
+
+// ReportError is a path and the error that was seen for it.
+type ReportError struct {
+	// UID is the unique identifier of the path.
+	UID string `json:"uid"`
+
+	// Backend is the name of the backend that erred. It is empty for
+	// iterator warnings.
+	Backend string `json:"backend,omitempty"`
+
+	// Error is the error string.
+	Error string `json:"error"`
+}
+
+// ReportSummary contains counts of the different report elements.
+type ReportSummary struct {
+	// Files is the number of files with results.
+	Files int `json:"files"`
+
+	// Skipped is the number of skipped paths.
+	Skipped int `json:"skipped"`
+
+	// Errors is the number of errors.
+	Errors int `json:"errors"`
+
+	// Warnings is the number of warnings.
+	Warnings int `json:"warnings"`
+
+	// Licenses is the number of times each license was found across all
+	// of the backend results.
+	Licenses map[string]int64 `json:"licenses"`
+
+	// Profiles is the summary for each profile name.
+	Profiles map[string]*ReportProfileSummary `json:"profiles"`
+}
+
+// ReportProfileSummary contains the counts for a single profile.
+type ReportProfileSummary struct {
+	// Files is the number of files that matched this profile.
+	Files int `json:"files"`
+
+	// Licenses is the number of times each license was found across the
+	// backend results of the matched files. This is the same accounting
+	// that the summary in SimpleProfiles displays.
+	Licenses map[string]int64 `json:"licenses"`
+}
+
+// BuildReport builds the structured Report from an Output.
+func BuildReport(output *Output) (*Report, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	report := &Report{
+		Schema:   ReportSchemaVersion,
+		Program:  output.Program,
+		Version:  output.Version,
+		Args:     []string{},
+		Backends: make(map[string]bool),
+		Profiles: []*ReportProfile{},
+		Files:    []*ReportFile{},
+		Skipped:  []string{},
+		Errors:   []*ReportError{},
+		Warnings: []*ReportError{},
+		Summary: &ReportSummary{
+			Licenses: make(map[string]int64),
+			Profiles: make(map[string]*ReportProfileSummary),
+		},
+	}
+	report.Args = append(report.Args, output.Args...)
+	for k, v := range output.Backends {
+		report.Backends[k] = v
+	}
+
+	for _, name := range output.Profiles {
+		profile := &ReportProfile{
+			Name:     name,
+			Licenses: []string{},
+		}
+		if data := output.ProfilesData[name]; data != nil {
+			for _, x := range data.Licenses {
+				profile.Licenses = append(profile.Licenses, x.String())
+			}
+			profile.Exclude = data.Exclude
+		}
+		report.Profiles = append(report.Profiles, profile)
+		report.Summary.Profiles[name] = &ReportProfileSummary{
+			Licenses: make(map[string]int64),
+		}
+	}
+
+	uids := []string{}
+	for uid := range output.Results {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids) // deterministic order
+
+	for _, uid := range uids {
+		m := output.Results[uid]
+		bs, f, err := annotateBackends(m, output.BackendWeights)
+		if err != nil {
+			return nil, err
+		}
+
+		file := &ReportFile{
+			UID:        uid,
+			SmartURI:   util.SmartURI(uid),
+			Confidence: f,
+			Licenses:   []string{},
+			Profiles:   []string{},
+			Results:    []*ReportResult{},
+		}
+
+		found := make(map[string]struct{}) // union of licenses
+		counts := make(map[string]int64)   // for the summaries
+		for _, b := range bs {
+			result := m[b.Backend]
+			r := reportResult(b.Backend.String(), result)
+			r.Weight = b.Weight
+			r.ScaledConfidence = b.ScaledConfidence
+			file.Results = append(file.Results, r)
+
+			if meta := result.Meta; meta != nil && file.SHA1 == "" {
+				file.SHA1 = meta.SHA1
+				file.SHA256 = meta.SHA256
+			}
+
+			for _, x := range result.Licenses {
+				found[x.String()] = struct{}{}
+				counts[x.String()]++
+			}
+
+			if result.Skip != nil {
+				report.Errors = append(report.Errors, &ReportError{
+					UID:     uid,
+					Backend: b.Backend.String(),
+					Error:   result.Skip.Error(),
+				})
+			}
+		}
+		for k := range found {
+			file.Licenses = append(file.Licenses, k)
+		}
+		sort.Strings(file.Licenses)
+
+		for k, v := range counts {
+			report.Summary.Licenses[k] += v
+		}
+
+		for _, name := range output.Profiles {
+			if !profileMatches(output.ProfilesData[name], m) {
+				continue
+			}
+			file.Profiles = append(file.Profiles, name)
+
+			summary := report.Summary.Profiles[name]
+			summary.Files++
+			for k, v := range counts {
+				summary.Licenses[k] += v
+			}
+		}
+
+		report.Files = append(report.Files, file)
+	}
+
+	report.Skipped = append(report.Skipped, output.Passes...)
+	sort.Strings(report.Skipped)
+
+	names := []string{}
+	for k := range output.Warnings {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		report.Warnings = append(report.Warnings, &ReportError{
+			UID:   k,
+			Error: output.Warnings[k].Error(),
+		})
+	}
+
+	report.Summary.Files = len(report.Files)
+	report.Summary.Skipped = len(report.Skipped)
+	report.Summary.Errors = len(report.Errors)
+	report.Summary.Warnings = len(report.Warnings)
+
+	return report, nil
+}
+
+// reportResult converts a single result into the report form. It does not fill
+// in the weight fields since those depend on the other backends.
+func reportResult(backend string, result *interfaces.Result) *ReportResult {
+	r := &ReportResult{
+		Backend:    backend,
+		Confidence: result.Confidence,
+		Licenses:   []string{},
+		Component:  result.Component,
+		Holders:    result.Holders,
+	}
+	for _, x := range result.Licenses {
+		r.Licenses = append(r.Licenses, x.String())
+	}
+	if result.Skip != nil {
+		r.Skip = result.Skip.Error()
+	}
+	for _, x := range result.More {
+		r.More = append(r.More, reportResult(backend, x))
+	}
+	return r
+}
+
+// annotateBackends computes the weight and scaled confidence of each backend
+// for the results at a single path. It returns them sorted by decreasing scaled
+// confidence, with ties broken by the backend name, and it also returns the
+// total weighted confidence if all the results were to agree.
+func annotateBackends(m map[interfaces.Backend]*interfaces.Result, backendWeights map[interfaces.Backend]float64) ([]*AnnotatedBackend, float64, error) {
+	bs := []*AnnotatedBackend{}
+	ttl := 0.0 // total weight for the set of backends at this uri
+	for backend := range m {
+		weight, exists := backendWeights[backend]
+		if !exists {
+			return nil, 0, fmt.Errorf("no weight found for backend: %s", backend.String())
+		}
+		b := &AnnotatedBackend{
+			Backend: backend,
+			Weight:  weight,
+		}
+		bs = append(bs, b)
+		ttl += weight
+	}
+
+	f := 0.0 // NOTE: confidence *if* the different results agree!
+	for _, b := range bs {
+		result := m[b.Backend]
+		scale := 0.0
+		if ttl > 0 {
+			scale = b.Weight / ttl
+		}
+		b.ScaledConfidence = result.Confidence * scale
+		f = f + b.ScaledConfidence
+	}
+
+	sort.Slice(bs, func(i, j int) bool { // sort by name first
+		return bs[i].Backend.String() < bs[j].Backend.String()
+	})
+	sort.Stable(sort.Reverse(SortedBackends(bs)))
+
+	return bs, f, nil
+}
+
+// profileMatches returns true if any of the results at a path should be shown
+// by this profile. A nil profile is the default profile which matches all. This
+// is the same logic that SimpleProfiles uses to decide what to display.
+func profileMatches(profile *ProfileData, m map[interfaces.Backend]*interfaces.Result) bool {
+	if profile == nil {
+		return true
+	}
+	return len(profileLicenses(profile, m)) > 0
+}
+
+// profileLicenses returns the list of licenses in the results at a path that
+// cause this profile to match it. For an include profile these are the found
+// licenses that are in the profile, and for an exclude profile these are the
+// found licenses that aren't. The list has no duplicates and is sorted. A nil
+// profile returns every license that was found.
+func profileLicenses(profile *ProfileData, m map[interfaces.Backend]*interfaces.Result) []*licenses.License {
+	found := []*licenses.License{}
+	for _, result := range m {
+		for _, x := range result.Licenses {
+			if profile != nil && licenses.Matches(x, profile.Licenses) == profile.Exclude {
+				continue
+			}
+			if licenses.InList(x, found) {
+				continue
+			}
+			found = append(found, x)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].String() < found[j].String()
+	})
+	return found
+}
@@ -0,0 +1,130 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+// testBackend is a backend that does nothing but has a name.
+type testBackend struct {
+	name string
+}
+
+func (obj *testBackend) String() string { return obj.name }
+
+// testOutput builds a small output with two backends and three files.
+func testOutput() *lib.Output {
+	b1 := &testBackend{name: "b1"}
+	b2 := &testBackend{name: "b2"}
+	mit := &licenses.License{SPDX: "MIT"}
+	gpl := &licenses.License{SPDX: "GPL-2.0-only"}
+
+	return &lib.Output{
+		Program:  "yesiscan",
+		Version:  "test",
+		Args:     []string{"/tmp/"},
+		Backends: map[string]bool{"b1": true, "b2": true},
+		Results: interfaces.ResultSet{
+			"file:///tmp/c": {
+				b1: {Licenses: []*licenses.License{gpl}, Confidence: 1.0},
+			},
+			"file:///tmp/a": {
+				b1: {Licenses: []*licenses.License{mit}, Confidence: 1.0},
+				b2: {Licenses: []*licenses.License{mit}, Confidence: 0.5},
+			},
+			"file:///tmp/b": {
+				b2: {Licenses: []*licenses.License{}, Confidence: 1.0, Skip: fmt.Errorf("oops")},
+			},
+		},
+		Passes:   []string{"file:///tmp/z", "file:///tmp/y"},
+		Warnings: map[string]error{"/tmp/x.zip": fmt.Errorf("bad zip")},
+		Profiles: []string{"default", "gpl"},
+		ProfilesData: map[string]*lib.ProfileData{
+			"default": nil,
+			"gpl": {
+				Licenses: []*licenses.License{gpl},
+			},
+		},
+		BackendWeights: map[interfaces.Backend]float64{
+			b1: 1.0,
+			b2: 3.0,
+		},
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	report, err := lib.BuildReport(testOutput())
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+
+	uids := []string{}
+	for _, x := range report.Files {
+		uids = append(uids, x.UID)
+	}
+	if exp := []string{"file:///tmp/a", "file:///tmp/b", "file:///tmp/c"}; !reflect.DeepEqual(uids, exp) {
+		t.Errorf("uids: %v, exp: %v", uids, exp)
+	}
+
+	a := report.Files[0]
+	if a.Confidence != 0.625 { // 1.0 * 1/4 + 0.5 * 3/4
+		t.Errorf("confidence: %v, exp: %v", a.Confidence, 0.625)
+	}
+	if a.Results[0].Backend != "b2" || a.Results[1].Backend != "b1" {
+		t.Errorf("results are not sorted by scaled confidence")
+	}
+	if exp := []string{"default"}; !reflect.DeepEqual(a.Profiles, exp) {
+		t.Errorf("profiles: %v, exp: %v", a.Profiles, exp)
+	}
+
+	c := report.Files[2]
+	if exp := []string{"default", "gpl"}; !reflect.DeepEqual(c.Profiles, exp) {
+		t.Errorf("profiles: %v, exp: %v", c.Profiles, exp)
+	}
+
+	if exp := []string{"file:///tmp/y", "file:///tmp/z"}; !reflect.DeepEqual(report.Skipped, exp) {
+		t.Errorf("skipped: %v, exp: %v", report.Skipped, exp)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].UID != "file:///tmp/b" || report.Errors[0].Backend != "b2" {
+		t.Errorf("unexpected errors: %+v", report.Errors)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Error != "bad zip" {
+		t.Errorf("unexpected warnings: %+v", report.Warnings)
+	}
+
+	if n := report.Summary.Licenses["MIT"]; n != 2 {
+		t.Errorf("mit count: %d, exp: %d", n, 2)
+	}
+	if n := report.Summary.Profiles["gpl"].Files; n != 1 {
+		t.Errorf("gpl files: %d, exp: %d", n, 1)
+	}
+}
@@ -61,33 +61,18 @@ func SimpleResults(results interfaces.ResultSet, backendWeights map[interfaces.B
 
 	str := ""
 	// XXX: handle dir's in here specially and merge in their weights with child paths!
-	for uri, m := range results { // FIXME: sort and process properly
-		bs := []*AnnotatedBackend{}
-		ttl := 0.0 // total weight for the set of backends at this uri
-		for backend := range m {
-			weight, exists := backendWeights[backend]
-			if !exists {
-				return "", fmt.Errorf("no weight found for backend: %s", backend.String())
-			}
-			b := &AnnotatedBackend{
-				Backend: backend,
-				Weight:  weight,
-			}
-			bs = append(bs, b)
-			ttl += weight
+	for _, uri := range sortedURIs(results) {
+		m := results[uri]
+		// NOTE: f is the confidence *if* the different results agree!
+		bs, f, err := annotateBackends(m, backendWeights)
+		if err != nil {
+			return "", err
 		}
-		f := 0.0 // NOTE: confidence *if* the different results agree!
-		//for backend, result := range m {
-		for _, b := range bs { // for backend, result := range m
-			backend := b.Backend
-			weight := b.Weight // backendWeights[backend]
-			result := m[backend]
-			scale := weight / ttl
-			b.ScaledConfidence = result.Confidence * scale
-			f = f + b.ScaledConfidence
+		ttl := 0.0 // total weight for the set of backends at this uri
+		for _, b := range bs {
+			ttl += b.Weight
 		}
 
-		sort.Sort(sort.Reverse(SortedBackends(bs)))
 		display := uri // show the URI
 		smartURI := util.SmartURI(uri)
 		hyperlink := util.ShellHyperlinkEncode(display, smartURI)
@@ -117,3 +102,14 @@ func SimpleResults(results interfaces.ResultSet, backendWeights map[interfaces.B
 	}
 	return str, nil
 }
+
+// sortedURIs returns the uri's of the results in sorted order, so that every
+// display function shows them in the same deterministic order.
+func sortedURIs(results interfaces.ResultSet) []string {
+	uris := []string{}
+	for uri := range results {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
@@ -0,0 +1,81 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/lib"
+)
+
+func TestSimpleOutputDeterministic(t *testing.T) {
+	output := testOutput()
+
+	exp, err := lib.SimpleResults(output.Results, output.BackendWeights)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	a := strings.Index(exp, "file:///tmp/a")
+	b := strings.Index(exp, "file:///tmp/b")
+	c := strings.Index(exp, "file:///tmp/c")
+	if a == -1 || !(a < b && b < c) {
+		t.Errorf("results are not sorted by uri: %s", exp)
+	}
+
+	for _, style := range []string{"ansi", "html", "text"} {
+		first, err := lib.SimpleProfiles(output.Results, output.Passes, output.Warnings, nil, true, output.BackendWeights, style)
+		if err != nil {
+			t.Errorf("err: %+v", err)
+			return
+		}
+		// map iteration order is random, so try a few times
+		for i := 0; i < 20; i++ {
+			s, err := lib.SimpleProfiles(output.Results, output.Passes, output.Warnings, nil, true, output.BackendWeights, style)
+			if err != nil {
+				t.Errorf("err: %+v", err)
+				return
+			}
+			if s != first {
+				t.Errorf("%s: output is not deterministic", style)
+				t.Logf("exp: %s", first)
+				t.Logf("got: %s", s)
+				break
+			}
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		s, err := lib.SimpleResults(output.Results, output.BackendWeights)
+		if err != nil {
+			t.Errorf("err: %+v", err)
+			return
+		}
+		if s != exp {
+			t.Errorf("results output is not deterministic")
+			break
+		}
+	}
+}
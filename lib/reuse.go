@@ -0,0 +1,278 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/backend"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// ReuseLicenseRefPrefix is the prefix of the custom license ids that
+	// the REUSE specification allows.
+	ReuseLicenseRefPrefix = "LicenseRef-"
+)
+
+// ReuseBackends are the names of the backends whose results count as the REUSE
+// licensing information of a file. The others detect licenses rather than read
+// what was declared, so they don't count.
+var ReuseBackends = []string{
+	"reuse",
+	"spdx",
+}
+
+// ReuseLint is a check of the scanned files against the REUSE specification,
+// which is similar to what the `reuse lint` tool reports. Every scanned file is
+// checked, and the license texts in any LICENSES directory count for all of
+// them.
+type ReuseLint struct {
+	Program string
+	Version string
+
+	// Files is the number of files that needed licensing information.
+	Files int
+
+	// Unlicensed is the sorted list of paths of the files that have no
+	// licensing information.
+	Unlicensed []string
+
+	// Missing is the sorted list of license ids that are used but which
+	// have no license text.
+	Missing []*ReuseLicense
+
+	// Unused is the sorted list of license texts whose id isn't used.
+	Unused []*ReuseLicense
+}
+
+// ReuseLicense is a license id and the paths of the files that it was found in.
+// These are the license texts for an unused license, and the files which use it
+// for a missing license.
+type ReuseLicense struct {
+	ID    string
+	Paths []string
+}
+
+// Compliant returns true if no problems were found.
+func (obj *ReuseLint) Compliant() bool {
+	return len(obj.Unlicensed) == 0 && len(obj.Missing) == 0 && len(obj.Unused) == 0
+}
+
+// ReturnOutputReuse returns a string of output, formatted as a REUSE lint report.
+func ReturnOutputReuse(output *Output) (string, error) {
+	lint, err := BuildReuseLint(output)
+	if err != nil {
+		return "", err
+	}
+
+	s := "# REUSE LINT\n\n"
+	s += fmt.Sprintf("Checked %d files with %s %s.\n", lint.Files, lint.Program, lint.Version)
+
+	if len(lint.Missing) > 0 {
+		s += "\n# MISSING LICENSE TEXTS\n\n"
+		s += fmt.Sprintf("These licenses are used, but there is no text for them in a %s directory:\n\n", backend.ReuseLicensesDir)
+		for _, x := range lint.Missing {
+			s += fmt.Sprintf("* %s, used in: %s\n", x.ID, strings.Join(x.Paths, ", "))
+		}
+	}
+
+	if len(lint.Unused) > 0 {
+		s += "\n# UNUSED LICENSE TEXTS\n\n"
+		s += "These license texts aren't used by any file:\n\n"
+		for _, x := range lint.Unused {
+			s += fmt.Sprintf("* %s: %s\n", x.ID, strings.Join(x.Paths, ", "))
+		}
+	}
+
+	if len(lint.Unlicensed) > 0 {
+		s += "\n# MISSING LICENSING INFORMATION\n\n"
+		s += "These files have no licensing information:\n\n"
+		for _, x := range lint.Unlicensed {
+			s += fmt.Sprintf("* %s\n", x)
+		}
+	}
+
+	s += "\n# SUMMARY\n\n"
+	s += fmt.Sprintf("* Files with licensing information: %d / %d\n", lint.Files-len(lint.Unlicensed), lint.Files)
+	s += fmt.Sprintf("* Missing license texts: %d\n", len(lint.Missing))
+	s += fmt.Sprintf("* Unused license texts: %d\n", len(lint.Unused))
+	s += "\n"
+	if lint.Compliant() {
+		s += "These files are compliant with the REUSE specification.\n"
+	} else {
+		s += "These files are not compliant with the REUSE specification.\n"
+	}
+	return s, nil
+}
+
+// BuildReuseLint checks the scanned files against the REUSE specification. A
+// file has licensing information if one of the ReuseBackends found a license
+// for it. The license texts, the annotation files, the sidecar files, and the
+// usual LICENSE and COPYING files don't need any, but the licenses in them are
+// still used. A license text is the file that is named after the license id in
+// a LICENSES directory. An exception, such as in `GPL-2.0-only WITH
+// Classpath-exception-2.0`, needs its own text.
+func BuildReuseLint(output *Output) (*ReuseLint, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	reuseBackends := make(map[string]struct{})
+	for _, x := range ReuseBackends {
+		reuseBackends[x] = struct{}{}
+	}
+
+	uids := []string{}
+	for uid := range output.Results {
+		uids = append(uids, uid)
+	}
+	uids = append(uids, output.Passes...)
+
+	paths := make(map[string][]string) // file path -> uids
+	for _, uid := range uids {
+		p := reusePath(uid)
+		if strings.HasSuffix(p, "/") {
+			continue // a directory
+		}
+		paths[p] = append(paths[p], uid)
+	}
+
+	lint := &ReuseLint{
+		Program:    output.Program,
+		Version:    output.Version,
+		Unlicensed: []string{},
+		Missing:    []*ReuseLicense{},
+		Unused:     []*ReuseLicense{},
+	}
+	texts := make(map[string][]string) // license id -> text paths
+	used := make(map[string][]string)  // license id -> file paths
+	for p, xs := range paths {
+		if path.Base(path.Dir(p)) == backend.ReuseLicensesDir {
+			name := path.Base(p)
+			id := strings.TrimSuffix(name, path.Ext(name))
+			texts[id] = append(texts[id], p)
+			continue
+		}
+		// The licenses that the exempt files declare are still used,
+		// since they apply to files that might not have been scanned.
+		exempt := reuseExempt(p)
+		found := false
+		for _, uid := range xs {
+			for b, result := range output.Results[uid] {
+				if _, exists := reuseBackends[b.String()]; !exists {
+					continue
+				}
+				for _, license := range result.Licenses {
+					for _, id := range reuseIDs(license) {
+						if !util.StrInList(p, used[id]) {
+							used[id] = append(used[id], p)
+						}
+					}
+					found = true
+				}
+			}
+		}
+		if exempt {
+			continue
+		}
+		lint.Files++
+		if !found {
+			lint.Unlicensed = append(lint.Unlicensed, p)
+		}
+	}
+	sort.Strings(lint.Unlicensed)
+
+	for id, xs := range used {
+		if _, exists := texts[id]; !exists {
+			sort.Strings(xs)
+			lint.Missing = append(lint.Missing, &ReuseLicense{ID: id, Paths: xs})
+		}
+	}
+	for id, xs := range texts {
+		if _, exists := used[id]; !exists {
+			sort.Strings(xs)
+			lint.Unused = append(lint.Unused, &ReuseLicense{ID: id, Paths: xs})
+		}
+	}
+	sort.Slice(lint.Missing, func(i, j int) bool { // deterministic order
+		return lint.Missing[i].ID < lint.Missing[j].ID
+	})
+	sort.Slice(lint.Unused, func(i, j int) bool { // deterministic order
+		return lint.Unused[i].ID < lint.Unused[j].ID
+	})
+	return lint, nil
+}
+
+// reusePath returns the path of the file that the uid is for.
+func reusePath(uid string) string {
+	return strings.TrimPrefix(stripQuery(uid), iterator.FileScheme)
+}
+
+// reuseExempt returns true if the file doesn't need licensing information. These
+// are the files in the .reuse and .git directories, the REUSE.toml files, the
+// sidecar files, and the usual license files. A sidecar is for a file that has
+// usually not been scanned, such as an image, so it isn't checked for.
+func reuseExempt(p string) bool {
+	for _, x := range strings.Split(path.Dir(p), "/") {
+		if x == backend.ReuseDir || x == ".git" {
+			return true
+		}
+	}
+	name := path.Base(p)
+	if name == backend.ReuseTomlFilename || licenses.IsLicenseFile(name) {
+		return true
+	}
+	return strings.HasSuffix(name, backend.ReuseSidecarExtension)
+}
+
+// reuseIDs returns the license ids that need a license text for this license.
+// Each of the choices needs one, and so does any exception. The other ids that
+// aren't on the SPDX list only need one if they are a LicenseRef.
+func reuseIDs(license *licenses.License) []string {
+	ids := []string{}
+	for _, x := range license.Alternatives() {
+		id := x.SPDX
+		if id == "" {
+			id = x.Custom
+		}
+		parts := strings.Split(id, " WITH ")
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if x.SPDX == "" && len(parts) == 1 && !strings.HasPrefix(part, ReuseLicenseRefPrefix) {
+				continue // not an id that can have a text
+			}
+			ids = append(ids, part)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,110 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+func TestBuildReuseLint(t *testing.T) {
+	reuse := &testBackend{name: "reuse"}
+	spdx := &testBackend{name: "spdx"}
+	other := &testBackend{name: "other"}
+	mit := &licenses.License{SPDX: "MIT"}
+	apache := &licenses.License{SPDX: "Apache-2.0"}
+	gpl := &licenses.License{Custom: "GPL-2.0-only WITH Classpath-exception-2.0"}
+	choice := &licenses.License{Or: []*licenses.License{apache, {Custom: "LicenseRef-Mine"}}}
+	output := &lib.Output{
+		Program: "yesiscan",
+		Version: "test",
+		Results: interfaces.ResultSet{
+			"file:///p/LICENSES/MIT.txt": {
+				reuse: {Licenses: []*licenses.License{mit}, Confidence: 1.0},
+			},
+			"file:///p/LICENSES/BSD-3-Clause.txt": {
+				reuse: {Licenses: []*licenses.License{{SPDX: "BSD-3-Clause"}}, Confidence: 1.0},
+			},
+			"file:///p/a.go": {
+				spdx: {Licenses: []*licenses.License{mit}, Confidence: 1.0},
+			},
+			"file:///p/b.go": {
+				reuse: {Licenses: []*licenses.License{choice}, Confidence: 1.0},
+			},
+			"file:///p/c.java": {
+				reuse: {Licenses: []*licenses.License{gpl}, Confidence: 1.0},
+			},
+			"file:///p/d.go": {
+				other: {Licenses: []*licenses.License{mit}, Confidence: 1.0},
+			},
+			"file:///p/img/logo.png.license": {
+				spdx: {Licenses: []*licenses.License{{SPDX: "CC0-1.0"}}, Confidence: 1.0},
+			},
+		},
+		Passes: []string{
+			"file:///p/",
+			"file:///p/e.go",
+			"file:///p/LICENSE",
+			"file:///p/REUSE.toml",
+		},
+	}
+
+	lint, err := lib.BuildReuseLint(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if lint.Files != 5 {
+		t.Errorf("exp: 5 files, got: %d", lint.Files)
+	}
+	if s := strings.Join(lint.Unlicensed, ", "); s != "/p/d.go, /p/e.go" {
+		t.Errorf("unexpected unlicensed files: %s", s)
+	}
+	missing := []string{}
+	for _, x := range lint.Missing {
+		missing = append(missing, x.ID+": "+strings.Join(x.Paths, " "))
+	}
+	if s := strings.Join(missing, ", "); s != "Apache-2.0: /p/b.go, CC0-1.0: /p/img/logo.png.license, Classpath-exception-2.0: /p/c.java, GPL-2.0-only: /p/c.java, LicenseRef-Mine: /p/b.go" {
+		t.Errorf("unexpected missing licenses: %s", s)
+	}
+	if len(lint.Unused) != 1 || lint.Unused[0].ID != "BSD-3-Clause" {
+		t.Errorf("unexpected unused licenses: %+v", lint.Unused)
+	}
+	if lint.Compliant() {
+		t.Errorf("exp: not compliant")
+	}
+
+	s, err := lib.ReturnOutputReuse(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if !strings.Contains(s, "* Files with licensing information: 3 / 5\n") {
+		t.Errorf("unexpected output: %s", s)
+	}
+}
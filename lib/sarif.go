@@ -0,0 +1,347 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// SARIFVersion is the version of the SARIF specification we output.
+	SARIFVersion = "2.1.0"
+
+	// SARIFSchema is the json schema of the above version.
+	SARIFSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// SARIFInformationURI is where to find out more about this tool.
+	SARIFInformationURI = "https://github.com/awslabs/yesiscan"
+
+	// SARIFRuleProfilePrefix is the prefix of the rule id for each profile.
+	SARIFRuleProfilePrefix = "profile/"
+
+	// SARIFRuleUnknownLicense is the rule id for licenses that aren't on
+	// the SPDX license list.
+	SARIFRuleUnknownLicense = "license/unknown"
+
+	// SARIFRuleScanError is the rule id for backends that failed to scan a
+	// file.
+	SARIFRuleScanError = "scan/error"
+)
+
+// SARIFLog is the top-level SARIF 2.1.0 document.
+type SARIFLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*SARIFRun `json:"runs"`
+}
+
+// SARIFRun is a single run of the tool.
+type SARIFRun struct {
+	Tool    *SARIFTool     `json:"tool"`
+	Results []*SARIFResult `json:"results"`
+}
+
+// SARIFTool describes the tool that produced the run.
+type SARIFTool struct {
+	Driver *SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver is the main component of the tool, and it contains the rules.
+type SARIFDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version,omitempty"`
+	InformationURI string       `json:"informationUri,omitempty"`
+	Rules          []*SARIFRule `json:"rules"`
+}
+
+// SARIFRule is a rule that results can be reported against. We build one for
+// each profile, and one for each of the other categories of findings.
+type SARIFRule struct {
+	ID                   string              `json:"id"`
+	Name                 string              `json:"name,omitempty"`
+	ShortDescription     *SARIFMessage       `json:"shortDescription,omitempty"`
+	FullDescription      *SARIFMessage       `json:"fullDescription,omitempty"`
+	DefaultConfiguration *SARIFConfiguration `json:"defaultConfiguration,omitempty"`
+}
+
+// SARIFConfiguration is the default configuration of a rule.
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFMessage is a plain text message.
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is a single finding. We build one for each rule that matched
+// each file.
+type SARIFResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    *SARIFMessage          `json:"message"`
+	Locations  []*SARIFLocation       `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// SARIFLocation is where a result was found.
+type SARIFLocation struct {
+	PhysicalLocation *SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation is a file and an optional region within it.
+type SARIFPhysicalLocation struct {
+	ArtifactLocation *SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion           `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation is the location of a file.
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion is a span of lines within a file.
+type SARIFRegion struct {
+	StartLine int64 `json:"startLine"`
+	EndLine   int64 `json:"endLine,omitempty"`
+}
+
+// ReturnOutputSARIF returns a string of output, formatted as a SARIF log.
+func ReturnOutputSARIF(output *Output) (string, error) {
+	log, err := BuildSARIFLog(output)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(log, "", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	return string(b) + "\n", nil
+}
+
+// BuildSARIFLog builds a SARIF log from an Output. There is one rule for each
+// profile, with the level taken from the profile severity, as well as one rule
+// for licenses that aren't on the SPDX license list and one for scan errors.
+// Each file gets a result for each rule that it matched. If a backend reported
+// the lines where it found the licenses, then those are used as the regions of
+// the result. Paths are relative to the root of the scanned input, and files in
+// archives are prefixed with the path of the archive.
+func BuildSARIFLog(output *Output) (*SARIFLog, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	rules := []*SARIFRule{}
+	for _, name := range output.Profiles {
+		profile := output.ProfilesData[name]
+		short := fmt.Sprintf("File has a license (%s profile)", name)
+		full := "Any license that is found matches this profile."
+		if profile != nil && !profile.Exclude {
+			short = fmt.Sprintf("File has a license in the %s profile", name)
+			full = fmt.Sprintf("Licenses in this profile: %s.", licenses.Join(profile.Licenses))
+		}
+		if profile != nil && profile.Exclude {
+			short = fmt.Sprintf("File has a license that is not in the %s profile", name)
+			full = fmt.Sprintf("Licenses that are allowed by this profile: %s.", licenses.Join(profile.Licenses))
+		}
+		rules = append(rules, &SARIFRule{
+			ID:                   SARIFRuleProfilePrefix + name,
+			Name:                 name,
+			ShortDescription:     &SARIFMessage{Text: short},
+			FullDescription:      &SARIFMessage{Text: full},
+			DefaultConfiguration: &SARIFConfiguration{Level: ProfileSeverity(profile)},
+		})
+	}
+	rules = append(rules, &SARIFRule{
+		ID:                   SARIFRuleUnknownLicense,
+		Name:                 "unknown license",
+		ShortDescription:     &SARIFMessage{Text: "File has a license that is not on the SPDX license list"},
+		DefaultConfiguration: &SARIFConfiguration{Level: SeverityWarning},
+	})
+	rules = append(rules, &SARIFRule{
+		ID:                   SARIFRuleScanError,
+		Name:                 "scan error",
+		ShortDescription:     &SARIFMessage{Text: "A backend failed to scan the file"},
+		DefaultConfiguration: &SARIFConfiguration{Level: SeverityNote},
+	})
+	index := make(map[string]int)
+	for i, x := range rules {
+		index[x.ID] = i
+	}
+
+	results := []*SARIFResult{}
+	add := func(id string, uri string, f *provenanceFile, ls []*licenses.License, text string) {
+		rule := rules[index[id]]
+		result := &SARIFResult{
+			RuleID:    id,
+			RuleIndex: index[id],
+			Level:     rule.DefaultConfiguration.Level,
+			Message:   &SARIFMessage{Text: text},
+			Locations: sarifLocations(uri, f.results, ls),
+			Properties: map[string]interface{}{
+				"uid": f.uid,
+			},
+		}
+		if len(ls) > 0 {
+			ids := []string{}
+			for _, x := range ls {
+				ids = append(ids, x.String())
+			}
+			result.Properties["licenses"] = ids
+			result.Properties["backends"] = sarifBackends(f.results, ls)
+		}
+		results = append(results, result)
+	}
+
+	for _, p := range buildProvenance(output) {
+		for _, f := range p.files {
+			uri := provenancePath(p, f)
+
+			for _, name := range output.Profiles {
+				profile := output.ProfilesData[name]
+				ls := profileLicenses(profile, f.results)
+				if len(ls) == 0 {
+					continue
+				}
+				text := fmt.Sprintf("Found %s, which matches the %s profile.", licenses.Join(ls), name)
+				if profile != nil && profile.Exclude {
+					text = fmt.Sprintf("Found %s, which is not allowed by the %s profile.", licenses.Join(ls), name)
+				}
+				add(SARIFRuleProfilePrefix+name, uri, f, ls, text)
+			}
+
+			unknown := []*licenses.License{}
+			for _, x := range profileLicenses(nil, f.results) {
+				if !x.IsSPDX() {
+					unknown = append(unknown, x)
+				}
+			}
+			if len(unknown) > 0 {
+				text := fmt.Sprintf("Found %s, which is not on the SPDX license list.", licenses.Join(unknown))
+				add(SARIFRuleUnknownLicense, uri, f, unknown, text)
+			}
+
+			errors := []string{}
+			for backend, result := range f.results {
+				if result.Skip == nil {
+					continue
+				}
+				errors = append(errors, fmt.Sprintf("%s: %s", backend.String(), result.Skip.Error()))
+			}
+			sort.Strings(errors)
+			if len(errors) > 0 {
+				text := fmt.Sprintf("Scan errors: %s", strings.Join(errors, "; "))
+				add(SARIFRuleScanError, uri, f, nil, text)
+			}
+		}
+	}
+
+	return &SARIFLog{
+		Schema:  SARIFSchema,
+		Version: SARIFVersion,
+		Runs: []*SARIFRun{
+			{
+				Tool: &SARIFTool{
+					Driver: &SARIFDriver{
+						Name:           output.Program,
+						Version:        output.Version,
+						InformationURI: SARIFInformationURI,
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}, nil
+}
+
+// sarifLocations returns the list of locations for a result. There is one for
+// each region where a backend found one of the licenses, or just the file if
+// there are no regions.
+func sarifLocations(uri string, m map[interfaces.Backend]*interfaces.Result, ls []*licenses.License) []*SARIFLocation {
+	regions := []*SARIFRegion{}
+	found := make(map[SARIFRegion]struct{})
+	for _, result := range m {
+		for _, x := range result.Regions {
+			if x.License == nil || !licenses.InList(x.License, ls) {
+				continue
+			}
+			region := SARIFRegion{StartLine: x.StartLine, EndLine: x.EndLine}
+			if _, exists := found[region]; exists {
+				continue
+			}
+			found[region] = struct{}{}
+			regions = append(regions, &region)
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].StartLine != regions[j].StartLine {
+			return regions[i].StartLine < regions[j].StartLine
+		}
+		return regions[i].EndLine < regions[j].EndLine
+	})
+
+	locations := []*SARIFLocation{}
+	for _, x := range regions {
+		locations = append(locations, &SARIFLocation{
+			PhysicalLocation: &SARIFPhysicalLocation{
+				ArtifactLocation: &SARIFArtifactLocation{URI: uri},
+				Region:           x,
+			},
+		})
+	}
+	if len(locations) == 0 {
+		locations = append(locations, &SARIFLocation{
+			PhysicalLocation: &SARIFPhysicalLocation{
+				ArtifactLocation: &SARIFArtifactLocation{URI: uri},
+			},
+		})
+	}
+	return locations
+}
+
+// sarifBackends returns the sorted names of the backends that found any of the
+// licenses.
+func sarifBackends(m map[interfaces.Backend]*interfaces.Result, ls []*licenses.License) []string {
+	names := []string{}
+	for backend, result := range m {
+		for _, x := range result.Licenses {
+			if licenses.InList(x, ls) {
+				names = append(names, backend.String())
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
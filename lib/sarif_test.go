@@ -0,0 +1,168 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestBuildSARIFLog(t *testing.T) {
+	absDir, err := safepath.ParseIntoAbsDir("/tmp/project/")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	fs := &iterator.Fs{
+		Path: absDir,
+	}
+	meta := &interfaces.Meta{
+		Iterator: fs,
+		SHA1:     "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+	}
+
+	b1 := &testBackend{name: "b1"}
+	b2 := &testBackend{name: "b2"}
+	mit := &licenses.License{SPDX: "MIT"}
+	gpl := &licenses.License{SPDX: "GPL-2.0-only"}
+	custom := &licenses.License{Custom: "Weird"}
+	output := &lib.Output{
+		Program: "yesiscan",
+		Version: "test",
+		Results: interfaces.ResultSet{
+			"file:///tmp/project/a.c": {
+				b1: {
+					Licenses:   []*licenses.License{gpl, mit},
+					Confidence: 1.0,
+					Meta:       meta,
+					Regions: []*interfaces.Region{
+						{License: gpl, StartLine: 7, EndLine: 9},
+						{License: gpl, StartLine: 2, EndLine: 2},
+						{License: mit, StartLine: 1, EndLine: 1},
+					},
+				},
+				b2: {
+					Licenses:   []*licenses.License{gpl},
+					Confidence: 1.0,
+					Meta:       meta,
+					Regions: []*interfaces.Region{
+						{License: gpl, StartLine: 2, EndLine: 2}, // duplicate
+					},
+				},
+			},
+			"file:///tmp/project/b.c": {
+				b1: {Licenses: []*licenses.License{custom}, Confidence: 1.0, Meta: meta},
+				b2: {Licenses: []*licenses.License{}, Confidence: 1.0, Meta: meta, Skip: fmt.Errorf("oops")},
+			},
+		},
+		Profiles: []string{"default", "gpl"},
+		ProfilesData: map[string]*lib.ProfileData{
+			"default": nil,
+			"gpl": {
+				Licenses: []*licenses.License{gpl},
+				Severity: lib.SeverityError,
+			},
+		},
+		BackendWeights: map[interfaces.Backend]float64{
+			b1: 1.0,
+			b2: 1.0,
+		},
+	}
+
+	log, err := lib.BuildSARIFLog(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if len(log.Runs) != 1 {
+		t.Errorf("expected one run, got: %d", len(log.Runs))
+		return
+	}
+	run := log.Runs[0]
+
+	rules := []string{}
+	for _, x := range run.Tool.Driver.Rules {
+		rules = append(rules, x.ID+":"+x.DefaultConfiguration.Level)
+	}
+	if exp := []string{"profile/default:note", "profile/gpl:error", "license/unknown:warning", "scan/error:note"}; !reflect.DeepEqual(rules, exp) {
+		t.Errorf("rules: %v, exp: %v", rules, exp)
+	}
+
+	results := []string{}
+	for _, x := range run.Results {
+		results = append(results, fmt.Sprintf("%s:%s:%d:%s", x.Locations[0].PhysicalLocation.ArtifactLocation.URI, x.RuleID, x.RuleIndex, x.Level))
+	}
+	exp := []string{
+		"a.c:profile/default:0:note",
+		"a.c:profile/gpl:1:error",
+		"b.c:profile/default:0:note",
+		"b.c:license/unknown:2:warning",
+		"b.c:scan/error:3:note",
+	}
+	if !reflect.DeepEqual(results, exp) {
+		t.Errorf("results: %v, exp: %v", results, exp)
+	}
+
+	// The gpl result only points at the gpl regions, which are sorted and
+	// de-duplicated across backends.
+	regions := []string{}
+	for _, x := range run.Results[1].Locations {
+		r := x.PhysicalLocation.Region
+		regions = append(regions, fmt.Sprintf("%d-%d", r.StartLine, r.EndLine))
+	}
+	if exp := []string{"2-2", "7-9"}; !reflect.DeepEqual(regions, exp) {
+		t.Errorf("regions: %v, exp: %v", regions, exp)
+	}
+	if backends := run.Results[1].Properties["backends"]; !reflect.DeepEqual(backends, []string{"b1", "b2"}) {
+		t.Errorf("backends: %v", backends)
+	}
+	if r := run.Results[4].Locations[0].PhysicalLocation.Region; r != nil {
+		t.Errorf("expected no region for the scan error, got: %+v", r)
+	}
+	if msg := run.Results[4].Message.Text; msg != "Scan errors: b2: oops" {
+		t.Errorf("unexpected message: %s", msg)
+	}
+
+	s, err := lib.ReturnOutputSARIF(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if m["version"] != lib.SARIFVersion || m["$schema"] != lib.SARIFSchema {
+		t.Errorf("unexpected header: %v, %v", m["version"], m["$schema"])
+	}
+}
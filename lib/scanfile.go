@@ -0,0 +1,559 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/errwrap"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+const (
+	// ScanFileSchemaVersion is the version of the ScanFile structure below.
+	// It must be incremented whenever an incompatible change is made to it,
+	// so that we don't load a saved scan incorrectly.
+	ScanFileSchemaVersion = 1
+
+	// ScanFileIteratorUnknown is the kind of an iterator that can't be
+	// saved. Results from it are loaded without an iterator.
+	ScanFileIteratorUnknown = "unknown"
+)
+
+// ScanFile is the on-disk form of the full results of a scan. Unlike the Report,
+// it contains everything that is needed to rebuild the Output, including the
+// chain of iterators, so that it can be loaded and rendered again with any
+// profiles and output type without running any iterators or backends.
+type ScanFile struct {
+	// Schema is the ScanFileSchemaVersion that this was saved with.
+	Schema int `json:"schema"`
+
+	Program string `json:"program"`
+	Version string `json:"version"`
+
+	// Args are the input strings that were scanned.
+	Args []string `json:"args"`
+
+	// Backends is the map of backend names, and whether they were enabled.
+	Backends map[string]bool `json:"backends"`
+
+	// BackendWeights is the weight of each backend that returned a result.
+	BackendWeights map[string]float64 `json:"backend_weights"`
+
+	// Iterators is the list of iterators that the results were tagged with.
+	// Each parent is listed before its children.
+	Iterators []*ScanFileIterator `json:"iterators"`
+
+	// Results is the map of uid to backend name to result.
+	Results map[string]map[string]*ScanFileResult `json:"results"`
+
+	// Passes is the list of paths which were scanned without any results.
+	Passes []string `json:"passes"`
+
+	// Warnings is the map of path to iterator error string.
+	Warnings map[string]string `json:"warnings"`
+}
+
+// ScanFileIterator is the saved form of an iterator.
+type ScanFileIterator struct {
+	// ID is the number that results use to refer to this. They start at
+	// one.
+	ID int `json:"id"`
+
+	// Parent is the ID of the iterator that built this one, or zero.
+	Parent int `json:"parent,omitempty"`
+
+	// Kind is the type of iterator, such as "fs", "git" or "zip".
+	Kind string `json:"kind"`
+
+	// Name is the human readable representation of the iterator.
+	Name string `json:"name"`
+
+	// Path is the local path for the fs and archive iterators.
+	Path string `json:"path,omitempty"`
+
+	// URL is the url for the git and http iterators.
+	URL  string `json:"url,omitempty"`
+	Hash string `json:"hash,omitempty"`
+	Ref  string `json:"ref,omitempty"`
+	Rev  string `json:"rev,omitempty"`
+
+	// RootUID is the uid that an fs iterator generated for its own path.
+	// It is used to rebuild the uid function.
+	RootUID string `json:"root_uid,omitempty"`
+}
+
+// ScanFileResult is the saved form of a backend result.
+type ScanFileResult struct {
+	Licenses   []*ScanFileLicense `json:"licenses"`
+	Confidence float64            `json:"confidence"`
+	Skip       string             `json:"skip,omitempty"`
+	Regions    []*ScanFileRegion  `json:"regions,omitempty"`
+	Component  string             `json:"component,omitempty"`
+	Holders    []string           `json:"holders,omitempty"`
+
+	// Iterator is the ID of the iterator that this result came from, or
+	// zero if it's not known.
+	Iterator    int      `json:"iterator,omitempty"`
+	SHA1        string   `json:"sha1,omitempty"`
+	SHA256      string   `json:"sha256,omitempty"`
+	Copyrights  []string `json:"copyrights,omitempty"`
+	LicenseText string   `json:"license_text,omitempty"`
+
+	More []*ScanFileResult `json:"more,omitempty"`
+}
+
+// ScanFileLicense is the saved form of a license. It keeps all of the fields so
+// that licenses which aren't on the SPDX list load back identically.
+type ScanFileLicense struct {
+	SPDX   string `json:"spdx,omitempty"`
+	Origin string `json:"origin,omitempty"`
+	Custom string `json:"custom,omitempty"`
+
+	Or []*ScanFileLicense `json:"or,omitempty"`
+}
+
+// ScanFileRegion is the saved form of a region.
+type ScanFileRegion struct {
+	License   *ScanFileLicense `json:"license,omitempty"`
+	StartLine int64            `json:"start_line"`
+	EndLine   int64            `json:"end_line"`
+}
+
+// scanFileBackend stands in for a backend when a scan is loaded. It only has a
+// name, since the backends never run again.
+type scanFileBackend struct {
+	name string
+}
+
+// String returns the name of the backend.
+func (obj *scanFileBackend) String() string {
+	return obj.name
+}
+
+// WriteScanFile saves the full results of a scan to a file at this path.
+func WriteScanFile(path string, output *Output) error {
+	scanFile, err := BuildScanFile(output)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(scanFile)
+	if err != nil {
+		return err
+	}
+	// TODO: is this the umask we should use?
+	return os.WriteFile(path, append(b, '\n'), 0660)
+}
+
+// ReadScanFile loads a scan that was saved with WriteScanFile. The Output that
+// it returns only has the default profile. Use LoadProfiles to add others.
+func ReadScanFile(path string) (*Output, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scanFile := &ScanFile{}
+	if err := json.Unmarshal(b, scanFile); err != nil {
+		return nil, errwrap.Wrapf(err, "could not decode saved scan")
+	}
+	return scanFile.Output()
+}
+
+// BuildScanFile builds the on-disk form of the results of a scan.
+func BuildScanFile(output *Output) (*ScanFile, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	obj := &scanFileBuilder{
+		scanFile: &ScanFile{
+			Schema:         ScanFileSchemaVersion,
+			Program:        output.Program,
+			Version:        output.Version,
+			Args:           []string{},
+			Backends:       make(map[string]bool),
+			BackendWeights: make(map[string]float64),
+			Iterators:      []*ScanFileIterator{},
+			Results:        make(map[string]map[string]*ScanFileResult),
+			Passes:         []string{},
+			Warnings:       make(map[string]string),
+		},
+		ids: make(map[interfaces.Iterator]int),
+	}
+	scanFile := obj.scanFile
+	scanFile.Args = append(scanFile.Args, output.Args...)
+	for k, v := range output.Backends {
+		scanFile.Backends[k] = v
+	}
+	for k, v := range output.BackendWeights {
+		scanFile.BackendWeights[k.String()] = v
+	}
+	scanFile.Passes = append(scanFile.Passes, output.Passes...)
+	sort.Strings(scanFile.Passes)
+	for k, v := range output.Warnings {
+		scanFile.Warnings[k] = v.Error()
+	}
+
+	uids := []string{}
+	for uid := range output.Results {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids) // deterministic iterator ids
+
+	for _, uid := range uids {
+		m := output.Results[uid]
+		names := []string{}
+		byName := make(map[string]*interfaces.Result)
+		for backend, result := range m {
+			names = append(names, backend.String())
+			byName[backend.String()] = result
+		}
+		sort.Strings(names)
+
+		scanFile.Results[uid] = make(map[string]*ScanFileResult)
+		for _, name := range names {
+			if _, exists := scanFile.Results[uid][name]; exists {
+				return nil, fmt.Errorf("duplicate backend name: %s", name)
+			}
+			scanFile.Results[uid][name] = obj.result(byName[name])
+		}
+	}
+
+	return scanFile, nil
+}
+
+// scanFileBuilder holds the state needed while building a scan file.
+type scanFileBuilder struct {
+	scanFile *ScanFile
+	ids      map[interfaces.Iterator]int
+}
+
+// result converts a result into the saved form.
+func (obj *scanFileBuilder) result(result *interfaces.Result) *ScanFileResult {
+	r := &ScanFileResult{
+		Licenses:   []*ScanFileLicense{},
+		Confidence: result.Confidence,
+		Component:  result.Component,
+		Holders:    result.Holders,
+	}
+	for _, x := range result.Licenses {
+		r.Licenses = append(r.Licenses, scanFileLicense(x))
+	}
+	if result.Skip != nil {
+		r.Skip = result.Skip.Error()
+	}
+	for _, x := range result.Regions {
+		region := &ScanFileRegion{
+			StartLine: x.StartLine,
+			EndLine:   x.EndLine,
+		}
+		if x.License != nil {
+			region.License = scanFileLicense(x.License)
+		}
+		r.Regions = append(r.Regions, region)
+	}
+	if meta := result.Meta; meta != nil {
+		r.Iterator = obj.iterator(meta.Iterator)
+		r.SHA1 = meta.SHA1
+		r.SHA256 = meta.SHA256
+		r.Copyrights = meta.Copyrights
+		r.LicenseText = meta.LicenseText
+	}
+	for _, x := range result.More {
+		r.More = append(r.More, obj.result(x))
+	}
+	return r
+}
+
+// iterator returns the id of an iterator, saving it and its parents first if
+// they haven't been already.
+func (obj *scanFileBuilder) iterator(it interfaces.Iterator) int {
+	if it == nil {
+		return 0
+	}
+	if id, exists := obj.ids[it]; exists {
+		return id
+	}
+	parent := obj.iterator(it.GetIterator())
+
+	x := &ScanFileIterator{
+		ID:     len(obj.scanFile.Iterators) + 1,
+		Parent: parent,
+		Kind:   ScanFileIteratorUnknown,
+		Name:   it.String(),
+	}
+	switch i := it.(type) {
+	case *iterator.Fs:
+		x.Kind = ProvenanceFs
+		x.Path = i.Path.String()
+		if i.GenUID != nil {
+			if uid, err := i.GenUID(i.Path); err == nil {
+				x.RootUID = uid
+			}
+		}
+	case *iterator.Git:
+		x.Kind = ProvenanceGit
+		x.URL = i.URL
+		x.Hash = i.Hash
+		x.Ref = i.Ref
+		x.Rev = i.Rev
+	case *iterator.Http:
+		x.Kind = ProvenanceHttp
+		x.URL = i.URL
+	case *iterator.Zip:
+		x.Kind = "zip"
+		x.Path = i.Path.String()
+	case *iterator.Tar:
+		x.Kind = "tar"
+		x.Path = i.Path.String()
+	case *iterator.Gzip:
+		x.Kind = "gzip"
+		x.Path = i.Path.String()
+	case *iterator.Bzip2:
+		x.Kind = "bzip2"
+		x.Path = i.Path.String()
+	}
+
+	obj.ids[it] = x.ID
+	obj.scanFile.Iterators = append(obj.scanFile.Iterators, x)
+	return x.ID
+}
+
+// Output rebuilds the Output from the saved scan. It only has the default
+// profile. Use LoadProfiles to add others.
+func (obj *ScanFile) Output() (*Output, error) {
+	if obj.Schema != ScanFileSchemaVersion {
+		return nil, fmt.Errorf("unsupported saved scan schema: %d", obj.Schema)
+	}
+
+	iterators := make(map[int]interfaces.Iterator)
+	for _, x := range obj.Iterators {
+		if x.Parent >= x.ID {
+			return nil, fmt.Errorf("iterator %d is listed before its parent", x.ID)
+		}
+		it, err := scanFileIterator(x, iterators[x.Parent])
+		if err != nil {
+			return nil, errwrap.Wrapf(err, "could not load iterator %d", x.ID)
+		}
+		if it != nil {
+			iterators[x.ID] = it
+		}
+	}
+
+	backends := make(map[string]interfaces.Backend)
+	backendWeights := make(map[interfaces.Backend]float64)
+	getBackend := func(name string) interfaces.Backend {
+		if backend, exists := backends[name]; exists {
+			return backend
+		}
+		backend := &scanFileBackend{name: name}
+		backends[name] = backend
+		backendWeights[backend] = obj.BackendWeights[name]
+		return backend
+	}
+	for name := range obj.BackendWeights {
+		getBackend(name)
+	}
+
+	results := make(interfaces.ResultSet)
+	for uid, m := range obj.Results {
+		results[uid] = make(map[interfaces.Backend]*interfaces.Result)
+		for name, r := range m {
+			backend := getBackend(name)
+			results[uid][backend] = scanFileOutputResult(r, backend, iterators)
+		}
+	}
+
+	warnings := make(map[string]error)
+	for k, v := range obj.Warnings {
+		warnings[k] = fmt.Errorf("%s", v)
+	}
+
+	output := &Output{
+		Program:        obj.Program,
+		Version:        obj.Version,
+		Args:           []string{},
+		Backends:       make(map[string]bool),
+		Results:        results,
+		Passes:         []string{},
+		Warnings:       warnings,
+		Profiles:       []string{DefaultProfileName},
+		ProfilesData:   map[string]*ProfileData{DefaultProfileName: nil},
+		BackendWeights: backendWeights,
+	}
+	output.Args = append(output.Args, obj.Args...)
+	output.Passes = append(output.Passes, obj.Passes...)
+	for k, v := range obj.Backends {
+		output.Backends[k] = v
+	}
+
+	return output, nil
+}
+
+// scanFileIterator rebuilds an iterator from the saved form. It returns nil for
+// the unknown kind. The iterators are never run, they are only used for their
+// fields.
+func scanFileIterator(x *ScanFileIterator, parent interfaces.Iterator) (interfaces.Iterator, error) {
+	archive := func() (safepath.AbsFile, error) {
+		return safepath.ParseIntoAbsFile(x.Path)
+	}
+
+	switch x.Kind {
+	case ProvenanceFs:
+		p, err := safepath.ParseIntoPath(x.Path, strings.HasSuffix(x.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+		fs := &iterator.Fs{
+			Iterator: parent,
+			Path:     p,
+		}
+		if x.RootUID != "" {
+			fs.GenUID = scanFileGenUID(p, x.RootUID)
+		}
+		return fs, nil
+
+	case ProvenanceGit:
+		return &iterator.Git{
+			Iterator: parent,
+			URL:      x.URL,
+			Hash:     x.Hash,
+			Ref:      x.Ref,
+			Rev:      x.Rev,
+		}, nil
+
+	case ProvenanceHttp:
+		return &iterator.Http{
+			Iterator: parent,
+			URL:      x.URL,
+		}, nil
+
+	case "zip":
+		p, err := archive()
+		return &iterator.Zip{Iterator: parent, Path: p}, err
+	case "tar":
+		p, err := archive()
+		return &iterator.Tar{Iterator: parent, Path: p}, err
+	case "gzip":
+		p, err := archive()
+		return &iterator.Gzip{Iterator: parent, Path: p}, err
+	case "bzip2":
+		p, err := archive()
+		return &iterator.Bzip2{Iterator: parent, Path: p}, err
+	}
+
+	return nil, nil // unknown
+}
+
+// scanFileGenUID rebuilds the uid function of an fs iterator from the uid that
+// it generated for its own path. The uid of any path below that is the root uid
+// with the relative path appended before any query string.
+func scanFileGenUID(root safepath.Path, rootUID string) func(safepath.Path) (string, error) {
+	base, query := rootUID, ""
+	if ix := strings.Index(rootUID, "?"); ix > -1 {
+		base, query = rootUID[:ix], rootUID[ix:]
+	}
+	return func(p safepath.Path) (string, error) {
+		if !strings.HasPrefix(p.String(), root.String()) {
+			// programming error
+			return "", fmt.Errorf("path doesn't have prefix")
+		}
+		rel := strings.TrimPrefix(p.String(), root.String())
+		if rel != "" && !strings.HasSuffix(base, "/") {
+			rel = "/" + rel
+		}
+		return base + rel + query, nil
+	}
+}
+
+// scanFileOutputResult rebuilds a result from the saved form.
+func scanFileOutputResult(r *ScanFileResult, backend interfaces.Backend, iterators map[int]interfaces.Iterator) *interfaces.Result {
+	result := &interfaces.Result{
+		Licenses:   []*licenses.License{},
+		Confidence: r.Confidence,
+		Component:  r.Component,
+		Holders:    r.Holders,
+		Meta: &interfaces.Meta{
+			Iterator:    iterators[r.Iterator],
+			Backend:     backend,
+			SHA1:        r.SHA1,
+			SHA256:      r.SHA256,
+			Copyrights:  r.Copyrights,
+			LicenseText: r.LicenseText,
+		},
+	}
+	for _, x := range r.Licenses {
+		result.Licenses = append(result.Licenses, x.license())
+	}
+	if r.Skip != "" {
+		result.Skip = fmt.Errorf("%s", r.Skip)
+	}
+	for _, x := range r.Regions {
+		region := &interfaces.Region{
+			StartLine: x.StartLine,
+			EndLine:   x.EndLine,
+		}
+		if x.License != nil {
+			region.License = x.License.license()
+		}
+		result.Regions = append(result.Regions, region)
+	}
+	for _, x := range r.More {
+		result.More = append(result.More, scanFileOutputResult(x, backend, iterators))
+	}
+	return result
+}
+
+// scanFileLicense converts a license into the saved form.
+func scanFileLicense(license *licenses.License) *ScanFileLicense {
+	l := &ScanFileLicense{
+		SPDX:   license.SPDX,
+		Origin: license.Origin,
+		Custom: license.Custom,
+	}
+	for _, x := range license.Or {
+		l.Or = append(l.Or, scanFileLicense(x))
+	}
+	return l
+}
+
+// license converts the saved form back into a license.
+func (obj *ScanFileLicense) license() *licenses.License {
+	license := &licenses.License{
+		SPDX:   obj.SPDX,
+		Origin: obj.Origin,
+		Custom: obj.Custom,
+	}
+	for _, x := range obj.Or {
+		license.Or = append(license.Or, x.license())
+	}
+	return license
+}
@@ -0,0 +1,139 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+	"github.com/awslabs/yesiscan/util/safepath"
+)
+
+func TestScanFileRoundTrip(t *testing.T) {
+	hash := "0123456789abcdef0123456789abcdef01234567"
+	git := &iterator.Git{
+		URL:  "https://example.com/project.git",
+		Hash: hash,
+	}
+	repoAbsDir, err := safepath.ParseIntoAbsDir("/tmp/repo/")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	fs := &iterator.Fs{
+		Iterator: git,
+		Path:     repoAbsDir,
+		GenUID: func(p safepath.Path) (string, error) {
+			rel := strings.TrimPrefix(p.String(), repoAbsDir.String())
+			return "git://example.com/project.git/" + rel + "?sha1=" + hash, nil
+		},
+	}
+
+	b1 := &testBackend{name: "b1"}
+	b2 := &testBackend{name: "b2"}
+	meta := &interfaces.Meta{
+		Iterator:   fs,
+		SHA1:       "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+		SHA256:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		Copyrights: []string{"Copyright (c) 2023 Alice"},
+	}
+	mit := &licenses.License{SPDX: "MIT"}
+	custom := &licenses.License{Custom: "Weird License"}
+	output := &lib.Output{
+		Program:  "yesiscan",
+		Version:  "test",
+		Args:     []string{"https://example.com/project.git"},
+		Backends: map[string]bool{"b1": true, "b2": true},
+		Results: interfaces.ResultSet{
+			"git://example.com/project.git/src/main.c?sha1=" + hash: {
+				b1: {
+					Licenses:   []*licenses.License{mit},
+					Confidence: 1.0,
+					Regions:    []*interfaces.Region{{License: mit, StartLine: 1, EndLine: 1}},
+					Meta:       meta,
+				},
+				b2: {Licenses: []*licenses.License{custom}, Confidence: 0.5, Meta: meta},
+			},
+		},
+		Passes:   []string{"git://example.com/project.git/README?sha1=" + hash},
+		Profiles: []string{lib.DefaultProfileName},
+		ProfilesData: map[string]*lib.ProfileData{
+			lib.DefaultProfileName: nil,
+		},
+		BackendWeights: map[interfaces.Backend]float64{
+			b1: 1.0,
+			b2: 2.0,
+		},
+	}
+
+	scanFile, err := lib.BuildScanFile(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	b, err := json.Marshal(scanFile)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	loadedFile := &lib.ScanFile{}
+	if err := json.Unmarshal(b, loadedFile); err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	loaded, err := loadedFile.Output()
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+
+	// the rendered outputs should be identical, including the provenance
+	for name, render := range map[string]func(*lib.Output) (string, error){
+		"json":      lib.ReturnOutputJSON,
+		"spdx-json": lib.ReturnOutputSPDXJSON,
+		"sarif":     lib.ReturnOutputSARIF,
+		"notice":    lib.ReturnOutputNotice,
+	} {
+		exp, err := render(output)
+		if err != nil {
+			t.Errorf("%s: err: %+v", name, err)
+			continue
+		}
+		got, err := render(loaded)
+		if err != nil {
+			t.Errorf("%s: err: %+v", name, err)
+			continue
+		}
+		if got != exp {
+			t.Errorf("%s: rendered output of the loaded scan differs", name)
+			t.Logf("exp: %s", exp)
+			t.Logf("got: %s", got)
+		}
+	}
+}
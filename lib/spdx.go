@@ -0,0 +1,557 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/iterator"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+const (
+	// SPDXVersion is the version of the SPDX specification that we output.
+	SPDXVersion = "SPDX-2.3"
+
+	// SPDXDataLicense is the license of the SPDX document itself. The spec
+	// requires that this is always CC0-1.0.
+	SPDXDataLicense = "CC0-1.0"
+
+	// SPDXNoAssertion is the special value used when we can't say anything.
+	SPDXNoAssertion = "NOASSERTION"
+
+	// SPDXDocumentID is the SPDX identifier of the document element.
+	SPDXDocumentID = "SPDXRef-DOCUMENT"
+
+	// SPDXNamespacePrefix is the prefix of the generated document namespace.
+	SPDXNamespacePrefix = "https://spdx.org/spdxdocs/"
+
+	// SPDXLicenseRefPrefix is the prefix used for any license that isn't
+	// on the SPDX license list.
+	SPDXLicenseRefPrefix = "LicenseRef-"
+)
+
+// SPDXDocument is an SPDX 2.3 document. The field names and json tags match the
+// official SPDX json schema, and the same struct is used to produce the
+// tag-value format.
+type SPDXDocument struct {
+	SPDXVersion       string            `json:"spdxVersion"`
+	DataLicense       string            `json:"dataLicense"`
+	SPDXID            string            `json:"SPDXID"`
+	Name              string            `json:"name"`
+	DocumentNamespace string            `json:"documentNamespace"`
+	CreationInfo      *SPDXCreationInfo `json:"creationInfo"`
+
+	Packages                   []*SPDXPackage          `json:"packages"`
+	Files                      []*SPDXFile             `json:"files"`
+	HasExtractedLicensingInfos []*SPDXExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+	Relationships              []*SPDXRelationship     `json:"relationships"`
+}
+
+// SPDXCreationInfo is the creation information section of an SPDX document.
+type SPDXCreationInfo struct {
+	Created            string   `json:"created"`
+	Creators           []string `json:"creators"`
+	LicenseListVersion string   `json:"licenseListVersion,omitempty"`
+}
+
+// SPDXPackage is a package element. We build one for each of the inputs that
+// we scanned, such as a git repository, a downloaded url or an archive.
+type SPDXPackage struct {
+	SPDXID                  string                   `json:"SPDXID"`
+	Name                    string                   `json:"name"`
+	VersionInfo             string                   `json:"versionInfo,omitempty"`
+	PackageFileName         string                   `json:"packageFileName,omitempty"`
+	DownloadLocation        string                   `json:"downloadLocation"`
+	FilesAnalyzed           bool                     `json:"filesAnalyzed"`
+	PackageVerificationCode *SPDXPackageVerification `json:"packageVerificationCode,omitempty"`
+	LicenseConcluded        string                   `json:"licenseConcluded"`
+	LicenseDeclared         string                   `json:"licenseDeclared"`
+	LicenseInfoFromFiles    []string                 `json:"licenseInfoFromFiles,omitempty"`
+	CopyrightText           string                   `json:"copyrightText"`
+	Comment                 string                   `json:"comment,omitempty"`
+
+	// files is the list of files directly contained in this package. It
+	// is used to build the verification code and the tag-value output.
+	files []*SPDXFile
+}
+
+// SPDXPackageVerification is the package verification code of a package.
+type SPDXPackageVerification struct {
+	Value string `json:"packageVerificationCodeValue"`
+}
+
+// SPDXFile is a file element. We build one for each scanned file that returned
+// a result.
+type SPDXFile struct {
+	SPDXID             string          `json:"SPDXID"`
+	FileName           string          `json:"fileName"`
+	Checksums          []*SPDXChecksum `json:"checksums"`
+	LicenseConcluded   string          `json:"licenseConcluded"`
+	LicenseInfoInFiles []string        `json:"licenseInfoInFiles"`
+	CopyrightText      string          `json:"copyrightText"`
+	Comment            string          `json:"comment,omitempty"`
+}
+
+// SPDXChecksum is a checksum of a file.
+type SPDXChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+// SPDXExtractedLicense is a license which isn't on the SPDX license list. We
+// don't have the text of these, since the backends only give us a name.
+type SPDXExtractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name,omitempty"`
+}
+
+// SPDXRelationship is a relationship between two elements.
+type SPDXRelationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// ReturnOutputSPDX returns a string of output, formatted as an SPDX document in
+// the tag-value format.
+func ReturnOutputSPDX(output *Output) (string, error) {
+	doc, err := BuildSPDXDocument(output)
+	if err != nil {
+		return "", err
+	}
+	return doc.TagValue(), nil
+}
+
+// ReturnOutputSPDXJSON returns a string of output, formatted as an SPDX
+// document in the json format.
+func ReturnOutputSPDXJSON(output *Output) (string, error) {
+	doc, err := BuildSPDXDocument(output)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	return string(b) + "\n", nil
+}
+
+// BuildSPDXDocument builds an SPDX document from an Output. Each scanned file
+// becomes a file element with the licenses that any backend found listed in
+// the LicenseInfoInFile field, and the license that the backends agreed on most
+// strongly, after weighting, as the concluded license. Each of the inputs that
+// we scanned becomes a package, and nested inputs such as an archive inside a
+// git repository are contained by the package they were found in. Directories
+// are not files in SPDX, so any results for those are not included.
+func BuildSPDXDocument(output *Output) (*SPDXDocument, error) {
+	if output == nil {
+		return nil, fmt.Errorf("got nil output")
+	}
+
+	obj := &spdxBuilder{
+		output: output,
+		refs:   make(map[string]*SPDXExtractedLicense),
+		doc: &SPDXDocument{
+			SPDXVersion:   SPDXVersion,
+			DataLicense:   SPDXDataLicense,
+			SPDXID:        SPDXDocumentID,
+			Name:          fmt.Sprintf("%s %s", output.Program, strings.Join(output.Args, " ")),
+			Packages:      []*SPDXPackage{},
+			Files:         []*SPDXFile{},
+			Relationships: []*SPDXRelationship{},
+		},
+	}
+	if err := obj.build(); err != nil {
+		return nil, err
+	}
+	doc := obj.doc
+
+	// Build a namespace from the contents so that it's unique per scan,
+	// and the same if the same things are scanned with the same results.
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	doc.DocumentNamespace = fmt.Sprintf("%s%s-%s", SPDXNamespacePrefix, output.Program, hex.EncodeToString(sum[:]))
+
+	doc.CreationInfo = &SPDXCreationInfo{
+		Created: time.Now().UTC().Format(time.RFC3339),
+		Creators: []string{
+			fmt.Sprintf("Tool: %s-%s", output.Program, output.Version),
+		},
+		LicenseListVersion: licenses.LicenseList.Version,
+	}
+
+	return doc, nil
+}
+
+// TagValue returns the document in the SPDX tag-value format. Each package is
+// followed by the files that it directly contains.
+func (obj *SPDXDocument) TagValue() string {
+	s := ""
+	s += fmt.Sprintf("SPDXVersion: %s\n", obj.SPDXVersion)
+	s += fmt.Sprintf("DataLicense: %s\n", obj.DataLicense)
+	s += fmt.Sprintf("SPDXID: %s\n", obj.SPDXID)
+	s += fmt.Sprintf("DocumentName: %s\n", obj.Name)
+	s += fmt.Sprintf("DocumentNamespace: %s\n", obj.DocumentNamespace)
+	if info := obj.CreationInfo; info != nil {
+		if info.LicenseListVersion != "" {
+			s += fmt.Sprintf("LicenseListVersion: %s\n", info.LicenseListVersion)
+		}
+		for _, x := range info.Creators {
+			s += fmt.Sprintf("Creator: %s\n", x)
+		}
+		s += fmt.Sprintf("Created: %s\n", info.Created)
+	}
+
+	for _, pkg := range obj.Packages {
+		s += "\n"
+		s += fmt.Sprintf("PackageName: %s\n", pkg.Name)
+		s += fmt.Sprintf("SPDXID: %s\n", pkg.SPDXID)
+		if pkg.VersionInfo != "" {
+			s += fmt.Sprintf("PackageVersion: %s\n", pkg.VersionInfo)
+		}
+		if pkg.PackageFileName != "" {
+			s += fmt.Sprintf("PackageFileName: %s\n", pkg.PackageFileName)
+		}
+		s += fmt.Sprintf("PackageDownloadLocation: %s\n", pkg.DownloadLocation)
+		s += fmt.Sprintf("FilesAnalyzed: %t\n", pkg.FilesAnalyzed)
+		if pkg.PackageVerificationCode != nil {
+			s += fmt.Sprintf("PackageVerificationCode: %s\n", pkg.PackageVerificationCode.Value)
+		}
+		s += fmt.Sprintf("PackageLicenseConcluded: %s\n", pkg.LicenseConcluded)
+		for _, x := range pkg.LicenseInfoFromFiles {
+			s += fmt.Sprintf("PackageLicenseInfoFromFiles: %s\n", x)
+		}
+		s += fmt.Sprintf("PackageLicenseDeclared: %s\n", pkg.LicenseDeclared)
+		s += fmt.Sprintf("PackageCopyrightText: %s\n", pkg.CopyrightText)
+		if pkg.Comment != "" {
+			s += fmt.Sprintf("PackageComment: <text>%s</text>\n", pkg.Comment)
+		}
+
+		for _, file := range pkg.files {
+			s += "\n"
+			s += fmt.Sprintf("FileName: %s\n", file.FileName)
+			s += fmt.Sprintf("SPDXID: %s\n", file.SPDXID)
+			for _, x := range file.Checksums {
+				s += fmt.Sprintf("FileChecksum: %s: %s\n", x.Algorithm, x.Value)
+			}
+			s += fmt.Sprintf("LicenseConcluded: %s\n", file.LicenseConcluded)
+			for _, x := range file.LicenseInfoInFiles {
+				s += fmt.Sprintf("LicenseInfoInFile: %s\n", x)
+			}
+			s += fmt.Sprintf("FileCopyrightText: %s\n", file.CopyrightText)
+			if file.Comment != "" {
+				s += fmt.Sprintf("FileComment: <text>%s</text>\n", file.Comment)
+			}
+		}
+	}
+
+	if len(obj.Relationships) > 0 {
+		s += "\n"
+	}
+	for _, x := range obj.Relationships {
+		s += fmt.Sprintf("Relationship: %s %s %s\n", x.Element, x.Type, x.Related)
+	}
+
+	for _, x := range obj.HasExtractedLicensingInfos {
+		s += "\n"
+		s += fmt.Sprintf("LicenseID: %s\n", x.LicenseID)
+		s += fmt.Sprintf("ExtractedText: <text>%s</text>\n", x.ExtractedText)
+		if x.Name != "" {
+			s += fmt.Sprintf("LicenseName: %s\n", x.Name)
+		}
+	}
+
+	return s
+}
+
+// spdxBuilder holds the state needed while building an SPDX document.
+type spdxBuilder struct {
+	output *Output
+	doc    *SPDXDocument
+
+	// refs stores the extracted license for each LicenseRef identifier.
+	refs map[string]*SPDXExtractedLicense
+}
+
+// build adds all of the packages, files and relationships to the document.
+func (obj *spdxBuilder) build() error {
+	packages := make(map[*provenance]*SPDXPackage)
+
+	for _, p := range buildProvenance(obj.output) {
+		pkg := &SPDXPackage{
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", len(obj.doc.Packages)+1),
+			Name:             p.name,
+			VersionInfo:      p.version,
+			PackageFileName:  p.fileName,
+			DownloadLocation: SPDXNoAssertion,
+			LicenseConcluded: SPDXNoAssertion,
+			LicenseDeclared:  SPDXNoAssertion,
+			CopyrightText:    SPDXNoAssertion,
+			Comment:          p.comment,
+			files:            []*SPDXFile{},
+		}
+		if p.url != "" {
+			pkg.DownloadLocation = spdxDownloadLocation(p)
+		}
+		packages[p] = pkg
+		obj.doc.Packages = append(obj.doc.Packages, pkg)
+
+		// nested inputs are contained in the package they were found in
+		if p.parent == nil {
+			obj.relate(SPDXDocumentID, "DESCRIBES", pkg.SPDXID)
+		} else {
+			obj.relate(packages[p.parent].SPDXID, "CONTAINS", pkg.SPDXID)
+		}
+
+		for _, f := range p.files {
+			file, err := obj.file(f)
+			if err != nil {
+				return err
+			}
+			obj.doc.Files = append(obj.doc.Files, file)
+			pkg.files = append(pkg.files, file)
+			obj.relate(pkg.SPDXID, "CONTAINS", file.SPDXID)
+		}
+
+		if len(pkg.files) == 0 {
+			continue // only contains other packages
+		}
+
+		pkg.FilesAnalyzed = true
+		found := make(map[string]struct{})
+		sums := []string{}
+		for _, file := range pkg.files {
+			sums = append(sums, file.Checksums[0].Value) // SHA1
+			for _, x := range file.LicenseInfoInFiles {
+				if _, exists := found[x]; exists {
+					continue
+				}
+				found[x] = struct{}{}
+				pkg.LicenseInfoFromFiles = append(pkg.LicenseInfoFromFiles, x)
+			}
+		}
+		sort.Strings(pkg.LicenseInfoFromFiles)
+
+		// this is the algorithm from the SPDX spec
+		sort.Strings(sums)
+		sum := sha1.Sum([]byte(strings.Join(sums, "")))
+		pkg.PackageVerificationCode = &SPDXPackageVerification{
+			Value: hex.EncodeToString(sum[:]),
+		}
+	}
+
+	ids := []string{}
+	for id := range obj.refs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		obj.doc.HasExtractedLicensingInfos = append(obj.doc.HasExtractedLicensingInfos, obj.refs[id])
+	}
+
+	return nil
+}
+
+// file builds the file element for a scanned file.
+func (obj *spdxBuilder) file(f *provenanceFile) (*SPDXFile, error) {
+	infos := []string{}
+	found := make(map[string]struct{})
+	for _, result := range f.results {
+		for _, license := range result.Licenses {
+			id := obj.licenseID(license)
+			if _, exists := found[id]; exists {
+				continue
+			}
+			found[id] = struct{}{}
+			infos = append(infos, id)
+		}
+	}
+	sort.Strings(infos)
+	if len(infos) == 0 {
+		infos = append(infos, SPDXNoAssertion)
+	}
+
+	concluded, err := ConsensusLicenses(f.results, obj.output.BackendWeights)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, license := range concluded {
+		ids = append(ids, obj.licenseID(license))
+	}
+	expression := strings.Join(ids, " AND ")
+	if expression == "" {
+		expression = SPDXNoAssertion
+	}
+
+	return &SPDXFile{
+		SPDXID:   fmt.Sprintf("SPDXRef-File-%d", len(obj.doc.Files)+1),
+		FileName: f.name,
+		Checksums: []*SPDXChecksum{
+			{Algorithm: "SHA1", Value: f.sha1},
+			{Algorithm: "SHA256", Value: f.sha256},
+		},
+		LicenseConcluded:   expression,
+		LicenseInfoInFiles: infos,
+		CopyrightText:      SPDXNoAssertion,
+		Comment:            f.uid,
+	}, nil
+}
+
+// relate adds a relationship to the document.
+func (obj *spdxBuilder) relate(element, typ, related string) {
+	obj.doc.Relationships = append(obj.doc.Relationships, &SPDXRelationship{
+		Element: element,
+		Type:    typ,
+		Related: related,
+	})
+}
+
+// licenseID returns the SPDX identifier to use for a license. Any LicenseRef
+// identifiers are also added to the list of extracted licenses.
+func (obj *spdxBuilder) licenseID(license *licenses.License) string {
+	if len(license.Or) > 0 {
+		for _, x := range license.Or {
+			obj.licenseID(x)
+		}
+		return SPDXLicenseID(license)
+	}
+	id := SPDXLicenseID(license)
+	if !strings.HasPrefix(id, SPDXLicenseRefPrefix) {
+		return id
+	}
+	if _, exists := obj.refs[id]; !exists {
+		obj.refs[id] = &SPDXExtractedLicense{
+			LicenseID:     id,
+			ExtractedText: SPDXNoAssertion, // we only have the name
+			Name:          license.String(),
+		}
+	}
+	return id
+}
+
+// SPDXLicenseID returns the SPDX identifier to use for a license in a license
+// expression. Licenses which aren't on the SPDX license list get a LicenseRef
+// identifier which is built from their name.
+func SPDXLicenseID(license *licenses.License) string {
+	if len(license.Or) > 0 {
+		ids := []string{}
+		for _, x := range license.Or {
+			ids = append(ids, SPDXLicenseID(x))
+		}
+		return "(" + strings.Join(ids, " OR ") + ")"
+	}
+	if license.SPDX != "" && license.Validate() == nil {
+		return license.SPDX
+	}
+	return SPDXLicenseRefPrefix + spdxIDString(license.String())
+}
+
+// ConsensusLicenses returns the list of licenses that the backends agree on
+// the most for a single path. Each backend votes for the exact set of licenses
+// that it found, with its weighted confidence. Backends that errored or that
+// found nothing don't vote. Ties are broken by the license names. If nobody
+// voted, then this returns an empty list.
+func ConsensusLicenses(m map[interfaces.Backend]*interfaces.Result, backendWeights map[interfaces.Backend]float64) ([]*licenses.License, error) {
+	bs, _, err := annotateBackends(m, backendWeights)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make(map[string]float64)
+	sets := make(map[string][]*licenses.License)
+	for _, b := range bs {
+		result := m[b.Backend]
+		if result.Skip != nil || len(result.Licenses) == 0 {
+			continue
+		}
+		ls := []*licenses.License{}
+		ls = append(ls, result.Licenses...)
+		sort.Slice(ls, func(i, j int) bool {
+			return ls[i].String() < ls[j].String()
+		})
+		key := licenses.Join(ls)
+		votes[key] += b.ScaledConfidence
+		sets[key] = ls
+	}
+
+	best := ""
+	for key, vote := range votes {
+		if _, exists := votes[best]; !exists || vote > votes[best] || (vote == votes[best] && key < best) {
+			best = key
+		}
+	}
+	if _, exists := sets[best]; !exists {
+		return []*licenses.License{}, nil
+	}
+	return sets[best], nil
+}
+
+// spdxDownloadLocation returns the download location of a package in the form
+// that the SPDX spec wants. Git repositories are given in the vcs form.
+func spdxDownloadLocation(p *provenance) string {
+	if p.kind != ProvenanceGit {
+		return p.url
+	}
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return SPDXNoAssertion
+	}
+	scheme := u.Scheme
+	if !strings.HasPrefix(scheme, iterator.GitSchemeRaw) {
+		scheme = iterator.GitSchemeRaw + "+" + scheme
+	}
+	s := fmt.Sprintf("%s://%s%s", scheme, u.Host, u.EscapedPath())
+	if p.version != "" {
+		s += "@" + p.version
+	}
+	return s
+}
+
+// spdxIDString replaces every character which isn't allowed in an SPDX
+// identifier with a dash.
+func spdxIDString(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
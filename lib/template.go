@@ -0,0 +1,215 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	htmlTemplate "html/template"
+	"strings"
+	textTemplate "text/template"
+
+	"github.com/awslabs/yesiscan/util"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+// TemplateData is the data model that is passed to user output templates. It
+// embeds the Report, so all of its fields such as .Files, .Profiles, .Skipped,
+// .Errors, .Warnings and .Summary are available at the top-level. Please see
+// the Report struct for the documentation of each of those.
+type TemplateData struct {
+	*Report
+
+	// Severities is the severity of each profile, keyed by profile name.
+	Severities map[string]string
+
+	// Provenance is the list of inputs that the scanned files came from,
+	// with each parent before its children.
+	Provenance []*TemplateProvenance
+}
+
+// TemplateProvenance is one of the inputs that was scanned, such as a git
+// repository at a specific hash, a downloaded url, an archive, or a local path.
+type TemplateProvenance struct {
+	// Kind is one of the Provenance* constants, such as "git".
+	Kind string
+
+	Name string
+
+	// Version is the git hash if this is a git repository.
+	Version string
+
+	// URL is where this can be downloaded from, without any credentials.
+	URL string
+
+	// Comment is a human readable description of where this came from.
+	Comment string
+
+	// Parent is the name of the provenance that this was found in. It is
+	// empty if this was one of the original inputs.
+	Parent string
+
+	// Files is the list of files that directly belong to this, sorted by
+	// uid. Directories are not included.
+	Files []*TemplateProvenanceFile
+}
+
+// TemplateProvenanceFile is a scanned file from a provenance.
+type TemplateProvenanceFile struct {
+	// UID is the unique identifier of the file, as used in Report.Files.
+	UID string
+
+	// Path is the path of the file relative to the root of the original
+	// input, including the paths of any archives that it's nested inside.
+	Path string
+}
+
+// ProfileFiles returns the list of files that matched the named profile.
+func (obj *TemplateData) ProfileFiles(name string) []*ReportFile {
+	files := []*ReportFile{}
+	for _, file := range obj.Files {
+		if util.StrInList(name, file.Profiles) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// BuildTemplateData builds the data model for user output templates.
+func BuildTemplateData(output *Output) (*TemplateData, error) {
+	report, err := BuildReport(output)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &TemplateData{
+		Report:     report,
+		Severities: make(map[string]string),
+		Provenance: []*TemplateProvenance{},
+	}
+	for _, name := range output.Profiles {
+		data.Severities[name] = ProfileSeverity(output.ProfilesData[name])
+	}
+
+	for _, p := range buildProvenance(output) {
+		x := &TemplateProvenance{
+			Kind:    p.kind,
+			Name:    p.name,
+			Version: p.version,
+			URL:     p.url,
+			Comment: p.comment,
+			Files:   []*TemplateProvenanceFile{},
+		}
+		if p.parent != nil {
+			x.Parent = p.parent.name
+		}
+		for _, f := range p.files {
+			x.Files = append(x.Files, &TemplateProvenanceFile{
+				UID:  f.uid,
+				Path: provenancePath(p, f),
+			})
+		}
+		data.Provenance = append(data.Provenance, x)
+	}
+
+	return data, nil
+}
+
+// TemplateFuncs returns the helper functions that are available in user output
+// templates in addition to the built-in ones.
+func TemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		// smartURI returns a (hopefully) clickable version of a uid.
+		"smartURI": util.SmartURI,
+
+		// licenseJoin joins a list of license names with commas.
+		"licenseJoin": func(ls []string) string {
+			return strings.Join(ls, ", ")
+		},
+
+		// spdxID returns the SPDX identifier to use for a license name in
+		// a license expression.
+		"spdxID": func(name string) (string, error) {
+			license, err := licenses.StringToLicense(name)
+			if err != nil {
+				return "", err
+			}
+			return SPDXLicenseID(license), nil
+		},
+
+		// join joins a list of strings with a separator.
+		"join": func(sep string, xs []string) string {
+			return strings.Join(xs, sep)
+		},
+
+		// percent formats a ratio such as a confidence as a percentage.
+		"percent": func(f float64) string {
+			return fmt.Sprintf("%.2f%%", f*100.0)
+		},
+	}
+}
+
+// OutputTemplate is a user-provided template for rendering a report.
+type OutputTemplate struct {
+	text *textTemplate.Template
+	html *htmlTemplate.Template
+}
+
+// ParseOutputTemplate parses a user-provided template. If isHtml is true, then
+// this is parsed as an html/template, which escapes all of the values that it
+// outputs, otherwise it is parsed as a text/template. The data passed to the
+// template is a TemplateData, and the TemplateFuncs are available. The name is
+// only used in error messages.
+func ParseOutputTemplate(name, data string, isHtml bool) (*OutputTemplate, error) {
+	obj := &OutputTemplate{}
+	var err error
+	if isHtml {
+		obj.html, err = htmlTemplate.New(name).Funcs(TemplateFuncs()).Parse(data)
+	} else {
+		obj.text, err = textTemplate.New(name).Funcs(TemplateFuncs()).Parse(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Render renders the template with the data model built from the output.
+func (obj *OutputTemplate) Render(output *Output) (string, error) {
+	data, err := BuildTemplateData(output)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if obj.html != nil {
+		err = obj.html.Execute(buf, data)
+	} else {
+		err = obj.text.Execute(buf, data)
+	}
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,69 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/awslabs/yesiscan/lib"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	text := `{{ range .Profiles }}{{ .Name }}: {{ range $.ProfileFiles .Name }}{{ licenseJoin .Licenses }} {{ percent .Confidence }};{{ end }}{{ end }}`
+
+	tmpl, err := lib.ParseOutputTemplate("test", text, false)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	s, err := tmpl.Render(testOutput())
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if exp := "default: MIT 62.50%; 100.00%;GPL-2.0-only 100.00%;gpl: GPL-2.0-only 100.00%;"; s != exp {
+		t.Errorf("exp: %s, got: %s", exp, s)
+	}
+
+	html := `<p>{{ .Program }}</p>`
+	tmpl, err = lib.ParseOutputTemplate("test.html", html, true)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	output := testOutput()
+	output.Program = "<script>"
+	s, err = tmpl.Render(output)
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if exp := "<p>&lt;script&gt;</p>"; s != exp {
+		t.Errorf("exp: %s, got: %s", exp, s)
+	}
+
+	if _, err := lib.ParseOutputTemplate("bad", "{{ .Nope", false); err == nil {
+		t.Errorf("expected a parse error")
+	}
+}
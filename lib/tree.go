@@ -0,0 +1,447 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/util"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+// treeNode is a file or directory in the tree view of the results.
+type treeNode struct {
+	// name is the displayed name. Directories end with a slash. Chains of
+	// directories with only one child are merged into a single node.
+	name string
+
+	// uid is the uid of the results at this path, if there are any.
+	uid string
+
+	children map[string]*treeNode
+
+	// licenses are the rolled up licenses for this whole subtree, with the
+	// number of files that each one was found in.
+	licenses map[string]*treeLicense
+
+	// files is the number of files with results in this subtree.
+	files int
+
+	// paths is the number of files and directories with results in this
+	// subtree. A directory has a result of its own when a backend makes a
+	// determination for a whole package there, and it counts towards the
+	// licenses just like a file does.
+	paths int
+
+	flagged    bool // the profile matches the results at this path
+	conflicted bool // the backends disagree at this path
+	unknown    bool // a license at this path isn't on the SPDX list
+
+	// interesting is true if anything in this subtree is flagged,
+	// conflicted, or has an unknown license. Only these are expanded.
+	interesting bool
+}
+
+// treeLicense is a license and the number of files that it was found in.
+type treeLicense struct {
+	license *licenses.License
+	count   int
+}
+
+// isDir returns true if this node is a directory.
+func (obj *treeNode) isDir() bool {
+	return len(obj.children) > 0 || strings.HasSuffix(obj.name, "/")
+}
+
+// uniform returns true if every path with results in this subtree has the same
+// one license.
+func (obj *treeNode) uniform() bool {
+	if len(obj.licenses) != 1 {
+		return false
+	}
+	for _, x := range obj.licenses {
+		return x.count == obj.paths
+	}
+	return false
+}
+
+// sortedChildren returns the children with the directories first, and then by
+// name, so that the tree is always displayed in the same order.
+func (obj *treeNode) sortedChildren() []*treeNode {
+	nodes := []*treeNode{}
+	for _, x := range obj.children {
+		nodes = append(nodes, x)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if a, b := nodes[i].isDir(), nodes[j].isDir(); a != b {
+			return a
+		}
+		return nodes[i].name < nodes[j].name
+	})
+	return nodes
+}
+
+// sortedLicenses returns the rolled up licenses by decreasing count, and then
+// by name.
+func (obj *treeNode) sortedLicenses() []*treeLicense {
+	ls := []*treeLicense{}
+	for _, x := range obj.licenses {
+		ls = append(ls, x)
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		if ls[i].count != ls[j].count {
+			return ls[i].count > ls[j].count
+		}
+		return ls[i].license.String() < ls[j].license.String()
+	})
+	return ls
+}
+
+// buildTree builds the directory hierarchy of the results, and rolls up the
+// licenses and interesting flags into each directory. The uid's are split on
+// slashes after the scheme, and any query string is ignored. It returns an
+// unnamed node whose children are the roots, which are usually one per input.
+func buildTree(results interfaces.ResultSet, profile *ProfileData) *treeNode {
+	top := &treeNode{children: make(map[string]*treeNode)}
+	for _, uid := range sortedURIs(results) {
+		s := stripQuery(uid)
+		prefix := ""
+		if ix := strings.Index(s, "://"); ix > -1 {
+			prefix, s = s[:ix+len("://")], s[ix+len("://"):]
+		}
+		if strings.HasPrefix(s, "/") {
+			prefix, s = prefix+"/", strings.TrimPrefix(s, "/")
+		}
+		isDir := strings.HasSuffix(s, "/")
+
+		node := top.child(prefix, prefix)
+		if s = strings.Trim(s, "/"); s != "" {
+			segments := strings.Split(s, "/")
+			for i, x := range segments {
+				name := x + "/"
+				if i == len(segments)-1 && !isDir {
+					name = x
+				}
+				node = node.child(x, name)
+			}
+		}
+		node.uid = uid
+	}
+
+	for _, x := range top.children {
+		x.compact()
+		x.rollup(results, profile)
+	}
+	return top
+}
+
+// child returns the child node with this key, adding it if it doesn't exist.
+func (obj *treeNode) child(key, name string) *treeNode {
+	if node, exists := obj.children[key]; exists {
+		return node
+	}
+	node := &treeNode{
+		name:     name,
+		children: make(map[string]*treeNode),
+	}
+	obj.children[key] = node
+	return node
+}
+
+// compact merges chains of directories that have only one child directory and
+// no results of their own into a single node, so that deep trees stay short.
+func (obj *treeNode) compact() {
+	if len(obj.children) > 0 && !strings.HasSuffix(obj.name, "/") {
+		obj.name += "/" // a directory uid without a trailing slash
+	}
+	for obj.uid == "" && len(obj.children) == 1 {
+		var child *treeNode
+		for _, x := range obj.children {
+			child = x
+		}
+		if !child.isDir() {
+			break
+		}
+		obj.name += child.name
+		obj.uid = child.uid
+		obj.children = child.children
+		if !strings.HasSuffix(obj.name, "/") {
+			obj.name += "/"
+		}
+	}
+	for _, x := range obj.children {
+		x.compact()
+	}
+}
+
+// rollup computes the flags of this node, and the rolled up licenses and flags
+// of the whole subtree.
+func (obj *treeNode) rollup(results interfaces.ResultSet, profile *ProfileData) {
+	obj.licenses = make(map[string]*treeLicense)
+
+	if m, exists := results[obj.uid]; exists && obj.uid != "" {
+		if !obj.isDir() {
+			obj.files++
+		}
+		obj.paths++
+		for _, x := range profileLicenses(nil, m) {
+			obj.licenses[x.String()] = &treeLicense{license: x, count: 1}
+			if !x.IsSPDX() {
+				obj.unknown = true
+			}
+		}
+		obj.flagged = profile != nil && profileMatches(profile, m)
+
+		found := make(map[string]struct{})
+		for _, result := range m {
+			if len(result.Licenses) == 0 {
+				continue // an error or nothing found isn't a conflict
+			}
+			found[licenses.Join(result.Licenses)] = struct{}{}
+		}
+		obj.conflicted = len(found) > 1
+	}
+	obj.interesting = obj.flagged || obj.conflicted || obj.unknown
+
+	for _, x := range obj.children {
+		x.rollup(results, profile)
+		obj.files += x.files
+		obj.paths += x.paths
+		obj.interesting = obj.interesting || x.interesting
+		for k, v := range x.licenses {
+			if l, exists := obj.licenses[k]; exists {
+				l.count += v.count
+				continue
+			}
+			obj.licenses[k] = &treeLicense{license: v.license, count: v.count}
+		}
+	}
+}
+
+// treeResults renders the results as a directory tree for SimpleProfiles. Each
+// directory shows the licenses found beneath it, and only the subtrees which
+// contain flagged, conflicted, or unknown licenses are expanded. The top level
+// of each input is always expanded. In the html style, the collapsed
+// directories can still be opened. The red function is used to highlight the
+// important parts.
+func treeResults(results interfaces.ResultSet, profile *ProfileData, backendWeights map[interfaces.Backend]float64, style string, red func(format string, a ...interface{}) string) (string, error) {
+	obj := &treeRenderer{
+		results:        results,
+		profile:        profile,
+		backendWeights: backendWeights,
+		style:          style,
+		red:            red,
+	}
+
+	str := ""
+	for _, x := range buildTree(results, profile).sortedChildren() {
+		s, err := obj.render(x, "", "", true)
+		if err != nil {
+			return "", err
+		}
+		str += s
+	}
+	if style == "html" {
+		str = "<tr><td><ul>" + str + "</ul></td></tr>"
+	}
+	return str, nil
+}
+
+// treeRenderer holds the state needed while rendering the tree.
+type treeRenderer struct {
+	results        interfaces.ResultSet
+	profile        *ProfileData
+	backendWeights map[interfaces.Backend]float64
+	style          string
+	red            func(format string, a ...interface{}) string
+}
+
+// render returns the rendered node and its subtree. The first prefix goes in
+// front of this node, and the second goes in front of everything beneath it.
+func (obj *treeRenderer) render(node *treeNode, prefix, indent string, expand bool) (string, error) {
+	name := node.name
+	if obj.style == "html" {
+		name = html.EscapeString(name)
+	}
+	if node.uid != "" {
+		smartURI := util.SmartURI(node.uid) // make it useful to click on
+		if obj.style == "ansi" {
+			name = util.ShellHyperlinkEncode(name, smartURI)
+		}
+		if obj.style == "html" {
+			name = util.HtmlHyperlinkEncode(name, smartURI)
+		}
+	}
+
+	if !node.isDir() {
+		return obj.renderFile(node, name, prefix, indent)
+	}
+
+	line := name
+	if s := obj.badges(node); s != "" {
+		line += "  " + s
+	}
+	expand = expand || node.interesting
+	children := node.sortedChildren()
+
+	if obj.style == "html" {
+		open := ""
+		if expand {
+			open = " open"
+		}
+		str := fmt.Sprintf("<li><details%s><summary>%s</summary><ul>", open, line)
+		for _, x := range children {
+			s, err := obj.render(x, "", "", false)
+			if err != nil {
+				return "", err
+			}
+			str += s
+		}
+		str += "</ul></details></li>"
+		return str, nil
+	}
+
+	str := prefix + line + "\n"
+	if !expand {
+		return str, nil
+	}
+	branch, last, pipe, space := "├── ", "└── ", "│   ", "    "
+	if obj.style == "text" {
+		branch, last, pipe, space = "|-- ", "`-- ", "|   ", "    "
+	}
+	for i, x := range children {
+		p, in := branch, pipe
+		if i == len(children)-1 {
+			p, in = last, space
+		}
+		s, err := obj.render(x, indent+p, indent+in, false)
+		if err != nil {
+			return "", err
+		}
+		str += s
+	}
+	return str, nil
+}
+
+// renderFile returns a rendered file. If the backends disagree about it, then
+// the result of each backend is shown beneath it.
+func (obj *treeRenderer) renderFile(node *treeNode, name, prefix, indent string) (string, error) {
+	m := obj.results[node.uid]
+	bs, f, err := annotateBackends(m, obj.backendWeights)
+	if err != nil {
+		return "", err
+	}
+
+	ll := []string{}
+	for _, x := range node.sortedLicenses() {
+		ll = append(ll, obj.license(x.license))
+	}
+	line := fmt.Sprintf("%s (%.2f%%)", name, f*100.0)
+	if len(ll) > 0 {
+		line += "  " + strings.Join(ll, ", ")
+	}
+	if node.flagged {
+		line += " " + obj.red("[flagged]")
+	}
+	if node.conflicted {
+		line += " " + obj.red("[conflict]")
+	}
+	if node.unknown {
+		line += " " + obj.red("[unknown]")
+	}
+
+	details := []string{}
+	if node.conflicted {
+		ttl := 0.0 // total weight for the set of backends at this uri
+		for _, b := range bs {
+			ttl += b.Weight
+		}
+		for _, b := range bs { // in a stable order
+			result := m[b.Backend]
+			ll := []string{}
+			for _, x := range result.Licenses {
+				ll = append(ll, obj.license(x))
+			}
+			details = append(details, fmt.Sprintf("%s (%.2f/%.2f)  %s (%.2f%%)", b.Backend.String(), b.Weight, ttl, strings.Join(ll, ", "), result.Confidence*100.0))
+		}
+	}
+
+	if obj.style == "html" {
+		str := "<li>" + line
+		if len(details) > 0 {
+			str += "<ul><li>" + strings.Join(details, "</li><li>") + "</li></ul>"
+		}
+		return str + "</li>", nil
+	}
+
+	str := prefix + line + "\n"
+	for _, x := range details {
+		str += indent + "    " + x + "\n"
+	}
+	return str, nil
+}
+
+// badges returns the rolled up licenses of a directory. A directory which is
+// uniformly one license shows it once with the number of files, otherwise each
+// license is shown with the number of files that it was found in.
+func (obj *treeRenderer) badges(node *treeNode) string {
+	ls := node.sortedLicenses()
+	if len(ls) == 0 {
+		return ""
+	}
+	if node.uniform() && node.files == 0 { // only a directory result
+		return obj.license(ls[0].license)
+	}
+	if node.uniform() {
+		files := "files"
+		if node.files == 1 {
+			files = "file"
+		}
+		return fmt.Sprintf("%s (%d %s)", obj.license(ls[0].license), node.files, files)
+	}
+	s := []string{}
+	for _, x := range ls {
+		s = append(s, fmt.Sprintf("%s (%d)", obj.license(x.license), x.count))
+	}
+	return strings.Join(s, ", ")
+}
+
+// license returns the license name, highlighted if the profile matches it.
+func (obj *treeRenderer) license(license *licenses.License) string {
+	s := license.String()
+	if obj.style == "html" {
+		s = html.EscapeString(s)
+	}
+	if !UseColour || obj.profile == nil {
+		return s
+	}
+	if licenses.Matches(license, obj.profile.Licenses) != obj.profile.Exclude {
+		return obj.red(s)
+	}
+	return s
+}
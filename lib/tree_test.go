@@ -0,0 +1,132 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awslabs/yesiscan/interfaces"
+	"github.com/awslabs/yesiscan/lib"
+	"github.com/awslabs/yesiscan/util/licenses"
+)
+
+func TestTreeProfiles(t *testing.T) {
+	b1 := &testBackend{name: "b1"}
+	b2 := &testBackend{name: "b2"}
+	mit := &licenses.License{SPDX: "MIT"}
+	apache := &licenses.License{SPDX: "Apache-2.0"}
+	gpl := &licenses.License{SPDX: "GPL-2.0-only"}
+	weights := map[interfaces.Backend]float64{b1: 1.0, b2: 1.0}
+
+	results := interfaces.ResultSet{
+		"file:///tmp/src/deep/a/b/1.go": {
+			b1: {Licenses: []*licenses.License{apache}, Confidence: 1.0},
+		},
+		"file:///tmp/src/deep/a/b/2.go": {
+			b1: {Licenses: []*licenses.License{apache}, Confidence: 1.0},
+		},
+		"file:///tmp/src/gpl.go": {
+			b1: {Licenses: []*licenses.License{gpl}, Confidence: 1.0},
+		},
+		"file:///tmp/vendor/x/x.go": {
+			b1: {Licenses: []*licenses.License{mit}, Confidence: 1.0},
+			b2: {Licenses: []*licenses.License{apache}, Confidence: 1.0},
+		},
+	}
+
+	s, err := lib.TreeProfiles(results, nil, nil, nil, false, weights, "text")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	exp := []string{
+		"skipped: 0 files/directories",
+		"file:///tmp/  Apache-2.0 (3), GPL-2.0-only (1), MIT (1)",
+		"|-- src/  Apache-2.0 (2), GPL-2.0-only (1)",
+		"`-- vendor/x/  Apache-2.0 (1), MIT (1)",
+		"    `-- x.go (100.00%)  Apache-2.0, MIT [conflict]",
+		"            b1 (1.00/2.00)  MIT (100.00%)",
+		"            b2 (1.00/2.00)  Apache-2.0 (100.00%)",
+	}
+	if got := strings.Split(strings.TrimSpace(s), "\n"); strings.Join(got, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("unexpected tree:\n%s", s)
+		t.Logf("exp:\n%s", strings.Join(exp, "\n"))
+	}
+
+	// the profile flags the gpl file, so that subtree gets expanded
+	profile := &lib.ProfileData{Licenses: []*licenses.License{gpl}}
+	s, err = lib.TreeProfiles(results, nil, nil, profile, false, weights, "text")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	for _, x := range []string{
+		"|   |-- deep/a/b/  Apache-2.0 (2 files)\n",
+		"|   `-- gpl.go (100.00%)  GPL-2.0-only [flagged]\n",
+	} {
+		if !strings.Contains(s, x) {
+			t.Errorf("missing: %s", x)
+			t.Logf("got:\n%s", s)
+		}
+	}
+	if strings.Contains(s, "1.go") {
+		t.Errorf("uniform directory was not collapsed:\n%s", s)
+	}
+}
+
+func TestTreeProfilesDirectoryResult(t *testing.T) {
+	b1 := &testBackend{name: "b1"}
+	mit := &licenses.License{SPDX: "MIT"}
+	apache := &licenses.License{SPDX: "Apache-2.0"}
+	weights := map[interfaces.Backend]float64{b1: 1.0}
+
+	// a package level determination on the directory, like npm makes
+	results := interfaces.ResultSet{
+		"file:///tmp/pkg/": {
+			b1: {Licenses: []*licenses.License{mit}, Confidence: 1.0, Component: "pkg@1.0.0"},
+		},
+		"file:///tmp/pkg/a.js": {
+			b1: {Licenses: []*licenses.License{mit}, Confidence: 1.0},
+		},
+		"file:///tmp/pkg/b.js": {
+			b1: {Licenses: []*licenses.License{mit}, Confidence: 1.0},
+		},
+		"file:///tmp/other.go": {
+			b1: {Licenses: []*licenses.License{apache}, Confidence: 1.0},
+		},
+	}
+
+	s, err := lib.TreeProfiles(results, nil, nil, nil, false, weights, "text")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if !strings.Contains(s, "|-- pkg/  MIT (2 files)\n") {
+		t.Errorf("uniform package directory is not shown as one license:\n%s", s)
+	}
+	if strings.Contains(s, "a.js") {
+		t.Errorf("uniform package directory was not collapsed:\n%s", s)
+	}
+}
@@ -78,8 +78,7 @@ func (obj *TrivialURIParser) Parse() ([]interfaces.Iterator, error) {
 
 	// this is a bit of a heuristic, but we'll go with it for now
 	// this is because we get https:// urls that are really github git URI's
-	isTar := strings.HasSuffix(strings.ToLower(s), iterator.TarExtension)
-	if strings.ToLower(u.Scheme) == iterator.HttpsSchemeRaw && (isZip(s) || isGzip(s) || isTar || isBzip2(s)) {
+	if strings.ToLower(u.Scheme) == iterator.HttpsSchemeRaw && (isZip(s) || isGzip(s) || isTar(s) || isBzip2(s)) {
 		iterator := &iterator.Http{
 			Debug: obj.Debug,
 			Logf: func(format string, v ...interface{}) {
@@ -194,7 +193,19 @@ func isGit(u *url.URL) bool {
 // isZip is a helper method to determine whether a string has a Zip extension
 // suffix.
 func isZip(input string) bool {
-	extensions := []string{iterator.ZipExtension, iterator.JarExtension, iterator.WhlExtension}
+	extensions := []string{iterator.ZipExtension, iterator.JarExtension, iterator.WhlExtension, iterator.NupkgExtension}
+	for _, extension := range extensions {
+		if strings.HasSuffix(strings.ToLower(input), extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTar is a helper method to determine whether a string has a Tar extension
+// suffix. Ruby .gem files are tar files.
+func isTar(input string) bool {
+	extensions := []string{iterator.TarExtension, iterator.GemExtension}
 	for _, extension := range extensions {
 		if strings.HasSuffix(strings.ToLower(input), extension) {
 			return true
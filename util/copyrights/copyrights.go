@@ -0,0 +1,117 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package copyrights finds copyright statements in files.
+package copyrights
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxBytesLine is the longest line that we look at. Copyright
+	// statements are short, so anything longer is probably minified code
+	// or some other kind of data.
+	MaxBytesLine = 1024 * 64 // 64 KiB
+
+	// MaxLength is the longest statement that we return. Anything longer is
+	// truncated.
+	MaxLength = 256
+
+	// MaxStatements is the maximum number of statements that we return for
+	// a single file.
+	MaxStatements = 100
+
+	// binaryCheckBytes is how much of the start of the data we look at when
+	// deciding if it's a binary file.
+	binaryCheckBytes = 8000
+)
+
+var (
+	// statementRegexp matches the start of a copyright statement. It needs
+	// either the (c) or © symbol, or a year, so that we don't match every
+	// use of the word.
+	statementRegexp = regexp.MustCompile(`(?i)(copyright\s*(\(c\)|©|&copy;)|copyright\s+\d{4}|©\s*\d{4}|\(c\)\s*\d{4})`)
+)
+
+// Find returns the list of copyright statements found in the data, in the order
+// that they appear and without any duplicates. Each statement starts at the
+// copyright marker and goes to the end of the line, with any trailing comment
+// characters removed. It returns nil for binary data.
+func Find(data []byte) []string {
+	n := len(data)
+	if n > binaryCheckBytes {
+		n = binaryCheckBytes
+	}
+	if bytes.IndexByte(data[:n], 0) > -1 { // probably binary
+		return nil
+	}
+
+	found := []string{}
+	seen := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	buf := []byte{}                   // create a buffer for very long lines
+	scanner.Buffer(buf, MaxBytesLine) // set the max size of that buffer
+	for scanner.Scan() {
+		s := scanner.Text() // newlines will be stripped here
+		loc := statementRegexp.FindStringIndex(s)
+		if loc == nil {
+			continue
+		}
+
+		statement := Clean(s[loc[0]:])
+		if statement == "" {
+			continue
+		}
+		if _, exists := seen[statement]; exists {
+			continue
+		}
+		seen[statement] = struct{}{}
+		found = append(found, statement)
+
+		if len(found) >= MaxStatements {
+			break
+		}
+	}
+	// If the scanner fails, such as with a line that is too long, then we
+	// return whatever we found so far, since it's still useful.
+
+	return found
+}
+
+// Clean normalizes a copyright statement by collapsing the whitespace, removing
+// any trailing comment characters, and truncating it if it's too long.
+func Clean(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, x := range []string{"*/", "-->", "#}", "%>", "\"", "'", "`", ",", ";"} {
+		s = strings.TrimSpace(strings.TrimSuffix(s, x))
+	}
+	if len(s) > MaxLength {
+		s = s[:MaxLength]
+	}
+	return strings.TrimSpace(s)
+}
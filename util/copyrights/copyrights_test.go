@@ -0,0 +1,56 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package copyrights_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/awslabs/yesiscan/util/copyrights"
+)
+
+func TestFind(t *testing.T) {
+	data := []byte(`/*
+ * Copyright (c) 2021  Jane Doe <jane@example.com>
+ * Copyright 2019-2022 Example Corp. */
+// copyright (C) 2021 Jane Doe <jane@example.com>
+var copyright = "the copyright holder"
+<!-- © 2020 Someone Else -->
+Copyright [yyyy] [name of copyright owner]
+Copyright (c) 2021 Jane Doe <jane@example.com>
+`)
+	exp := []string{
+		"Copyright (c) 2021 Jane Doe <jane@example.com>",
+		"Copyright 2019-2022 Example Corp.",
+		"copyright (C) 2021 Jane Doe <jane@example.com>",
+		"© 2020 Someone Else",
+	}
+	if got := copyrights.Find(data); !reflect.DeepEqual(got, exp) {
+		t.Errorf("exp: %+v, got: %+v", exp, got)
+	}
+
+	if got := copyrights.Find([]byte("Copyright (c) 2021\x00binary")); len(got) != 0 {
+		t.Errorf("expected nothing from binary data, got: %+v", got)
+	}
+}
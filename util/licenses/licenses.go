@@ -34,6 +34,7 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -147,10 +148,24 @@ type License struct {
 	// Custom is a custom string that is a unique identifier for the license
 	// in the aforementioned Origin namespace.
 	Custom string
+
+	// Or is a list of licenses which may be chosen from, as with the SPDX
+	// OR operator. When this is used, the other fields are empty. They are
+	// sorted by their string representation, so that the same choice is
+	// always represented the same way.
+	Or []*License
 }
 
 // String returns a string representation of whatever license is specified.
 func (obj *License) String() string {
+	if len(obj.Or) > 0 {
+		xs := []string{}
+		for _, x := range obj.Or {
+			xs = append(xs, x.String())
+		}
+		return "(" + strings.Join(xs, " OR ") + ")"
+	}
+
 	if obj.Origin != "" && obj.Custom != "" {
 		return fmt.Sprintf("%s(%s)", obj.Custom, obj.Origin)
 	}
@@ -163,10 +178,37 @@ func (obj *License) String() string {
 	return obj.SPDX
 }
 
+// IsSPDX returns true if the license is on the SPDX license list. A choice of
+// licenses is only on the list if all of them are.
+func (obj *License) IsSPDX() bool {
+	if len(obj.Or) > 0 {
+		return obj.Validate() == nil
+	}
+	return obj.SPDX != "" && obj.Validate() == nil
+}
+
+// Alternatives returns the list of licenses which may be chosen from. For a
+// license which isn't a choice, this is a list of just that license.
+func (obj *License) Alternatives() []*License {
+	if len(obj.Or) > 0 {
+		return obj.Or
+	}
+	return []*License{obj}
+}
+
 // Validate returns an error if the license doesn't have a valid representation.
 // For example, if you express the license as an SPDX ID, this will validate
 // that it is among the known licenses.
 func (obj *License) Validate() error {
+	if len(obj.Or) > 0 {
+		for _, x := range obj.Or {
+			if err := x.Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if obj.SPDX != "" {
 		// if an SPDX ID is specified, we validate based on it!
 		_, err := ID(obj.SPDX)
@@ -196,6 +238,14 @@ func (obj *License) Cmp(license *License) error {
 	if obj.Custom != license.Custom {
 		return fmt.Errorf("the Custom field differs")
 	}
+	if len(obj.Or) != len(license.Or) {
+		return fmt.Errorf("the Or field differs")
+	}
+	for i, x := range obj.Or {
+		if x.Cmp(license.Or[i]) != nil {
+			return fmt.Errorf("the Or field differs")
+		}
+	}
 
 	return nil
 }
@@ -217,6 +267,22 @@ func ID(spdx string) (*LicenseSPDX, error) {
 // license identifier.
 // TODO: add some tests
 func StringToLicense(name string) (*License, error) {
+	// parse the (name1 OR name2) syntax of a choice of licenses
+	if s := strings.TrimSuffix(strings.TrimPrefix(name, "("), ")"); strings.Contains(s, " OR ") && len(s) == len(name)-2 {
+		license := &License{}
+		for _, x := range strings.Split(s, " OR ") {
+			l, err := StringToLicense(strings.TrimSpace(x))
+			if err != nil {
+				return nil, err
+			}
+			license.Or = append(license.Or, l)
+		}
+		sort.Slice(license.Or, func(i, j int) bool {
+			return license.Or[i].String() < license.Or[j].String()
+		})
+		return license, nil
+	}
+
 	license := &License{
 		SPDX: name,
 	}
@@ -291,16 +357,89 @@ func InList(needle *License, haystack []*License) bool {
 	return false
 }
 
+// Matches returns true if a license matches any license in a list. This is like
+// InList, except that a choice of licenses also matches each of the licenses
+// that it is a choice between, so that a profile which lists one of them will
+// match it.
+func Matches(needle *License, haystack []*License) bool {
+	for _, x := range haystack {
+		if needle.Cmp(x) == nil {
+			return true
+		}
+		if len(x.Or) > 0 && len(needle.Or) == 0 && InList(needle, x.Or) {
+			return true
+		}
+	}
+	for _, x := range needle.Or {
+		if Matches(x, haystack) {
+			return true
+		}
+	}
+	return false
+}
+
 // Union returns the union of licenses in both input lists. It uses the pointers
 // from the first list in the results. It does not try to remove duplicates so
 // if either list has duplicates, you may end up with duplicates in the result.
-// It uses the license Cmp method to determine equality.
+// It uses the Matches function to determine equality.
 func Union(haystack1 []*License, haystack2 []*License) []*License {
 	union := []*License{}
 	for _, x := range haystack1 {
-		if InList(x, haystack2) {
+		if Matches(x, haystack2) {
 			union = append(union, x)
 		}
 	}
 	return union
 }
+
+// licenseFileNames are the upper case base names of the files that commonly
+// contain the full text of a license.
+var licenseFileNames = []string{
+	"LICENSE",
+	"LICENCE",
+	"COPYING",
+	"COPYRIGHT",
+	"NOTICE",
+	"UNLICENSE",
+}
+
+// licenseFileExts are the upper case file extensions that a license file can
+// have.
+var licenseFileExts = []string{
+	"",
+	".TXT",
+	".MD",
+	".RST",
+}
+
+// IsLicenseFile returns true if the file name looks like it contains the full
+// text of a license, such as LICENSE, COPYING.txt, or LICENSE-MIT.
+func IsLicenseFile(name string) bool {
+	s := strings.ToUpper(name)
+	ext := ""
+	if ix := strings.LastIndex(s, "."); ix > 0 {
+		ext = s[ix:]
+	}
+	found := false
+	for _, x := range licenseFileExts {
+		if ext == x {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+	s = strings.TrimSuffix(s, ext)
+
+	for _, x := range licenseFileNames {
+		if s == x {
+			return true
+		}
+		// eg: LICENSE-MIT, LICENSE_APACHE, or LICENSE.MIT.txt
+		if strings.HasPrefix(s, x) && strings.ContainsAny(s[len(x):len(x)+1], "-_.") {
+			return true
+		}
+	}
+	return false
+}
@@ -45,3 +45,112 @@ func TestID(t *testing.T) {
 		return
 	}
 }
+
+func TestIsLicenseFile(t *testing.T) {
+	for name, exp := range map[string]bool{
+		"LICENSE":        true,
+		"LICENSE.txt":    true,
+		"licence.md":     true,
+		"COPYING":        true,
+		"LICENSE-MIT":    true,
+		"NOTICE":         true,
+		"license.go":     false,
+		"licenses.json":  false,
+		"LICENSEE":       false,
+		"README.md":      false,
+		"COPYING.tar.gz": false,
+	} {
+		if got := licenses.IsLicenseFile(name); got != exp {
+			t.Errorf("name: %s, exp: %t, got: %t", name, exp, got)
+		}
+	}
+}
+
+func TestOr(t *testing.T) {
+	license, err := licenses.StringToLicense("(MIT OR Apache-2.0)")
+	if err != nil {
+		t.Errorf("err: %+v", err)
+		return
+	}
+	if s := license.String(); s != "(Apache-2.0 OR MIT)" {
+		t.Errorf("exp: (Apache-2.0 OR MIT), got: %s", s)
+	}
+	if err := license.Validate(); err != nil {
+		t.Errorf("err: %+v", err)
+	}
+	if !license.IsSPDX() {
+		t.Errorf("exp: a choice of SPDX licenses")
+	}
+	mit := &licenses.License{SPDX: "MIT"}
+	if !licenses.Matches(mit, []*licenses.License{license}) {
+		t.Errorf("exp: MIT to match the choice")
+	}
+	if licenses.InList(mit, []*licenses.License{license}) {
+		t.Errorf("exp: MIT to not be in the list")
+	}
+	gpl := &licenses.License{SPDX: "GPL-2.0-only"}
+	if licenses.Matches(gpl, []*licenses.License{license}) {
+		t.Errorf("exp: GPL-2.0-only to not match the choice")
+	}
+}
+
+func TestURLToLicense(t *testing.T) {
+	tests := map[string]string{
+		"http://www.apache.org/licenses/LICENSE-2.0.txt":     "Apache-2.0",
+		"https://www.apache.org/licenses/LICENSE-2.0.html/":  "Apache-2.0",
+		"http://opensource.org/licenses/mit-license.php":     "MIT",
+		"https://opensource.org/licenses/BSD-3-Clause":       "BSD-3-Clause",
+		"https://spdx.org/licenses/isc.html":                 "ISC",
+		"http://www.gnu.org/licenses/old-licenses/gpl-2.0":   "GPL-2.0-only",
+		"https://www.eclipse.org/legal/epl-v10.html#section": "EPL-1.0",
+		"https://example.com/LICENSE":                        "",
+		"":                                                   "",
+	}
+	for u, exp := range tests {
+		license, err := licenses.URLToLicense(u)
+		if exp == "" {
+			if err == nil {
+				t.Errorf("url: %s, exp: error, got: %s", u, license)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("url: %s, err: %+v", u, err)
+			continue
+		}
+		if license.SPDX != exp {
+			t.Errorf("url: %s, exp: %s, got: %s", u, exp, license.SPDX)
+		}
+	}
+}
+
+func TestNameToLicense(t *testing.T) {
+	tests := map[string]string{
+		"The Apache Software License, Version 2.0":     "Apache-2.0",
+		"Apache License, Version 2.0":                  "Apache-2.0",
+		"apache-2.0":                                   "Apache-2.0",
+		"The MIT License (MIT)":                        "MIT",
+		"GNU Lesser General Public License (LGPL-2.1)": "LGPL-2.1",
+		"MIT License":                                  "MIT",
+		"Eclipse Public License - v 1.0":               "EPL-1.0",
+		"New BSD License":                              "BSD-3-Clause",
+		"BSD License":                                  "",
+		"":                                             "",
+	}
+	for name, exp := range tests {
+		license, err := licenses.NameToLicense(name)
+		if exp == "" {
+			if err == nil {
+				t.Errorf("name: %s, exp: error, got: %s", name, license)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("name: %s, err: %+v", name, err)
+			continue
+		}
+		if license.SPDX != exp {
+			t.Errorf("name: %s, exp: %s, got: %s", name, exp, license.SPDX)
+		}
+	}
+}
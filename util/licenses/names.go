@@ -0,0 +1,196 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package licenses
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Names is a table of the names that are commonly used for licenses in package
+// metadata, such as in a pom.xml file, and their SPDX IDs. The keys are in the
+// form that NormalizeName returns. Names which could mean more than one SPDX
+// license, such as "BSD License" or "GNU Lesser General Public License", are not
+// here on purpose. The full names from the SPDX license list are used as well.
+var Names = map[string]string{
+	"apache 2":                            "Apache-2.0",
+	"apache 2.0":                          "Apache-2.0",
+	"apache license 2.0":                  "Apache-2.0",
+	"apache license v2":                   "Apache-2.0",
+	"apache license v2.0":                 "Apache-2.0",
+	"apache license version 2":            "Apache-2.0",
+	"apache license version 2.0":          "Apache-2.0",
+	"apache public license 2.0":           "Apache-2.0",
+	"apache software license 2.0":         "Apache-2.0",
+	"apache software license version 2.0": "Apache-2.0",
+	"apache v2":                           "Apache-2.0",
+	"apache-2":                            "Apache-2.0",
+	"asf 2.0":                             "Apache-2.0",
+	"asl 2.0":                             "Apache-2.0",
+	"mit":                                 "MIT",
+	"mit license":                         "MIT",
+	"mit licence":                         "MIT",
+	"expat license":                       "MIT",
+	"bouncy castle licence":               "MIT",
+	"isc license":                         "ISC",
+	"new bsd license":                     "BSD-3-Clause",
+	"modified bsd license":                "BSD-3-Clause",
+	"revised bsd license":                 "BSD-3-Clause",
+	"3-clause bsd license":                "BSD-3-Clause",
+	"bsd 3-clause":                        "BSD-3-Clause",
+	"bsd 3-clause license":                "BSD-3-Clause",
+	"bsd-3-clause license":                "BSD-3-Clause",
+	"eclipse distribution license v 1.0":  "BSD-3-Clause",
+	"eclipse distribution license v1.0":   "BSD-3-Clause",
+	"edl 1.0":                             "BSD-3-Clause",
+	"go license":                          "BSD-3-Clause",
+	"simplified bsd license":              "BSD-2-Clause",
+	"2-clause bsd license":                "BSD-2-Clause",
+	"bsd 2-clause":                        "BSD-2-Clause",
+	"bsd 2-clause license":                "BSD-2-Clause",
+	"eclipse public license 1.0":          "EPL-1.0",
+	"eclipse public license v 1.0":        "EPL-1.0",
+	"eclipse public license v1.0":         "EPL-1.0",
+	"eclipse public license version 1.0":  "EPL-1.0",
+	"epl 1.0":                             "EPL-1.0",
+	"eclipse public license 2.0":          "EPL-2.0",
+	"eclipse public license v 2.0":        "EPL-2.0",
+	"eclipse public license v2.0":         "EPL-2.0",
+	"eclipse public license version 2.0":  "EPL-2.0",
+	"epl 2.0":                             "EPL-2.0",
+	"common public license version 1.0":   "CPL-1.0",
+	"cddl 1.0":                            "CDDL-1.0",
+	"cddl v1.0":                           "CDDL-1.0",
+	"cddl 1.1":                            "CDDL-1.1",
+	"cddl v1.1":                           "CDDL-1.1",
+	"common development and distribution license cddl v1.0": "CDDL-1.0",
+	"common development and distribution license cddl v1.1": "CDDL-1.1",
+	"mozilla public license 1.1":                            "MPL-1.1",
+	"mozilla public license version 1.1":                    "MPL-1.1",
+	"mpl 1.1":                                               "MPL-1.1",
+	"mozilla public license 2.0":                            "MPL-2.0",
+	"mozilla public license version 2.0":                    "MPL-2.0",
+	"mpl 2.0":                                               "MPL-2.0",
+	"gnu general public license version 2":                  "GPL-2.0-only",
+	"gnu general public license v2.0":                       "GPL-2.0-only",
+	"gpl 2.0":                                               "GPL-2.0-only",
+	"gpl2":                                                  "GPL-2.0-only",
+	"gplv2":                                                 "GPL-2.0-only",
+	"gnu general public license version 3":                  "GPL-3.0-only",
+	"gnu general public license v3.0":                       "GPL-3.0-only",
+	"gpl 3.0":                                               "GPL-3.0-only",
+	"gpl3":                                                  "GPL-3.0-only",
+	"gplv3":                                                 "GPL-3.0-only",
+	"gnu lesser general public license version 2.1": "LGPL-2.1-only",
+	"gnu lesser general public license v2.1":        "LGPL-2.1-only",
+	"lgpl 2.1":                                      "LGPL-2.1-only",
+	"lgplv2.1":                                      "LGPL-2.1-only",
+	"gnu lesser general public license version 3":   "LGPL-3.0-only",
+	"gnu lesser general public license v3.0":        "LGPL-3.0-only",
+	"lgpl 3.0":                                      "LGPL-3.0-only",
+	"lgplv3":                                        "LGPL-3.0-only",
+	"gnu affero general public license version 3":   "AGPL-3.0-only",
+	"agpl 3.0":                                      "AGPL-3.0-only",
+	"agplv3":                                        "AGPL-3.0-only",
+	"cc0":                                           "CC0-1.0",
+	"cc0 1.0":                                       "CC0-1.0",
+	"cc0 1.0 universal":                             "CC0-1.0",
+	"unlicense":                                     "Unlicense",
+	"boost software license 1.0":                    "BSL-1.0",
+	"boost software license version 1.0":            "BSL-1.0",
+	"zlib license":                                  "Zlib",
+	"json license":                                  "JSON",
+	"universal permissive license v 1.0":            "UPL-1.0",
+	"universal permissive license v1.0":             "UPL-1.0",
+	"postgresql license":                            "PostgreSQL",
+	"public domain cc0":                             "CC0-1.0",
+}
+
+// NormalizeName returns the form of a license name that is used to look it up.
+// It is lower case, punctuation such as commas and brackets is removed, a word
+// that is only a dash is removed, and a leading "the" is removed, so that "The
+// Apache Software License, Version 2.0" becomes "apache software license
+// version 2.0".
+func NormalizeName(name string) string {
+	s := strings.ToLower(name)
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`,;:()[]"'/`, r) {
+			return ' '
+		}
+		return r
+	}, s)
+	words := []string{}
+	for _, x := range strings.Fields(s) {
+		if x == "-" || len(words) == 0 && x == "the" {
+			continue
+		}
+		words = append(words, x)
+	}
+	return strings.Join(words, " ")
+}
+
+// NameToLicense returns the license for a name if it is well-known. It accepts
+// an SPDX ID in any case, a name in the Names table, and the full name of a
+// license on the SPDX license list. A name which ends with an SPDX ID or a short
+// name in brackets is also accepted. It returns an error if the name isn't
+// known.
+func NameToLicense(name string) (*License, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("empty name")
+	}
+	for _, license := range LicenseList.Licenses {
+		if strings.EqualFold(license.LicenseID, name) {
+			return &License{SPDX: license.LicenseID}, nil
+		}
+	}
+
+	key := NormalizeName(name)
+	if id, exists := Names[key]; exists {
+		return &License{SPDX: id}, nil
+	}
+	for _, license := range LicenseList.Licenses {
+		if license.IsDeprecated {
+			continue
+		}
+		if NormalizeName(license.Name) == key {
+			return &License{SPDX: license.LicenseID}, nil
+		}
+	}
+
+	// eg: The MIT License (MIT)
+	if i := strings.LastIndex(name, "("); i > 0 && strings.HasSuffix(name, ")") {
+		if license, err := NameToLicense(name[:i]); err == nil {
+			return license, nil
+		}
+		inner := strings.TrimSpace(name[i+1 : len(name)-1])
+		for _, license := range LicenseList.Licenses {
+			if strings.EqualFold(license.LicenseID, inner) {
+				return &License{SPDX: license.LicenseID}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unknown license name: %s", name)
+}
@@ -0,0 +1,165 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package licenses
+
+import (
+	"fmt"
+	"strings"
+)
+
+// URLs is a table of the urls of well-known licenses and their SPDX IDs. These
+// are the urls that are commonly used in package metadata, such as the url of a
+// license in a pom.xml file. The keys are in the form that NormalizeURL returns.
+// The SPDX license list also has a list of urls for each license, and those are
+// used as well, but these take precedence. A url that only names a version of
+// the GPL family is taken to mean that version only, since that is the more
+// cautious reading.
+var URLs = map[string]string{
+	"apache.org/licenses/license-2.0":                              "Apache-2.0",
+	"apache.org/licenses/license-1.1":                              "Apache-1.1",
+	"opensource.org/licenses/apache2.0":                            "Apache-2.0",
+	"opensource.org/licenses/mit-license":                          "MIT",
+	"opensource.org/licenses/bsd-license":                          "BSD-2-Clause",
+	"opensource.org/licenses/isc-license":                          "ISC",
+	"opensource.org/licenses/lgpl-license":                         "LGPL-2.1-only",
+	"opensource.org/licenses/cddl1":                                "CDDL-1.0",
+	"opensource.org/licenses/eclipse-1.0":                          "EPL-1.0",
+	"mit-license.org":                                              "MIT",
+	"gnu.org/licenses/gpl":                                         "GPL-3.0-only",
+	"gnu.org/licenses/gpl-3.0":                                     "GPL-3.0-only",
+	"gnu.org/licenses/gpl-3.0-standalone":                          "GPL-3.0-only",
+	"gnu.org/licenses/lgpl":                                        "LGPL-3.0-only",
+	"gnu.org/licenses/lgpl-3.0":                                    "LGPL-3.0-only",
+	"gnu.org/licenses/lgpl-3.0-standalone":                         "LGPL-3.0-only",
+	"gnu.org/licenses/agpl":                                        "AGPL-3.0-only",
+	"gnu.org/licenses/agpl-3.0":                                    "AGPL-3.0-only",
+	"gnu.org/licenses/fdl-1.3":                                     "GFDL-1.3-only",
+	"gnu.org/licenses/gpl-2.0":                                     "GPL-2.0-only",
+	"gnu.org/licenses/lgpl-2.1":                                    "LGPL-2.1-only",
+	"gnu.org/licenses/old-licenses/gpl-2.0":                        "GPL-2.0-only",
+	"gnu.org/licenses/old-licenses/gpl-2.0-standalone":             "GPL-2.0-only",
+	"gnu.org/licenses/old-licenses/lgpl-2.1":                       "LGPL-2.1-only",
+	"gnu.org/licenses/old-licenses/lgpl-2.1-standalone":            "LGPL-2.1-only",
+	"gnu.org/licenses/old-licenses/lgpl-2.0":                       "LGPL-2.0-only",
+	"gnu.org/copyleft/lesser":                                      "LGPL-2.1-only",
+	"gnu.org/copyleft/gpl":                                         "GPL-3.0-only",
+	"eclipse.org/legal/epl-v10":                                    "EPL-1.0",
+	"eclipse.org/legal/epl-v20":                                    "EPL-2.0",
+	"eclipse.org/legal/epl-2.0":                                    "EPL-2.0",
+	"eclipse.org/legal/cpl-v10":                                    "CPL-1.0",
+	"eclipse.org/org/documents/edl-v10":                            "BSD-3-Clause",
+	"eclipse.org/org/documents/epl-v10":                            "EPL-1.0",
+	"eclipse.org/org/documents/epl-2.0/epl-2.0":                    "EPL-2.0",
+	"mozilla.org/mpl/2.0":                                          "MPL-2.0",
+	"mozilla.org/mpl/mpl-1.1":                                      "MPL-1.1",
+	"mozilla.org/mpl/mpl-1.0":                                      "MPL-1.0",
+	"creativecommons.org/publicdomain/zero/1.0":                    "CC0-1.0",
+	"creativecommons.org/publicdomain/zero/1.0/legalcode":          "CC0-1.0",
+	"creativecommons.org/licenses/by/4.0":                          "CC-BY-4.0",
+	"creativecommons.org/licenses/by/3.0":                          "CC-BY-3.0",
+	"creativecommons.org/licenses/by-sa/4.0":                       "CC-BY-SA-4.0",
+	"unlicense.org":                                                "Unlicense",
+	"json.org/license":                                             "JSON",
+	"bouncycastle.org/licence":                                     "MIT",
+	"antlr.org/license":                                            "BSD-3-Clause",
+	"jdom.org/docs/faq":                                            "Saxpath",
+	"glassfish.dev.java.net/public/cddlv1.0":                       "CDDL-1.0",
+	"opensource.org/licenses/cddl-1.0":                             "CDDL-1.0",
+	"boost.org/license_1_0":                                        "BSL-1.0",
+	"boost.org/users/license":                                      "BSL-1.0",
+	"zlib.net/zlib_license":                                        "Zlib",
+	"postgresql.org/about/licence":                                 "PostgreSQL",
+	"python.org/download/releases/2.0/license":                     "Python-2.0",
+	"w3.org/consortium/legal/2015/copyright-software-and-document": "W3C-20150513",
+	"w3.org/consortium/legal/copyright-software":                   "W3C",
+}
+
+// NormalizeURL returns the form of a url that is used to look it up. The scheme,
+// a leading `www.`, any query string or fragment, a trailing slash and a common
+// file extension such as `.html` or `.txt` are removed, and it is lower case.
+func NormalizeURL(u string) string {
+	s := strings.ToLower(strings.TrimSpace(u))
+	for _, x := range []string{"https://", "http://", "ftp://"} {
+		s = strings.TrimPrefix(s, x)
+	}
+	s = strings.TrimPrefix(s, "www.")
+	if ix := strings.IndexAny(s, "?#"); ix > -1 {
+		s = s[:ix]
+	}
+	s = strings.TrimRight(s, "/")
+	for _, x := range []string{".html", ".htm", ".php", ".txt", ".md"} {
+		s = strings.TrimSuffix(s, x)
+	}
+	return s
+}
+
+// URLToLicense returns the license for a url if it is well-known. It looks in
+// the URLs table, then for a url on the SPDX or OSI websites that is named by
+// an SPDX ID, and then in the urls of the SPDX license list. It returns an error
+// if the url isn't known.
+func URLToLicense(u string) (*License, error) {
+	key := NormalizeURL(u)
+	if key == "" {
+		return nil, fmt.Errorf("empty url")
+	}
+	if id, exists := URLs[key]; exists {
+		return &License{SPDX: id}, nil
+	}
+
+	// eg: https://spdx.org/licenses/MIT.html or opensource.org/licenses/MIT
+	for _, x := range []string{"spdx.org/licenses/", "opensource.org/licenses/", "opensource.org/license/"} {
+		if !strings.HasPrefix(key, x) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, x), ".json")
+		for _, license := range LicenseList.Licenses {
+			if strings.EqualFold(license.LicenseID, name) {
+				return &License{SPDX: license.LicenseID}, nil
+			}
+		}
+	}
+
+	// the same url is sometimes listed for more than one license, and for
+	// deprecated ids, so only use it if it's unambiguous
+	found := ""
+	for _, license := range LicenseList.Licenses {
+		if license.IsDeprecated {
+			continue
+		}
+		for _, x := range license.SeeAlso {
+			if NormalizeURL(x) != key {
+				continue
+			}
+			if found != "" && found != license.LicenseID {
+				return nil, fmt.Errorf("ambiguous license url: %s", u)
+			}
+			found = license.LicenseID
+		}
+	}
+	if found != "" {
+		return &License{SPDX: found}, nil
+	}
+
+	return nil, fmt.Errorf("unknown license url: %s", u)
+}
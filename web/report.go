@@ -0,0 +1,88 @@
+// Copyright Amazon.com Inc or its affiliates and the project contributors
+// Written by James Shubin <purple@amazon.com> and the project contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+//
+// We will never require a CLA to submit a patch. All contributions follow the
+// `inbound == outbound` rule.
+//
+// This is not an official Amazon product. Amazon does not offer support for
+// this project.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package web
+
+import (
+	"bytes"
+	_ "embed" // embed the report assets
+	"encoding/json"
+	"html/template"
+
+	"github.com/awslabs/yesiscan/lib"
+)
+
+// reportTemplate is the page for the interactive report. The report data is
+// embedded in it as json, and is displayed by the javascript, so that it can
+// be sorted, filtered, and expanded without a server.
+//
+//go:embed report/report.html
+var reportTemplate string
+
+//go:embed report/report.css
+var reportCSS string
+
+//go:embed report/report.js
+var reportJS string
+
+// ReturnOutputHtml returns a string of output, formatted as an interactive html
+// report. It is a single self-contained file with the data embedded as json. If
+// the output has the tree option, then the report opens as a directory tree.
+func ReturnOutputHtml(output *lib.Output) (string, error) {
+	report, err := lib.BuildReport(output)
+	if err != nil {
+		return "", err
+	}
+	return ReturnReportHtml(report, output.Tree)
+}
+
+// ReturnReportHtml returns the interactive html report for the structured form
+// of the output. The tree option makes it open as a directory tree, which can
+// also be toggled in the page.
+func ReturnReportHtml(report *lib.Report, tree bool) (string, error) {
+	// json escapes the <, > and & characters, so it's safe inside a script
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer) // we'll write to here
+	if err := t.Execute(buf, map[string]interface{}{
+		"program": report.Program,
+		"version": report.Version,
+		"image":   base64Yesiscan,
+		"css":     template.CSS(reportCSS),
+		"js":      template.JS(reportJS),
+		"data":    template.JS(data),
+		"tree":    tree,
+	}); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,526 @@
+// This renders the interactive report from the json data which is embedded in
+// the page. It is kept free of any dependencies, so that the saved report is a
+// single file which works offline.
+(function() {
+	"use strict";
+
+	var report = JSON.parse(document.getElementById("data").textContent);
+
+	var state = {
+		filter: [],      // lowercase words which must all match
+		profiles: {},    // profile name -> enabled
+		all: false,      // show the files that no profile matches too
+		group: true,     // group the files by directory
+		tree: false,     // display the files as a directory tree
+		opened: {},      // tree directory -> opened or closed by the user
+		openAll: null,   // all tree directories are opened or closed
+		sort: "path",    // the column to sort by
+		reverse: false,  // sort in decreasing order
+		collapsed: {},   // directory -> collapsed
+		expanded: {}     // uid -> backend details are shown
+	};
+
+	// el builds an element with some text and optional class name.
+	function el(tag, text, className) {
+		var e = document.createElement(tag);
+		if (text !== undefined && text !== null) {
+			e.textContent = text;
+		}
+		if (className) {
+			e.className = className;
+		}
+		return e;
+	}
+
+	// path returns the uid without any query string.
+	function path(uid) {
+		var i = uid.indexOf("?");
+		return i > -1 ? uid.substring(0, i) : uid;
+	}
+
+	// dir returns the directory part of the uid, ending with a slash.
+	function dir(uid) {
+		var p = path(uid);
+		if (p.charAt(p.length - 1) === "/") {
+			p = p.substring(0, p.length - 1);
+		}
+		return p.substring(0, p.lastIndexOf("/") + 1);
+	}
+
+	// flagged returns true if an enabled profile, other than one that
+	// matches everything, would flag this license.
+	function flagged(license) {
+		return report.profiles.some(function(profile) {
+			if (!state.profiles[profile.name] || profile.licenses.length === 0 && !profile.exclude) {
+				return false;
+			}
+			return (profile.licenses.indexOf(license) > -1) !== profile.exclude;
+		});
+	}
+
+	// licenses returns an element listing the licenses, with the flagged
+	// ones highlighted.
+	function licenses(list) {
+		var span = el("span");
+		list.forEach(function(license, i) {
+			if (i > 0) {
+				span.appendChild(document.createTextNode(", "));
+			}
+			span.appendChild(el("span", license, flagged(license) ? "flagged" : ""));
+		});
+		return span;
+	}
+
+	function percent(x) {
+		return (x * 100).toFixed(2) + "%";
+	}
+
+	// visible returns true if the file matches an enabled profile and the
+	// filter text.
+	function visible(file) {
+		var matched = state.all || file.profiles.some(function(name) {
+			return state.profiles[name];
+		});
+		if (!matched) {
+			return false;
+		}
+		if (state.filter.length === 0) {
+			return true;
+		}
+		var words = [file.uid].concat(file.licenses);
+		file.results.forEach(function(result) {
+			words.push(result.backend);
+			words = words.concat(result.holders || []);
+			if (result.skip) {
+				words.push(result.skip);
+			}
+		});
+		var text = words.join(" ").toLowerCase();
+		return state.filter.every(function(x) {
+			return text.indexOf(x) > -1;
+		});
+	}
+
+	// compare is the sort function for the chosen column.
+	function compare(a, b) {
+		var x = 0;
+		if (state.sort === "licenses") {
+			x = a.licenses.join(", ").localeCompare(b.licenses.join(", "));
+		} else if (state.sort === "confidence") {
+			x = a.confidence - b.confidence;
+		} else if (state.sort === "profiles") {
+			x = a.profiles.length - b.profiles.length;
+		}
+		if (x === 0) {
+			x = a.uid < b.uid ? -1 : a.uid > b.uid ? 1 : 0;
+		}
+		return state.reverse ? -x : x;
+	}
+
+	// details returns the row which shows the result of each backend.
+	function details(file) {
+		var tr = el("tr", null, "details");
+		var td = el("td");
+		td.colSpan = 4;
+		var table = el("table");
+		var head = el("tr");
+		["backend", "weight", "confidence", "scaled", "licenses"].forEach(function(x) {
+			head.appendChild(el("th", x));
+		});
+		table.appendChild(head);
+		var add = function(result, more) {
+			var row = el("tr", null, more ? "muted" : "");
+			row.appendChild(el("td", more ? "(more) " + result.backend : result.backend));
+			row.appendChild(el("td", more ? "" : result.weight.toFixed(2)));
+			row.appendChild(el("td", percent(result.confidence)));
+			row.appendChild(el("td", more ? "" : percent(result.scaled_confidence)));
+			var cell = el("td");
+			if (result.component) {
+				cell.appendChild(el("span", result.component + ": ", "muted"));
+			}
+			cell.appendChild(licenses(result.licenses));
+			if (result.holders) {
+				cell.appendChild(el("span", " copyright: " + result.holders.join("; "), "muted"));
+			}
+			if (result.skip) {
+				cell.appendChild(el("span", " " + result.skip, "error"));
+			}
+			row.appendChild(cell);
+			table.appendChild(row);
+		};
+		file.results.forEach(function(result) {
+			add(result, false);
+			(result.more || []).forEach(function(x) {
+				add(x, true);
+			});
+		});
+		td.appendChild(table);
+		if (file.sha256) {
+			td.appendChild(el("div", "sha256: " + file.sha256, "muted"));
+		}
+		tr.appendChild(td);
+		return tr;
+	}
+
+	// fileRow returns the row for a file, and its details if expanded. The
+	// depth is the number of directories that it is nested in.
+	function fileRow(file, name, depth) {
+		var rows = [];
+		var tr = el("tr", null, depth > 0 ? "file indent" : "file");
+		var td = el("td");
+		if (depth > 1) {
+			td.style.paddingLeft = (2 * depth) + "em";
+		}
+		var a = el("a", name);
+		a.href = file.smart_uri;
+		a.addEventListener("click", function(e) {
+			e.stopPropagation(); // follow the link only
+		});
+		td.appendChild(a);
+		tr.appendChild(td);
+		var cell = el("td");
+		cell.appendChild(licenses(file.licenses));
+		tr.appendChild(cell);
+		tr.appendChild(el("td", percent(file.confidence)));
+		tr.appendChild(el("td", file.profiles.join(", ")));
+		tr.addEventListener("click", function() {
+			state.expanded[file.uid] = !state.expanded[file.uid];
+			render();
+		});
+		rows.push(tr);
+		if (state.expanded[file.uid]) {
+			rows.push(details(file));
+		}
+		return rows;
+	}
+
+	// dirRow returns the header row for a directory of files.
+	function dirRow(name, files) {
+		var tr = el("tr", null, state.collapsed[name] ? "dir collapsed" : "dir");
+		tr.appendChild(el("td", name + " (" + files.length + ")"));
+		var found = [];
+		files.forEach(function(file) {
+			file.licenses.forEach(function(x) {
+				if (found.indexOf(x) === -1) {
+					found.push(x);
+				}
+			});
+		});
+		found.sort();
+		var cell = el("td");
+		cell.appendChild(licenses(found));
+		tr.appendChild(cell);
+		tr.appendChild(el("td"));
+		tr.appendChild(el("td"));
+		tr.addEventListener("click", function() {
+			state.collapsed[name] = !state.collapsed[name];
+			render();
+		});
+		return tr;
+	}
+
+	// interesting returns true if the file has a flagged or unknown license,
+	// or if the backends disagree about it. Only the tree directories which
+	// contain one of these are opened at first, like in the text report.
+	function interesting(file) {
+		if (file.licenses.some(function(x) {
+			return flagged(x) || /\(unknown\)$/.test(x);
+		})) {
+			return true;
+		}
+		var found = null;
+		return file.results.some(function(result) {
+			if (result.licenses.length === 0) {
+				return false;
+			}
+			var x = result.licenses.slice().sort().join(", ");
+			if (found === null) {
+				found = x;
+			}
+			return x !== found;
+		});
+	}
+
+	// buildTree returns the directory hierarchy of the files. The uid's are
+	// split on slashes after the scheme, and chains of directories with only
+	// one child directory are merged, so that deep paths stay readable.
+	function buildTree(files) {
+		var top = {name: "", key: "", dirs: {}, files: [], all: []};
+		files.forEach(function(file) {
+			var s = path(file.uid);
+			var prefix = "";
+			var i = s.indexOf("://");
+			if (i > -1) {
+				prefix = s.substring(0, i + 3);
+				s = s.substring(i + 3);
+			}
+			if (s.charAt(0) === "/") {
+				prefix += "/";
+				s = s.substring(1);
+			}
+			var segments = s.split("/").filter(function(x) {
+				return x !== "";
+			});
+			var isDir = s === "" || s.charAt(s.length - 1) === "/";
+			var names = [prefix].concat(segments.slice(0, isDir ? segments.length : segments.length - 1).map(function(x) {
+				return x + "/";
+			}));
+			var node = top;
+			names.forEach(function(name) {
+				if (!node.dirs[name]) {
+					node.dirs[name] = {name: name, key: node.key + name, dirs: {}, files: [], all: []};
+				}
+				node = node.dirs[name];
+				node.all.push(file);
+			});
+			var base = isDir ? "./" : segments[segments.length - 1];
+			node.files.push({file: file, name: base});
+		});
+
+		var merge = function(node) {
+			Object.keys(node.dirs).forEach(function(name) {
+				var child = node.dirs[name];
+				var keys = Object.keys(child.dirs);
+				while (keys.length === 1 && child.files.length === 0) {
+					var next = child.dirs[keys[0]];
+					next.name = child.name + next.name;
+					child = next;
+					keys = Object.keys(child.dirs);
+				}
+				delete node.dirs[name];
+				node.dirs[child.name] = child;
+				merge(child);
+			});
+		};
+		merge(top);
+		return top;
+	}
+
+	// treeRows returns the rows for the directory and everything inside it
+	// which is opened.
+	function treeRows(node, depth) {
+		var rows = [];
+		var open = state.opened[node.key];
+		if (open === undefined) {
+			open = state.openAll !== null ? state.openAll : node.all.some(interesting);
+		}
+
+		var counts = {};
+		node.all.forEach(function(file) {
+			file.licenses.forEach(function(x) {
+				counts[x] = (counts[x] || 0) + 1;
+			});
+		});
+		var found = Object.keys(counts).sort(function(a, b) {
+			return counts[b] - counts[a] || (a < b ? -1 : a > b ? 1 : 0);
+		});
+
+		var tr = el("tr", null, open ? "dir" : "dir collapsed");
+		var td = el("td", node.name + " (" + node.all.length + ")");
+		td.style.paddingLeft = (2 * depth + 0.5) + "em";
+		tr.appendChild(td);
+		var cell = el("td");
+		found.forEach(function(license, i) {
+			if (i > 0) {
+				cell.appendChild(document.createTextNode(", "));
+			}
+			cell.appendChild(el("span", license, flagged(license) ? "flagged" : ""));
+			cell.appendChild(el("span", " (" + counts[license] + ")", "muted"));
+		});
+		tr.appendChild(cell);
+		tr.appendChild(el("td"));
+		tr.appendChild(el("td"));
+		tr.addEventListener("click", function() {
+			state.opened[node.key] = !open;
+			render();
+		});
+		rows.push(tr);
+		if (!open) {
+			return rows;
+		}
+
+		var names = Object.keys(node.dirs).sort();
+		if (state.reverse && state.sort === "path") {
+			names.reverse();
+		}
+		names.forEach(function(name) {
+			rows = rows.concat(treeRows(node.dirs[name], depth + 1));
+		});
+		node.files.forEach(function(x) {
+			rows = rows.concat(fileRow(x.file, x.name, depth + 1));
+		});
+		return rows;
+	}
+
+	// render rebuilds the table of files from the current state.
+	function render() {
+		var tbody = document.querySelector("#files tbody");
+		var files = report.files.filter(visible).sort(compare);
+		var rows = [];
+
+		if (state.tree) {
+			var top = buildTree(files);
+			Object.keys(top.dirs).sort().forEach(function(name) {
+				rows = rows.concat(treeRows(top.dirs[name], 0));
+			});
+		} else if (!state.group) {
+			files.forEach(function(file) {
+				rows = rows.concat(fileRow(file, file.uid, 0));
+			});
+		} else {
+			var names = [];
+			var dirs = {};
+			files.forEach(function(file) {
+				var d = dir(file.uid);
+				if (!dirs[d]) {
+					dirs[d] = [];
+					names.push(d);
+				}
+				dirs[d].push(file);
+			});
+			names.sort();
+			if (state.reverse && state.sort === "path") {
+				names.reverse();
+			}
+			names.forEach(function(name) {
+				rows.push(dirRow(name, dirs[name]));
+				if (state.collapsed[name]) {
+					return;
+				}
+				dirs[name].forEach(function(file) {
+					rows = rows.concat(fileRow(file, path(file.uid).substring(name.length), 1));
+				});
+			});
+		}
+
+		tbody.textContent = "";
+		rows.forEach(function(tr) {
+			tbody.appendChild(tr);
+		});
+		if (files.length === 0) {
+			var tr = el("tr");
+			var td = el("td", "no results", "muted");
+			td.colSpan = 4;
+			tr.appendChild(td);
+			tbody.appendChild(tr);
+		}
+		document.getElementById("count").textContent = files.length + " of " + report.files.length + " files";
+
+		document.querySelectorAll("#files th").forEach(function(th) {
+			th.className = "";
+			if (th.getAttribute("data-sort") === state.sort) {
+				th.className = state.reverse ? "desc" : "asc";
+			}
+		});
+	}
+
+	// section adds a collapsible list of extra information to the page.
+	function section(title, list, text) {
+		if (list.length === 0) {
+			return;
+		}
+		var d = el("details");
+		d.appendChild(el("summary", title + " (" + list.length + ")"));
+		var ul = el("ul");
+		list.forEach(function(x) {
+			ul.appendChild(el("li", text(x)));
+		});
+		d.appendChild(ul);
+		document.getElementById("extra").appendChild(d);
+	}
+
+	function init() {
+		document.getElementById("inputs").textContent = report.args.join(" ");
+
+		var profiles = document.getElementById("profiles");
+		profiles.appendChild(el("span", "profiles: "));
+		report.profiles.forEach(function(profile) {
+			state.profiles[profile.name] = true;
+			var label = el("label");
+			var input = el("input");
+			input.type = "checkbox";
+			input.checked = true;
+			input.addEventListener("change", function() {
+				state.profiles[profile.name] = input.checked;
+				render();
+			});
+			label.appendChild(input);
+			label.appendChild(document.createTextNode(" " + profile.name));
+			label.title = (profile.exclude ? "exclude: " : "include: ") + profile.licenses.join(", ");
+			profiles.appendChild(label);
+		});
+
+		document.getElementById("filter").addEventListener("input", function(e) {
+			state.filter = e.target.value.toLowerCase().split(/\s+/).filter(function(x) {
+				return x !== "";
+			});
+			render();
+		});
+
+		document.getElementById("all").addEventListener("change", function(e) {
+			state.all = e.target.checked;
+			render();
+		});
+
+		document.getElementById("group").addEventListener("change", function(e) {
+			state.group = e.target.checked;
+			render();
+		});
+
+		var tree = document.getElementById("tree");
+		state.tree = tree.checked; // the --tree flag checks it
+		tree.addEventListener("change", function(e) {
+			state.tree = e.target.checked;
+			render();
+		});
+
+		document.getElementById("expand").addEventListener("click", function() {
+			state.collapsed = {};
+			state.opened = {};
+			state.openAll = true;
+			report.files.forEach(function(file) {
+				state.expanded[file.uid] = true;
+			});
+			render();
+		});
+
+		document.getElementById("collapse").addEventListener("click", function() {
+			state.expanded = {};
+			state.opened = {};
+			state.openAll = false;
+			report.files.forEach(function(file) {
+				state.collapsed[dir(file.uid)] = true;
+			});
+			render();
+		});
+
+		document.querySelectorAll("#files th").forEach(function(th) {
+			th.addEventListener("click", function() {
+				var sort = th.getAttribute("data-sort");
+				state.reverse = state.sort === sort ? !state.reverse : false;
+				state.sort = sort;
+				render();
+			});
+		});
+
+		section("errors", report.errors, function(x) {
+			return x.uid + ": " + x.error + " (" + x.backend + ")";
+		});
+		section("warnings", report.warnings, function(x) {
+			return x.uid + ": " + x.error;
+		});
+		section("skipped", report.skipped, function(x) {
+			return x;
+		});
+		var summary = Object.keys(report.summary.licenses).sort().map(function(x) {
+			return x + ": " + report.summary.licenses[x];
+		});
+		section("summary", summary, function(x) {
+			return x;
+		});
+
+		render();
+	}
+
+	init();
+})();
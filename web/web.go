@@ -40,7 +40,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/awslabs/yesiscan/art"
 	"github.com/awslabs/yesiscan/interfaces"
@@ -687,6 +686,10 @@ func (obj *Server) Router() *gin.Engine {
 		if err != nil {
 			return "", err
 		}
+		data, err := lib.BuildReport(output)
+		if err != nil {
+			return "", err
+		}
 
 		report := &Report{
 			Program:  obj.Program,
@@ -696,7 +699,7 @@ func (obj *Server) Router() *gin.Engine {
 			Profiles: profilesMap,
 			// XXX: consider storing full datastructure of profiles
 			Html: s,
-			// XXX: consider storing output instead of HTML
+			Data: data,
 		}
 
 		//store and get a URL...
@@ -865,6 +868,17 @@ func (obj *Server) Router() *gin.Engine {
 		}
 
 		filename := fmt.Sprintf("%s.html", r)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+		if report.Data != nil { // save the interactive version
+			s, err := ReturnReportHtml(report.Data, false)
+			if err != nil {
+				obj.Logf("error during save: %+v", err)
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			c.Data(http.StatusOK, "application/octet-stream", []byte(s))
+			return
+		}
 		h := gin.H{
 			"program":     report.Program,
 			"version":     report.Version,
@@ -882,7 +896,6 @@ func (obj *Server) Router() *gin.Engine {
 		instance := obj.ginEngine.HTMLRender.Instance(templateName, h)
 
 		c.Status(http.StatusOK)
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 		c.Header("Content-Type", "application/octet-stream")
 		//c.Header("Content-Length", fmt.Sprintf("%d", len(data))) // TODO: figure out length
 
@@ -897,28 +910,29 @@ func (obj *Server) Router() *gin.Engine {
 	return router
 }
 
+// Store saves the report and returns its unique ID. The ID is the checksum of
+// the report contents, so storing an identical report again returns the same
+// ID, and it's easy to tell if two scans had the same output.
 // TODO: consider adding a context.Context
 func (obj *Server) Store(report *Report) (string, error) {
 	if report == nil {
 		return "", fmt.Errorf("got nil report")
 	}
-	// make a unique ID for the file
-	// XXX: we can consider different algorithms or methods here later...
-	now := strconv.FormatInt(time.Now().UnixMilli(), 10) // itoa but int64
-	sum := sha256.Sum256([]byte(report.Html + now))      // XXX: for now
-	uid := fmt.Sprintf("%x", sum)
-	hashRelFile, err := safepath.ParseIntoRelFile(fmt.Sprintf("%s.json", uid))
+
+	// json encodes the maps with sorted keys, so this is deterministic
+	b, err := json.Marshal(report)
 	if err != nil {
 		return "", err
 	}
-	// TODO: split into subfolders when we have very large numbers of files
-	absFile := safepath.JoinToAbsFile(obj.reportPrefix, hashRelFile)
-	obj.Logf("report: %s", absFile)
+	uid := ReportID(b)
 
-	b, err := json.Marshal(report)
+	hashRelFile, err := safepath.ParseIntoRelFile(fmt.Sprintf("%s.json", uid))
 	if err != nil {
 		return "", err
 	}
+	// TODO: split into subfolders when we have very large numbers of files
+	absFile := safepath.JoinToAbsFile(obj.reportPrefix, hashRelFile)
+	obj.Logf("report: %s", absFile)
 
 	if err := os.WriteFile(absFile.Path(), b, os.ModePerm); err != nil {
 		return "", errwrap.Wrapf(err, "error writing our file to disk at %s", absFile)
@@ -927,6 +941,13 @@ func (obj *Server) Store(report *Report) (string, error) {
 	return uid, nil
 }
 
+// ReportID returns the content-addressed ID of an encoded report. It is the hex
+// encoded sha256 sum of the data.
+func ReportID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%x", sum)
+}
+
 // TODO: consider adding a context.Context
 // TODO: we have no auth on this at the moment, anyone can lookup a report
 func (obj *Server) Load(uid string) (*Report, error) {
@@ -1039,13 +1060,16 @@ type Report struct {
 	Profiles map[string]bool `json:"profiles"`
 
 	// Html is a rendered version of the core report content.
-	// XXX: we might choose to store the data itself in the future...
 	Html string `json:"html"`
+
+	// Data is the structured form of the scan output. It is used to build
+	// the interactive report when it is saved. Older stored reports don't
+	// have it.
+	Data *lib.Report `json:"data,omitempty"`
 }
 
 // ReturnOutputHtmlBody returns a string of output, formatted in html. It is
-// the body portion of the larger full html output that comes from
-// ReturnOutputHtml.
+// the body portion of the report page that the web server displays.
 func ReturnOutputHtmlBody(output *lib.Output) (string, error) {
 	if len(output.Results) == 0 {
 		// handle this here, otherwise we'll get an error below...
@@ -1056,9 +1080,13 @@ func ReturnOutputHtmlBody(output *lib.Output) (string, error) {
 		return s, nil
 	}
 
+	display := lib.SimpleProfiles
+	if output.Tree {
+		display = lib.TreeProfiles
+	}
 	str := ""
 	for _, x := range output.Profiles {
-		pro, err := lib.SimpleProfiles(output.Results, output.Passes, output.Warnings, output.ProfilesData[x], displaySummary, output.BackendWeights, "html")
+		pro, err := display(output.Results, output.Passes, output.Warnings, output.ProfilesData[x], displaySummary, output.BackendWeights, "html")
 		if err != nil {
 			return "", err
 		}
@@ -1072,52 +1100,6 @@ func ReturnOutputHtmlBody(output *lib.Output) (string, error) {
 	return str, nil
 }
 
-// ReturnOutputHtml returns a string of output, formatted in html.
-func ReturnOutputHtml(output *lib.Output) (string, error) {
-
-	body, err := ReturnOutputHtmlBody(output)
-	if err != nil {
-		return "", err
-	}
-	profiles := make(map[string]bool)
-	for _, x := range output.Profiles {
-		profiles[x] = true
-	}
-
-	args := fmt.Sprintf("%+v", output.Args)
-	if len(output.Args) == 1 {
-		args = output.Args[0]
-	}
-
-	data := gin.H{ // XXX: change to map[string]interface{} ?
-		"program":     output.Program,
-		"version":     output.Version, // XXX: use me in template
-		"image":       base64Yesiscan,
-		"base64Files": base64Files,
-		"status":      "success",
-		"body":        template.HTML(body), // avoid escaping the html!
-		"uri":         args,                // XXX: display this differently?
-		"backends":    output.Backends,
-		"profiles":    profiles,
-		"fancy":       fancyRendering,
-		"uuid":        "",
-	}
-
-	// TODO: why is name used twice? Does this even make sense?
-	t, err := template.New(templateName).Funcs(funcMap).Parse(indexTemplate)
-	if err != nil {
-		return "", err
-	}
-
-	buf := new(bytes.Buffer) // we'll write to here
-
-	if err := t.ExecuteTemplate(buf, templateName, data); err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
-}
-
 // mustFs is a helper function so we can return static files that we added with
 // the embed package.
 func mustFs() http.FileSystem {